@@ -2,13 +2,37 @@ package app
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/irreal/order-packs/models"
+	"github.com/irreal/order-packs/orders"
+	"github.com/irreal/order-packs/packs"
 	"github.com/irreal/order-packs/utils"
 )
 
+// sizes orders can use, or with ?at= the ones in effect at that time, past or scheduled.
+// only sizes, like it always was, the details are on /api/packs/details
 func (a *App) handleGetPacks(w http.ResponseWriter, r *http.Request) {
+	at, err := readTimeParam(r.URL.Query(), "at")
+	if err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !at.IsZero() {
+		packs, err := a.packsService.GetPacksAt(at)
+		if err != nil {
+			writePacksErrorResponse(w, err)
+			return
+		}
+		utils.WriteAPISuccessResponse(w, packs)
+		return
+	}
+
 	packs, err := a.packsService.GetPacks()
 	if err != nil {
 		utils.WriteAPIErrorResponse(w, http.StatusInternalServerError, "internal server error")
@@ -17,9 +41,48 @@ func (a *App) handleGetPacks(w http.ResponseWriter, r *http.Request) {
 	utils.WriteAPISuccessResponse(w, packs)
 }
 
+// live packs with their details, disabled ones included, or with ?at= the packs in effect at that time
+func (a *App) handleGetPackDetails(w http.ResponseWriter, r *http.Request) {
+	at, err := readTimeParam(r.URL.Query(), "at")
+	if err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !at.IsZero() {
+		version, err := a.packsService.GetVersionAt(at)
+		if err != nil {
+			writePacksErrorResponse(w, err)
+			return
+		}
+		utils.WriteAPISuccessResponse(w, version.Packs)
+		return
+	}
+
+	packs, err := a.packsService.GetPackDetails()
+	if err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	utils.WriteAPISuccessResponse(w, packs)
+}
+
+func (a *App) handleGetUpcomingPacks(w http.ResponseWriter, r *http.Request) {
+	upcoming, err := a.packsService.GetUpcomingChanges()
+	if err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	utils.WriteAPISuccessResponse(w, upcoming)
+}
+
 func (a *App) handleSetPacks(w http.ResponseWriter, r *http.Request) {
+	// packs can be given as plain sizes or with their details
 	var request struct {
-		Packs []int `json:"packs"`
+		Packs []models.PackDetails `json:"packs"`
+		// kept with the new pack set version
+		CreatedBy string `json:"createdBy"`
+		// schedules the change instead of making it right away
+		EffectiveFrom *time.Time `json:"effectiveFrom"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -32,18 +95,277 @@ func (a *App) handleSetPacks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var newPacks models.Packs
-	for _, packSize := range request.Packs {
-		if packSize <= 0 {
-			utils.WriteAPIErrorResponse(w, http.StatusBadRequest, "pack size must be positive")
+	for _, pack := range request.Packs {
+		if err := pack.Validate(); err != nil {
+			utils.WriteAPIErrorResponse(w, http.StatusBadRequest, err.Error())
 			return
 		}
-		newPacks = append(newPacks, models.Pack(packSize))
 	}
 
-	if err := a.packsService.SavePacks(newPacks); err != nil {
+	// the analysis finds these too, but they can't be saved
+	if duplicates := packs.DuplicateSizes(request.Packs); len(duplicates) > 0 {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("pack size %d is given more than once", duplicates[0]))
+		return
+	}
+
+	if len(models.EnabledPacks(request.Packs)) == 0 {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, packs.NoEnabledPacksError.Error())
+		return
+	}
+
+	if request.EffectiveFrom != nil {
+		version, err := a.packsService.SchedulePacks(request.Packs, request.CreatedBy, *request.EffectiveFrom)
+		if errors.Is(err, packs.InvalidEffectiveFromError) || errors.Is(err, orders.PackSetTooLargeError) {
+			utils.WriteAPIErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			utils.WriteAPIErrorResponse(w, http.StatusInternalServerError, "failed to save packs")
+			return
+		}
+		utils.WriteAPISuccessResponse(w, "packs scheduled for "+version.EffectiveFrom.Format(time.RFC3339))
+		return
+	}
+
+	_, err := a.packsService.SavePacks(request.Packs, request.CreatedBy)
+	if errors.Is(err, orders.PackSetTooLargeError) {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
 		utils.WriteAPIErrorResponse(w, http.StatusInternalServerError, "failed to save packs")
 		return
 	}
 	utils.WriteAPISuccessResponse(w, "packs saved successfully")
 }
+
+// adds the pack or replaces everything about it, the body is the pack like in POST /api/packs
+func (a *App) handlePutPack(w http.ResponseWriter, r *http.Request) {
+	size, ok := readPackSize(w, r)
+	if !ok {
+		return
+	}
+
+	var pack models.PackDetails
+	if err := json.NewDecoder(r.Body).Decode(&pack); err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, "invalid JSON format")
+		return
+	}
+	// the size is in the path already, the body doesn't have to repeat it
+	if pack.Size == 0 {
+		pack.Size = size
+	}
+	if pack.Size != size {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("pack size %d does not match %d in the path", pack.Size, size))
+		return
+	}
+
+	version, err := a.packsService.PutPack(pack, r.URL.Query().Get("createdBy"))
+	a.writePackChangeResponse(w, version, err)
+}
+
+func (a *App) handleDeletePack(w http.ResponseWriter, r *http.Request) {
+	size, ok := readPackSize(w, r)
+	if !ok {
+		return
+	}
+	version, err := a.packsService.DeletePack(size, r.URL.Query().Get("createdBy"))
+	a.writePackChangeResponse(w, version, err)
+}
+
+func (a *App) handleEnablePack(w http.ResponseWriter, r *http.Request) {
+	a.setPackEnabled(w, r, true)
+}
+
+func (a *App) handleDisablePack(w http.ResponseWriter, r *http.Request) {
+	a.setPackEnabled(w, r, false)
+}
+
+func (a *App) setPackEnabled(w http.ResponseWriter, r *http.Request, enabled bool) {
+	size, ok := readPackSize(w, r)
+	if !ok {
+		return
+	}
+	version, err := a.packsService.SetPackEnabled(size, enabled, r.URL.Query().Get("createdBy"))
+	a.writePackChangeResponse(w, version, err)
+}
+
+// several changes to the live packs at once, saved together as one version or not at all
+func (a *App) handlePatchPacks(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Operations []models.PackOperation `json:"operations"`
+		// kept with the new pack set version
+		CreatedBy string `json:"createdBy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, "invalid JSON format")
+		return
+	}
+
+	version, err := a.packsService.ApplyPackOperations(request.Operations, request.CreatedBy)
+	a.writePackChangeResponse(w, version, err)
+}
+
+func readPackSize(w http.ResponseWriter, r *http.Request) (models.Pack, bool) {
+	size, err := strconv.Atoi(r.PathValue("size"))
+	if err != nil || size <= 0 {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid pack size: %q", r.PathValue("size")))
+		return 0, false
+	}
+	return models.Pack(size), true
+}
+
+// incremental changes respond with the version they created
+func (a *App) writePackChangeResponse(w http.ResponseWriter, version *models.PackSetVersion, err error) {
+	if err != nil {
+		fmt.Fprintf(a.stderr, "error changing packs: %v\n", err)
+		writePacksErrorResponse(w, err)
+		return
+	}
+	utils.WriteAPISuccessResponse(w, version)
+}
+
+// what looks wrong with a pack set, without saving it
+func (a *App) handleAnalyzePacks(w http.ResponseWriter, r *http.Request) {
+	var request models.PackAnalysisRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, "invalid JSON format")
+		return
+	}
+
+	analysis, err := packs.AnalyzePackSet(request)
+	if errors.Is(err, packs.InvalidPackAnalysisError) || errors.Is(err, packs.NoEnabledPacksError) || errors.Is(err, orders.InvalidObjectiveError) {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(a.stderr, "error analyzing packs: %v\n", err)
+		utils.WriteAPIErrorResponse(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	utils.WriteAPISuccessResponse(w, analysis)
+}
+
+// how a pack set would have done on past orders. the orders can be filtered like order history
+func (a *App) handleSimulatePacks(w http.ResponseWriter, r *http.Request) {
+	query, err := readOrderQuery(r.URL.Query())
+	if err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var request models.SimulationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, "invalid JSON format")
+		return
+	}
+
+	simulation, err := a.orderService.Simulate(request, query)
+	if err != nil {
+		fmt.Fprintf(a.stderr, "error simulating packs: %v\n", err)
+		writeOrderErrorResponse(w, err)
+		return
+	}
+	utils.WriteAPISuccessResponse(w, simulation)
+}
+
+// a pack set suggested by the item counts of past orders, next to how the live packs do on them
+func (a *App) handleRecommendPacks(w http.ResponseWriter, r *http.Request) {
+	var request models.PackRecommendationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, "invalid JSON format")
+		return
+	}
+
+	current, err := a.packsService.GetPackDetails()
+	if err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	recommendation, err := a.orderService.RecommendPacks(request, current)
+	if err != nil {
+		fmt.Fprintf(a.stderr, "error recommending packs: %v\n", err)
+		writeOrderErrorResponse(w, err)
+		return
+	}
+	utils.WriteAPISuccessResponse(w, recommendation)
+}
+
+func (a *App) handleGetPackVersions(w http.ResponseWriter, r *http.Request) {
+	versions, err := a.packsService.GetVersions()
+	if err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	utils.WriteAPISuccessResponse(w, versions)
+}
+
+func (a *App) handleGetPackVersion(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid version: %q", r.PathValue("id")))
+		return
+	}
+
+	version, err := a.packsService.GetVersion(id)
+	if err != nil {
+		writePacksErrorResponse(w, err)
+		return
+	}
+	utils.WriteAPISuccessResponse(w, version)
+}
+
+func (a *App) handleDiffPackVersions(w http.ResponseWriter, r *http.Request) {
+	from, errFrom := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+	to, errTo := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+	if errFrom != nil || errTo != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, "from and to have to be pack set version ids")
+		return
+	}
+
+	diff, err := a.packsService.DiffVersions(from, to)
+	if err != nil {
+		writePacksErrorResponse(w, err)
+		return
+	}
+	utils.WriteAPISuccessResponse(w, diff)
+}
+
+func (a *App) handleRollbackPacks(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid version: %q", r.PathValue("id")))
+		return
+	}
+
+	// the body is optional, it only says who rolled back
+	var request struct {
+		CreatedBy string `json:"createdBy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, "invalid JSON format")
+		return
+	}
+
+	version, err := a.packsService.Rollback(id, request.CreatedBy)
+	if err != nil {
+		fmt.Fprintf(a.stderr, "error rolling back packs: %v\n", err)
+		writePacksErrorResponse(w, err)
+		return
+	}
+	utils.WriteAPISuccessResponse(w, version)
+}
+
+func writePacksErrorResponse(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, packs.PackSetVersionNotFoundError), errors.Is(err, packs.PackNotFoundError):
+		utils.WriteAPIErrorResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, packs.InvalidPackOperationError), errors.Is(err, orders.PackSetTooLargeError):
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, packs.PackExistsError), errors.Is(err, packs.NoEnabledPacksError):
+		utils.WriteAPIErrorResponse(w, http.StatusConflict, err.Error())
+	default:
+		utils.WriteAPIErrorResponse(w, http.StatusInternalServerError, "internal server error")
+	}
+}
@@ -1,17 +1,40 @@
 package app
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/irreal/order-packs/app/pages"
 	"github.com/irreal/order-packs/models"
+	"github.com/irreal/order-packs/orders"
+	"github.com/irreal/order-packs/packs"
 	"github.com/irreal/order-packs/utils"
 )
 
 func (a *App) handleAdminPageGet(w http.ResponseWriter, r *http.Request) {
 
-	packs, err := a.packsService.GetPacks()
+	packs, err := a.packsService.GetPackDetails()
+	if err != nil {
+		utils.Render(w, r, pages.ErrorPage(err.Error()))
+		return
+	}
+
+	upcoming, err := a.packsService.GetUpcomingChanges()
+	if err != nil {
+		utils.Render(w, r, pages.ErrorPage(err.Error()))
+		return
+	}
+
+	// oldest first, they have waited the longest
+	awaitingApproval, err := a.orderService.ListOrders(models.OrderQuery{
+		Statuses: []models.OrderStatus{models.OrderStatusNeedsApproval},
+		Sort:     models.OrderSortOldest,
+		Limit:    orders.MaxOrderPageSize,
+	}, "")
 	if err != nil {
 		utils.Render(w, r, pages.ErrorPage(err.Error()))
 		return
@@ -19,7 +42,23 @@ func (a *App) handleAdminPageGet(w http.ResponseWriter, r *http.Request) {
 
 	success := r.URL.Query().Get("success") == "1"
 
-	utils.Render(w, r, pages.AdminPage(packs, success))
+	utils.Render(w, r, pages.AdminPage(packs, upcoming, awaitingApproval.Orders, success))
+}
+
+func (a *App) handleApproveOrderWeb(w http.ResponseWriter, r *http.Request) {
+	if _, err := a.orderService.ApproveOrder(r.PathValue("id")); err != nil {
+		utils.Render(w, r, pages.ErrorPage(err.Error()))
+		return
+	}
+	http.Redirect(w, r, "/admin", http.StatusSeeOther)
+}
+
+func (a *App) handleRejectOrderWeb(w http.ResponseWriter, r *http.Request) {
+	if _, err := a.orderService.RejectOrder(r.PathValue("id")); err != nil {
+		utils.Render(w, r, pages.ErrorPage(err.Error()))
+		return
+	}
+	http.Redirect(w, r, "/admin", http.StatusSeeOther)
 }
 
 func (a *App) handleAdminPageSetPacks(w http.ResponseWriter, r *http.Request) {
@@ -34,19 +73,84 @@ func (a *App) handleAdminPageSetPacks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// convert to slice
-	var newPacks models.Packs
-	for _, packStr := range packValues {
+	// every pack has one of each field, in the same order
+	var newPacks []models.PackDetails
+	for i, packStr := range packValues {
 		packSize, err := strconv.Atoi(packStr)
 		if err != nil || packSize <= 0 {
 			utils.Render(w, r, pages.ErrorPage("Invalid pack size: "+packStr))
 			return
 		}
-		newPacks = append(newPacks, models.Pack(packSize))
+
+		pack := models.PackDetails{
+			Size: models.Pack(packSize),
+			SKU:  formValue(r, "sku", i),
+			Name: formValue(r, "name", i),
+		}
+
+		numbers := []struct {
+			field string
+			value *int
+		}{
+			{"unitCost", &pack.UnitCost},
+			{"tareWeight", &pack.TareWeight},
+			{"length", &pack.Dimensions.Length},
+			{"width", &pack.Dimensions.Width},
+			{"height", &pack.Dimensions.Height},
+		}
+		for _, number := range numbers {
+			valueStr := formValue(r, number.field, i)
+			if valueStr == "" {
+				continue
+			}
+			if *number.value, err = strconv.Atoi(valueStr); err != nil {
+				utils.Render(w, r, pages.ErrorPage("Invalid "+number.field+" for pack "+packStr+": "+valueStr))
+				return
+			}
+		}
+
+		// stock is optional, empty means it is not tracked
+		if stockStr := formValue(r, "stock", i); stockStr != "" {
+			stock, err := strconv.Atoi(stockStr)
+			if err != nil {
+				utils.Render(w, r, pages.ErrorPage("Invalid stock for pack "+packStr+": "+stockStr))
+				return
+			}
+			pack.Stock = &stock
+		} else {
+			pack.ClearStock = true
+		}
+
+		pack.Disabled = formValue(r, "disabled", i) == "true"
+
+		if err := pack.Validate(); err != nil {
+			utils.Render(w, r, pages.ErrorPage(err.Error()))
+			return
+		}
+		newPacks = append(newPacks, pack)
 	}
 
-	// persist to repo
-	if err := a.packsService.SavePacks(newPacks); err != nil {
+	if duplicates := packs.DuplicateSizes(newPacks); len(duplicates) > 0 {
+		utils.Render(w, r, pages.ErrorPage(fmt.Sprintf("Pack size %d is given more than once", duplicates[0])))
+		return
+	}
+	if len(models.EnabledPacks(newPacks)) == 0 {
+		utils.Render(w, r, pages.ErrorPage("At least one pack has to stay enabled"))
+		return
+	}
+
+	// persist to repo, right away unless the change is scheduled. the form's times are in UTC
+	if effectiveFromStr := r.Form.Get("effectiveFrom"); effectiveFromStr != "" {
+		effectiveFrom, err := time.Parse("2006-01-02T15:04", effectiveFromStr)
+		if err != nil {
+			utils.Render(w, r, pages.ErrorPage("Invalid effective from: "+effectiveFromStr))
+			return
+		}
+		if _, err := a.packsService.SchedulePacks(newPacks, r.Form.Get("createdBy"), effectiveFrom); err != nil {
+			utils.Render(w, r, pages.ErrorPage(err.Error()))
+			return
+		}
+	} else if _, err := a.packsService.SavePacks(newPacks, r.Form.Get("createdBy")); err != nil {
 		utils.Render(w, r, pages.ErrorPage(err.Error()))
 		return
 	}
@@ -54,3 +158,69 @@ func (a *App) handleAdminPageSetPacks(w http.ResponseWriter, r *http.Request) {
 	// redirect to admin page
 	http.Redirect(w, r, "/admin?success=1", http.StatusSeeOther)
 }
+
+func (a *App) handlePackVersionsPage(w http.ResponseWriter, r *http.Request) {
+	versions, err := a.packsService.GetVersions()
+	if err != nil {
+		utils.Render(w, r, pages.ErrorPage(err.Error()))
+		return
+	}
+
+	var liveID int64
+	if live := packs.LiveVersion(versions); live != nil {
+		liveID = live.ID
+	}
+
+	rolledBack := r.URL.Query().Get("rolledBack") == "1"
+	utils.Render(w, r, pages.PackVersionsPage(versions, liveID, rolledBack))
+}
+
+func (a *App) handlePackDiffPage(w http.ResponseWriter, r *http.Request) {
+	from, errFrom := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+	to, errTo := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+	if errFrom != nil || errTo != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		utils.Render(w, r, pages.ErrorPage("Pick two versions to compare"))
+		return
+	}
+
+	diff, err := a.packsService.DiffVersions(from, to)
+	if err != nil {
+		if errors.Is(err, packs.PackSetVersionNotFoundError) {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		utils.Render(w, r, pages.ErrorPage(err.Error()))
+		return
+	}
+
+	utils.Render(w, r, pages.PackDiffPage(diff))
+}
+
+func (a *App) handleRollbackPacksWeb(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		utils.Render(w, r, pages.ErrorPage(err.Error()))
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.Render(w, r, pages.ErrorPage("Invalid version: "+r.PathValue("id")))
+		return
+	}
+
+	if _, err := a.packsService.Rollback(id, r.Form.Get("createdBy")); err != nil {
+		utils.Render(w, r, pages.ErrorPage(err.Error()))
+		return
+	}
+
+	http.Redirect(w, r, "/admin/versions?rolledBack=1", http.StatusSeeOther)
+}
+
+// i-th value of a repeated form field, empty if missing
+func formValue(r *http.Request, field string, i int) string {
+	values := r.Form[field]
+	if i >= len(values) {
+		return ""
+	}
+	return strings.TrimSpace(values[i])
+}
@@ -9,21 +9,39 @@ import (
 	"time"
 
 	"github.com/irreal/order-packs/db"
+	"github.com/irreal/order-packs/idempotency"
+	"github.com/irreal/order-packs/models"
 	"github.com/irreal/order-packs/orders"
+	"github.com/irreal/order-packs/packaging"
 	"github.com/irreal/order-packs/packs"
+	"github.com/irreal/order-packs/postgres"
+	"github.com/irreal/order-packs/products"
 	"github.com/irreal/order-packs/web"
 )
 
 // holds the top level dependencies of the app
 type App struct {
-	orderService *orders.Service
-	packsService *packs.Service
-	database     *db.DB
-	server       *http.Server
-	stdin        io.Reader
-	stdout       io.Writer
-	stderr       io.Writer
-	configGetter func(key string) string
+	orderService       *orders.Service
+	packsService       *packs.Service
+	productsService    *products.Service
+	packagingService   *packaging.Service
+	idempotencyService *idempotency.Service
+	database           store
+	server             *http.Server
+	stdin              io.Reader
+	stdout             io.Writer
+	stderr             io.Writer
+	configGetter       func(key string) string
+}
+
+// what the app keeps in its database, the sqlite db.DB and postgres.DB both do all of it
+type store interface {
+	orders.OrderRepository
+	packs.PackRepository
+	products.ProductRepository
+	packaging.PackagingRepository
+	idempotency.KeyRepository
+	Close() error
 }
 
 type Config struct {
@@ -40,21 +58,32 @@ func NewApp(stdin io.Reader, stdout io.Writer, stderr io.Writer, configGetter fu
 	}
 }
 
-func (a *App) Initialize() error {
+// postgres when DATABASE_URL is set, the sqlite file at DB_PATH otherwise
+func (a *App) openDatabase() (store, error) {
+	if url := a.configGetter("DATABASE_URL"); url != "" {
+		return postgres.NewDB(url)
+	}
+	return db.NewDB(a.dbPath())
+}
 
-	//setup db
+func (a *App) dbPath() string {
 	dbPath := a.configGetter("DB_PATH")
 	if dbPath == "" {
 		dbPath = "./data/app.db"
 	}
+	return dbPath
+}
+
+func (a *App) Initialize() error {
 
-	database, err := db.NewDB(dbPath)
+	//setup db
+	database, err := a.openDatabase()
 	if err != nil {
 		return fmt.Errorf("failed to initialize database: %w", err)
 	}
 	a.database = database
 
-	maxOrderItemCount := 1000000
+	maxOrderItemCount := 1000000000
 
 	maxOrderItemCountString := a.configGetter("MAX_ORDER_ITEM_COUNT")
 	if maxOrderItemCountString != "" {
@@ -65,24 +94,113 @@ func (a *App) Initialize() error {
 		maxOrderItemCount = maxOrderItemCountInt
 	}
 
+	idempotencyKeyTTL := idempotency.DefaultTTL
+
+	idempotencyKeyTTLString := a.configGetter("IDEMPOTENCY_KEY_TTL")
+	if idempotencyKeyTTLString != "" {
+		idempotencyKeyTTL, err = time.ParseDuration(idempotencyKeyTTLString)
+		if err != nil || idempotencyKeyTTL <= 0 {
+			return fmt.Errorf("invalid IDEMPOTENCY_KEY_TTL %q, use a positive duration like 24h", idempotencyKeyTTLString)
+		}
+	}
+
+	// orders can go over their request as far as the packs need, and their packs are only split into containers, until a limit is set.
+	// a MAX_OVERSHOOT of 0 is a limit too, nothing may ship over the request
+	var overshoot models.OvershootLimit
+	var containers models.ContainerConfig
+	limitSettings := []struct {
+		key   string
+		value func(int)
+	}{
+		{"MAX_OVERSHOOT", func(value int) { overshoot.MaxItems = &value }},
+		{"MAX_OVERSHOOT_PERCENT", func(value int) { overshoot.MaxPercent = &value }},
+		{"CONTAINER_MAX_PACKS", func(value int) { containers.MaxPacks = value }},
+		{"CONTAINER_MAX_WEIGHT", func(value int) { containers.MaxWeight = value }},
+		{"CONTAINER_ITEM_WEIGHT", func(value int) { containers.ItemWeight = value }},
+	}
+	for _, setting := range limitSettings {
+		valueString := a.configGetter(setting.key)
+		if valueString == "" {
+			continue
+		}
+		value, err := strconv.Atoi(valueString)
+		if err != nil || value < 0 {
+			return fmt.Errorf("invalid %s %q, use a whole number that isn't negative", setting.key, valueString)
+		}
+		setting.value(value)
+	}
+
+	// orders over the overshoot limit are rejected unless this says they wait for approval
+	onOvershoot := models.OvershootAction(a.configGetter("OVERSHOOT_ACTION"))
+	if onOvershoot != "" && onOvershoot != models.OvershootReject && onOvershoot != models.OvershootApproval {
+		return fmt.Errorf("invalid OVERSHOOT_ACTION %q, use %s or %s", onOvershoot, models.OvershootReject, models.OvershootApproval)
+	}
+
 	a.orderService = orders.NewService(maxOrderItemCount, database)
+	a.orderService.Containers = containers
+	a.orderService.Overshoot = overshoot
+	a.orderService.OnOvershoot = onOvershoot
 	a.packsService = packs.NewService(database)
+	a.productsService = products.NewService(database)
+	a.packagingService = packaging.NewService(database)
+	a.orderService.Packaging = a.packagingService
+	a.idempotencyService = idempotency.NewService(idempotencyKeyTTL, database)
+
+	// orders cache a solver per pack set, drop them whenever admins change the packs or a product
+	a.packsService.OnPacksChanged(a.orderService.InvalidateSolver)
+	a.productsService.OnProductChanged(a.orderService.InvalidateLineSolvers)
 
 	mux := http.NewServeMux()
 
 	// API endpoints
 	mux.HandleFunc("/healthz", a.handleHealth)
-	mux.HandleFunc("GET /api/orders", a.handleGetLast10Orders)
-	mux.HandleFunc("POST /api/orders", a.handleCreateOrder)
+	mux.HandleFunc("GET /api/orders", a.handleListOrders)
+	mux.HandleFunc("POST /api/orders", a.idempotent(a.handleCreateOrder))
+	mux.HandleFunc("GET /api/orders/{id}", a.handleGetOrder)
+	mux.HandleFunc("GET /api/admin/orders/{id}", a.handleGetOrderAdmin)
+	mux.HandleFunc("PATCH /api/orders/{id}/status", a.handleUpdateOrderStatus)
+	mux.HandleFunc("POST /api/orders/{id}/approve", a.handleApproveOrder)
+	mux.HandleFunc("POST /api/orders/{id}/reject", a.handleRejectOrder)
+	mux.HandleFunc("GET /api/quote", a.handleGetQuote)
+	mux.HandleFunc("POST /api/quote", a.handleBatchQuote)
 	mux.HandleFunc("GET /api/packs", a.handleGetPacks)
+	mux.HandleFunc("GET /api/packs/details", a.handleGetPackDetails)
 	mux.HandleFunc("POST /api/packs", a.handleSetPacks)
+	mux.HandleFunc("PATCH /api/packs", a.handlePatchPacks)
+	mux.HandleFunc("POST /api/packs/analyze", a.handleAnalyzePacks)
+	mux.HandleFunc("POST /api/packs/simulate", a.handleSimulatePacks)
+	mux.HandleFunc("POST /api/packs/recommend", a.handleRecommendPacks)
+	mux.HandleFunc("PUT /api/packs/{size}", a.handlePutPack)
+	mux.HandleFunc("DELETE /api/packs/{size}", a.handleDeletePack)
+	mux.HandleFunc("POST /api/packs/{size}/enable", a.handleEnablePack)
+	mux.HandleFunc("POST /api/packs/{size}/disable", a.handleDisablePack)
+	mux.HandleFunc("GET /api/packs/upcoming", a.handleGetUpcomingPacks)
+	mux.HandleFunc("GET /api/packs/versions", a.handleGetPackVersions)
+	mux.HandleFunc("GET /api/packs/versions/diff", a.handleDiffPackVersions)
+	mux.HandleFunc("GET /api/packs/versions/{id}", a.handleGetPackVersion)
+	mux.HandleFunc("POST /api/packs/versions/{id}/rollback", a.handleRollbackPacks)
+	mux.HandleFunc("GET /api/packaging", a.handleGetPackaging)
+	mux.HandleFunc("PUT /api/packaging", a.handleSavePackaging)
+	mux.HandleFunc("GET /api/products", a.handleGetProducts)
+	mux.HandleFunc("POST /api/products", a.handleCreateProduct)
+	mux.HandleFunc("GET /api/products/{id}", a.handleGetProduct)
+	mux.HandleFunc("PUT /api/products/{id}", a.handleUpdateProduct)
 
 	// Web endpoints
 	mux.HandleFunc("/", a.handleHomePage)
 	mux.HandleFunc("/admin", a.handleAdminPageGet)
 	mux.HandleFunc("POST /admin", a.handleAdminPageSetPacks)
+	mux.HandleFunc("GET /admin/versions", a.handlePackVersionsPage)
+	mux.HandleFunc("GET /admin/versions/diff", a.handlePackDiffPage)
+	mux.HandleFunc("POST /admin/versions/{id}/rollback", a.handleRollbackPacksWeb)
+	mux.HandleFunc("POST /admin/orders/{id}/approve", a.handleApproveOrderWeb)
+	mux.HandleFunc("POST /admin/orders/{id}/reject", a.handleRejectOrderWeb)
 	mux.HandleFunc("GET /order", a.handleOrderPage)
+	mux.HandleFunc("GET /orders", a.handleOrderHistoryPage)
 	mux.HandleFunc("POST /order", a.handleCreateOrderWeb)
+	mux.HandleFunc("POST /order/lines", a.handleCreateLineOrderWeb)
+	mux.HandleFunc("GET /order/{id}", a.handleOrderDetailPage)
+	mux.HandleFunc("POST /order/{id}/status", a.handleUpdateOrderStatusWeb)
 	// Static files
 	web.SetupStatic(mux)
 
@@ -104,6 +222,11 @@ func (a *App) Initialize() error {
 func (a *App) Run(ctx context.Context) error {
 	fmt.Fprintf(a.stdout, "starting server on: %s\n", a.server.Addr)
 
+	// reads don't make scheduled pack sets live, this does it in the background
+	go a.packsService.RunActivation(ctx, packs.ActivationInterval, func(err error) {
+		fmt.Fprintf(a.stderr, "error activating scheduled packs: %v\n", err)
+	})
+
 	// use a goroutine
 	serverErr := make(chan error, 1)
 	go func() {
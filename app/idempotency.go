@@ -0,0 +1,114 @@
+package app
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/irreal/order-packs/idempotency"
+	"github.com/irreal/order-packs/utils"
+)
+
+// largest request body read for hashing, order requests are tiny
+const maxIdempotentBodySize = 1 << 20
+
+// Lets clients safely retry next by sending an Idempotency-Key header.
+// The first response for a key is stored and replayed for retries with the same request,
+// a different request with the same key gets 422. Server errors aren't stored, those can be retried for real
+func (a *App) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodySize))
+		if err != nil {
+			utils.WriteAPIErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		record, err := a.idempotencyService.Begin(key, requestHash(r, body))
+		if err != nil {
+			writeIdempotencyErrorResponse(w, err)
+			return
+		}
+		if record != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(record.StatusCode)
+			w.Write(record.ResponseBody)
+			return
+		}
+
+		// a panicking handler never gets to store a response, free the key so a retry isn't stuck with 409 until the lease runs out
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				if err := a.idempotencyService.Release(key); err != nil {
+					fmt.Fprintf(a.stderr, "error releasing idempotency key %q: %v\n", key, err)
+				}
+				panic(recovered)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next(recorder, r)
+
+		if recorder.statusCode >= http.StatusInternalServerError {
+			err = a.idempotencyService.Release(key)
+		} else {
+			err = a.idempotencyService.Complete(key, recorder.statusCode, recorder.body.Bytes())
+		}
+		if err != nil {
+			fmt.Fprintf(a.stderr, "error storing idempotency key %q: %v\n", key, err)
+		}
+	}
+}
+
+// what makes two requests the same, the query matters too since it can ask for alternatives
+func requestHash(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s?%s\n", r.Method, r.URL.Path, r.URL.RawQuery)
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func writeIdempotencyErrorResponse(w http.ResponseWriter, err error) {
+	if errors.Is(err, idempotency.InvalidKeyError) {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, err.Error())
+	} else if errors.Is(err, idempotency.KeyReusedError) {
+		utils.WriteAPIErrorResponse(w, http.StatusUnprocessableEntity, err.Error())
+	} else if errors.Is(err, idempotency.RequestInProgressError) {
+		utils.WriteAPIErrorResponse(w, http.StatusConflict, err.Error())
+	} else {
+		utils.WriteAPIErrorResponse(w, http.StatusInternalServerError, "internal server error")
+	}
+}
+
+// passes the response through while keeping a copy of it
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if !r.wroteHeader {
+		r.statusCode = statusCode
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
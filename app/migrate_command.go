@@ -0,0 +1,95 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/irreal/order-packs/db"
+	"github.com/irreal/order-packs/models"
+	"github.com/irreal/order-packs/postgres"
+)
+
+const migrateUsage = "usage: migrate [up | down | to <version> | status]"
+
+// the sqlite and postgres databases each have their own migrations
+type migrator interface {
+	Migrate() error
+	MigrateTo(version int) error
+	SchemaVersion() (int, error)
+	MigrationStatus() ([]models.MigrationStatus, error)
+	Close() error
+}
+
+// the database the app would use, as it is, and the latest schema version it has migrations for
+func (a *App) openMigrator() (migrator, int, error) {
+	if url := a.configGetter("DATABASE_URL"); url != "" {
+		database, err := postgres.Open(url)
+		return database, postgres.LatestSchemaVersion, err
+	}
+	database, err := db.Open(a.dbPath())
+	return database, db.LatestSchemaVersion, err
+}
+
+// runs the migrate command against the database in DATABASE_URL or DB_PATH:
+// up (default) applies every pending migration, down undoes the last one, to goes up or down to a version
+// and status lists the migrations. unlike starting the app, it never adds sample data
+func (a *App) Migrate(args []string) error {
+	database, latest, err := a.openMigrator()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch {
+	case command == "up" && len(args) <= 1:
+		err = database.Migrate()
+	case command == "down" && len(args) == 1:
+		var current int
+		current, err = database.SchemaVersion()
+		if err == nil && current > 0 {
+			err = database.MigrateTo(current - 1)
+		}
+	case command == "to" && len(args) == 2:
+		var version int
+		version, err = strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid version %q, %s", args[1], migrateUsage)
+		}
+		err = database.MigrateTo(version)
+	case command == "status" && len(args) == 1:
+		return a.printMigrationStatus(database)
+	default:
+		return fmt.Errorf("unknown migrate command %q, %s", command, migrateUsage)
+	}
+	if err != nil {
+		return err
+	}
+
+	version, err := database.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "schema version %d (latest %d)\n", version, latest)
+	return nil
+}
+
+func (a *App) printMigrationStatus(database migrator) error {
+	statuses, err := database.MigrationStatus()
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		applied := "pending"
+		if status.AppliedAt != nil {
+			applied = "applied " + status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(a.stdout, "%3d  %-50s %s\n", status.Version, status.Name, applied)
+	}
+	return nil
+}
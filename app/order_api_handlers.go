@@ -8,6 +8,7 @@ import (
 
 	"github.com/irreal/order-packs/models"
 	"github.com/irreal/order-packs/orders"
+	"github.com/irreal/order-packs/products"
 	"github.com/irreal/order-packs/utils"
 )
 
@@ -17,34 +18,138 @@ func (a *App) handleCreateOrder(w http.ResponseWriter, r *http.Request) {
 		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON request: %v", err))
 		return
 	}
+	if err := readAlternativesParam(r, &orderRequest); err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	order, err := a.createOrder(orderRequest)
+	if err != nil {
+		fmt.Fprintf(a.stderr, "error creating order: %v\n", err)
+		writeOrderErrorResponse(w, err)
+		return
+	}
+
+	utils.WriteAPISuccessResponse(w, order)
+}
+
+// single count orders are packed with the live packs, orders with lines with their products' packs
+func (a *App) createOrder(orderRequest models.OrderRequest) (*models.Order, error) {
+	if len(orderRequest.Lines) == 0 {
+		// creating an order takes stock, so a change that came due since the last activation run goes live first
+		if err := a.packsService.ActivateDue(); err != nil {
+			return nil, err
+		}
+		packs, version, err := a.packsService.GetLivePackSet()
+		if err != nil {
+			return nil, err
+		}
+		return a.orderService.CreateOrder(orderRequest, packs, version)
+	}
 
-	packs, err := a.packsService.GetPacks()
+	ids := make([]int64, len(orderRequest.Lines))
+	for i, line := range orderRequest.Lines {
+		ids[i] = line.ProductID
+	}
+	products, err := a.productsService.GetProductsByID(ids)
 	if err != nil {
+		return nil, err
+	}
+	return a.orderService.CreateLineOrder(orderRequest, products)
+}
+
+// customize response code based on error type, shared by orders and quotes
+func writeOrderErrorResponse(w http.ResponseWriter, err error) {
+	var transitionErr *orders.StatusTransitionError
+	var overshootErr *orders.OvershootLimitError
+	if errors.Is(err, orders.InvalidOrderItemCountError) || errors.Is(err, orders.InvalidObjectiveError) ||
+		errors.Is(err, orders.InvalidAlternativesError) || errors.Is(err, orders.InvalidStatusError) ||
+		errors.Is(err, orders.InvalidOrderQueryError) || errors.Is(err, orders.InvalidPackSetError) ||
+		errors.Is(err, orders.InvalidRecommendationError) || errors.Is(err, orders.InvalidOrderLinesError) ||
+		errors.Is(err, orders.InvalidFulfillmentError) || errors.Is(err, orders.InvalidOvershootLimitError) ||
+		errors.Is(err, products.ProductNotFoundError) {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, err.Error())
+	} else if errors.Is(err, orders.OrderNotFoundError) {
+		utils.WriteAPIErrorResponse(w, http.StatusNotFound, err.Error())
+	} else if errors.Is(err, orders.InsufficientStockError) || errors.Is(err, orders.OrderStatusChangedError) ||
+		errors.Is(err, orders.NotEnoughOrdersError) || errors.Is(err, orders.ContainerCapacityError) ||
+		errors.Is(err, orders.BackorderError) || errors.As(err, &transitionErr) || errors.As(err, &overshootErr) {
+		utils.WriteAPIErrorResponse(w, http.StatusConflict, err.Error())
+	} else {
 		utils.WriteAPIErrorResponse(w, http.StatusInternalServerError, "internal server error")
+	}
+}
+
+func (a *App) handleListOrders(w http.ResponseWriter, r *http.Request) {
+	query, err := readOrderQuery(r.URL.Query())
+	if err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	order, err := a.orderService.CreateOrder(orderRequest, packs)
+	page, err := a.orderService.ListOrders(query, r.URL.Query().Get("cursor"))
 	if err != nil {
-		fmt.Fprintf(a.stderr, "error creating order: %v\n", err)
+		writeOrderErrorResponse(w, err)
+		return
+	}
+	utils.WriteAPISuccessResponse(w, page)
+}
 
-		// customize response code based on error type
-		if errors.Is(err, orders.InvalidOrderItemCountError) {
-			utils.WriteAPIErrorResponse(w, http.StatusBadRequest, err.Error())
-		} else {
-			utils.WriteAPIErrorResponse(w, http.StatusInternalServerError, "internal server error")
-		}
+// customers only get orders by their public id
+func (a *App) handleGetOrder(w http.ResponseWriter, r *http.Request) {
+	order, err := a.orderService.FindPublicOrder(r.PathValue("id"))
+	if err != nil {
+		writeOrderErrorResponse(w, err)
 		return
 	}
+	utils.WriteAPISuccessResponse(w, order)
+}
 
+// admins can use the sequential id too
+func (a *App) handleGetOrderAdmin(w http.ResponseWriter, r *http.Request) {
+	order, err := a.orderService.FindOrder(r.PathValue("id"))
+	if err != nil {
+		writeOrderErrorResponse(w, err)
+		return
+	}
 	utils.WriteAPISuccessResponse(w, order)
 }
 
-func (a *App) handleGetLast10Orders(w http.ResponseWriter, r *http.Request) {
-	orders, err := a.orderService.GetLast10Orders()
+func (a *App) handleUpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Status models.OrderStatus `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON request: %v", err))
+		return
+	}
+
+	order, err := a.orderService.UpdateOrderStatus(r.PathValue("id"), request.Status)
 	if err != nil {
-		utils.WriteAPIErrorResponse(w, http.StatusInternalServerError, "internal server error")
+		fmt.Fprintf(a.stderr, "error updating order status: %v\n", err)
+		writeOrderErrorResponse(w, err)
 		return
 	}
-	utils.WriteAPISuccessResponse(w, orders)
+	utils.WriteAPISuccessResponse(w, order)
+}
+
+// orders over their overshoot limit that wait for approval, approving makes them new
+func (a *App) handleApproveOrder(w http.ResponseWriter, r *http.Request) {
+	order, err := a.orderService.ApproveOrder(r.PathValue("id"))
+	if err != nil {
+		fmt.Fprintf(a.stderr, "error approving order: %v\n", err)
+		writeOrderErrorResponse(w, err)
+		return
+	}
+	utils.WriteAPISuccessResponse(w, order)
+}
+
+func (a *App) handleRejectOrder(w http.ResponseWriter, r *http.Request) {
+	order, err := a.orderService.RejectOrder(r.PathValue("id"))
+	if err != nil {
+		fmt.Fprintf(a.stderr, "error rejecting order: %v\n", err)
+		writeOrderErrorResponse(w, err)
+		return
+	}
+	utils.WriteAPISuccessResponse(w, order)
 }
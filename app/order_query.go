@@ -0,0 +1,83 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/irreal/order-packs/models"
+)
+
+// order history filters from query parameters, shared by the api and the history page.
+// status can be repeated or comma separated, dates are RFC 3339 or plain days, createdTo is exclusive
+func readOrderQuery(values url.Values) (models.OrderQuery, error) {
+	query := models.OrderQuery{Sort: models.OrderSort(values.Get("sort"))}
+
+	for _, statuses := range values["status"] {
+		for status := range strings.SplitSeq(statuses, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				query.Statuses = append(query.Statuses, models.OrderStatus(status))
+			}
+		}
+	}
+
+	var err error
+	if query.CreatedFrom, err = readTimeParam(values, "createdFrom"); err != nil {
+		return query, err
+	}
+	if query.CreatedTo, err = readTimeParam(values, "createdTo"); err != nil {
+		return query, err
+	}
+
+	ints := []struct {
+		name   string
+		target *int
+	}{
+		{"minRequested", &query.MinRequested},
+		{"maxRequested", &query.MaxRequested},
+		{"minShipped", &query.MinShipped},
+		{"maxShipped", &query.MaxShipped},
+		{"limit", &query.Limit},
+	}
+	for _, param := range ints {
+		if *param.target, err = readIntParam(values, param.name); err != nil {
+			return query, err
+		}
+	}
+	packSize, err := readIntParam(values, "packSize")
+	if err != nil {
+		return query, err
+	}
+	query.PackSize = models.Pack(packSize)
+
+	return query, nil
+}
+
+func readIntParam(values url.Values, name string) (int, error) {
+	value := values.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid %s: %q", name, value)
+	}
+	return n, nil
+}
+
+func readTimeParam(values url.Values, name string) (time.Time, error) {
+	value := values.Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid %s: %q, use RFC 3339 or YYYY-MM-DD", name, value)
+	}
+	return t, nil
+}
@@ -1,11 +1,14 @@
 package app
 
 import (
+	"errors"
+	"maps"
 	"net/http"
 	"strconv"
 
 	"github.com/irreal/order-packs/app/pages"
 	"github.com/irreal/order-packs/models"
+	"github.com/irreal/order-packs/orders"
 	"github.com/irreal/order-packs/utils"
 )
 
@@ -22,10 +25,22 @@ func (a *App) handleOrderPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	imminentChange, err := a.packsService.GetImminentChange()
+	if err != nil {
+		utils.Render(w, r, pages.ErrorPage(err.Error()))
+		return
+	}
+
+	products, err := a.productsService.GetProducts()
+	if err != nil {
+		utils.Render(w, r, pages.ErrorPage(err.Error()))
+		return
+	}
+
 	maxCount := int32(a.orderService.MaxOrderItemCount)
 	success := r.URL.Query().Get("success") == "1"
 
-	utils.Render(w, r, pages.OrderPage(orders, packs, maxCount, success))
+	utils.Render(w, r, pages.OrderPage(orders, packs, products, maxCount, success, imminentChange))
 }
 
 func (a *App) handleCreateOrderWeb(w http.ResponseWriter, r *http.Request) {
@@ -48,21 +63,119 @@ func (a *App) handleCreateOrderWeb(w http.ResponseWriter, r *http.Request) {
 	}
 
 	orderRequest := models.OrderRequest{
-		ItemCount: amount,
+		ItemCount:   amount,
+		Fulfillment: models.Fulfillment(r.Form.Get("fulfillment")),
 	}
 
-	packs, err := a.packsService.GetPacks()
+	_, err = a.createOrder(orderRequest)
 	if err != nil {
 		utils.Render(w, r, pages.ErrorPage(err.Error()))
 		return
 	}
 
-	_, err = a.orderService.CreateOrder(orderRequest, packs)
-	if err != nil {
+	// Redirect back to the order page with success
+	http.Redirect(w, r, "/order?success=1", http.StatusSeeOther)
+}
+
+// the product form has a product and a count field per product, products left empty aren't ordered
+func (a *App) handleCreateLineOrderWeb(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		utils.Render(w, r, pages.ErrorPage(err.Error()))
+		return
+	}
+
+	productIDs, counts := r.Form["product"], r.Form["count"]
+	if len(productIDs) != len(counts) {
+		utils.Render(w, r, pages.ErrorPage("Every product needs a count"))
+		return
+	}
+
+	var orderRequest models.OrderRequest
+	for i, countStr := range counts {
+		if countStr == "" || countStr == "0" {
+			continue
+		}
+		productID, err := strconv.ParseInt(productIDs[i], 10, 64)
+		if err != nil {
+			utils.Render(w, r, pages.ErrorPage("Invalid product"))
+			return
+		}
+		count, err := strconv.Atoi(countStr)
+		if err != nil {
+			utils.Render(w, r, pages.ErrorPage("Invalid amount format"))
+			return
+		}
+		orderRequest.Lines = append(orderRequest.Lines, models.OrderLineRequest{ProductID: productID, ItemCount: count})
+	}
+	if len(orderRequest.Lines) == 0 {
+		utils.Render(w, r, pages.ErrorPage("Pick an amount of at least one product"))
+		return
+	}
+
+	if _, err := a.createOrder(orderRequest); err != nil {
 		utils.Render(w, r, pages.ErrorPage(err.Error()))
 		return
 	}
 
-	// Redirect back to the order page with success
 	http.Redirect(w, r, "/order?success=1", http.StatusSeeOther)
 }
+
+func (a *App) handleOrderHistoryPage(w http.ResponseWriter, r *http.Request) {
+	filters := r.URL.Query()
+	query, err := readOrderQuery(filters)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		utils.Render(w, r, pages.ErrorPage(err.Error()))
+		return
+	}
+
+	page, err := a.orderService.ListOrders(query, filters.Get("cursor"))
+	if err != nil {
+		utils.Render(w, r, pages.ErrorPage(err.Error()))
+		return
+	}
+
+	// the next page keeps the filters, only the cursor moves
+	nextURL := ""
+	if page.NextCursor != "" {
+		next := maps.Clone(filters)
+		next.Set("cursor", page.NextCursor)
+		nextURL = "/orders?" + next.Encode()
+	}
+
+	utils.Render(w, r, pages.OrderHistoryPage(page, filters, nextURL))
+}
+
+func (a *App) handleOrderDetailPage(w http.ResponseWriter, r *http.Request) {
+	order, err := a.orderService.FindPublicOrder(r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, orders.OrderNotFoundError) {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		utils.Render(w, r, pages.ErrorPage(err.Error()))
+		return
+	}
+
+	utils.Render(w, r, pages.OrderDetailPage(order))
+}
+
+// html forms can't send PATCH, so the web page posts the new status
+func (a *App) handleUpdateOrderStatusWeb(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		utils.Render(w, r, pages.ErrorPage(err.Error()))
+		return
+	}
+
+	order, err := a.orderService.UpdateOrderStatus(r.PathValue("id"), models.OrderStatus(r.Form.Get("status")))
+	if err != nil {
+		utils.Render(w, r, pages.ErrorPage(err.Error()))
+		return
+	}
+
+	// back to wherever the button was, the order list or the order's own page
+	redirect := "/order/" + order.PublicID
+	if r.Form.Get("from") == "list" {
+		redirect = "/order"
+	}
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/irreal/order-packs/models"
+	"github.com/irreal/order-packs/packaging"
+	"github.com/irreal/order-packs/utils"
+)
+
+func (a *App) handleGetPackaging(w http.ResponseWriter, r *http.Request) {
+	hierarchy, err := a.packagingService.GetPackaging()
+	if err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	utils.WriteAPISuccessResponse(w, hierarchy)
+}
+
+// replaces every case and pallet type, an empty body of {} removes them
+func (a *App) handleSavePackaging(w http.ResponseWriter, r *http.Request) {
+	var hierarchy models.PackagingHierarchy
+	if err := json.NewDecoder(r.Body).Decode(&hierarchy); err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, "invalid JSON format")
+		return
+	}
+
+	saved, err := a.packagingService.SavePackaging(hierarchy)
+	if errors.Is(err, packaging.InvalidPackagingError) {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(a.stderr, "error saving packaging: %v\n", err)
+		utils.WriteAPIErrorResponse(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	utils.WriteAPISuccessResponse(w, saved)
+}
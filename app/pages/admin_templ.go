@@ -9,11 +9,14 @@ import "github.com/a-h/templ"
 import templruntime "github.com/a-h/templ/runtime"
 
 import (
+	"fmt"
 	"github.com/irreal/order-packs/models"
 	"github.com/irreal/order-packs/web"
 )
 
-func AdminPage(packs models.Packs, success bool) templ.Component {
+// upcoming are the scheduled pack sets that haven't gone live yet, soonest first.
+// awaitingApproval are the orders over their overshoot limit, oldest first
+func AdminPage(packs []models.PackDetails, upcoming []*models.PackSetVersion, awaitingApproval []*models.Order, success bool) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -34,7 +37,7 @@ func AdminPage(packs models.Packs, success bool) templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = web.BaseLayout(adminPage(packs, success)).Render(ctx, templ_7745c5c3_Buffer)
+		templ_7745c5c3_Err = web.BaseLayout(adminPage(packs, upcoming, awaitingApproval, success)).Render(ctx, templ_7745c5c3_Buffer)
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -42,7 +45,7 @@ func AdminPage(packs models.Packs, success bool) templ.Component {
 	})
 }
 
-func adminPage(packs models.Packs, success bool) templ.Component {
+func adminPage(packs []models.PackDetails, upcoming []*models.PackSetVersion, awaitingApproval []*models.Order, success bool) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -63,7 +66,7 @@ func adminPage(packs models.Packs, success bool) templ.Component {
 			templ_7745c5c3_Var2 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<!-- Header Section --><div class=\"bg-gradient-to-r from-purple-500 to-pink-500 text-white py-16\"><div class=\"container mx-auto px-4 text-center\"><div class=\"text-8xl mb-6 animate-bounce\">🎛️</div><h1 class=\"text-5xl font-bold mb-4\">Balloon Pack Admin Center</h1><p class=\"text-xl opacity-90\">Managing balloon packs with style and whimsy!</p><div class=\"mt-4 bg-yellow-200 text-yellow-800 px-4 py-2 rounded-lg inline-block\"><span class=\"text-sm font-medium\">🔓 No auth required - we trust you! (This is a toy project for a job application, anyone can feel free to edit packs)</span></div><div class=\"mt-6\"><a href=\"/order\" class=\"btn btn-outline btn-lg border-white text-white hover:bg-white hover:text-purple-600 hover:shadow-lg transform hover:scale-105 transition-all\">🛒 Back to Order Page</a></div></div></div><!-- Success Message -->")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<!-- Header Section --><div class=\"bg-gradient-to-r from-purple-500 to-pink-500 text-white py-16\"><div class=\"container mx-auto px-4 text-center\"><div class=\"text-8xl mb-6 animate-bounce\">🎛️</div><h1 class=\"text-5xl font-bold mb-4\">Balloon Pack Admin Center</h1><p class=\"text-xl opacity-90\">Managing balloon packs with style and whimsy!</p><div class=\"mt-4 bg-yellow-200 text-yellow-800 px-4 py-2 rounded-lg inline-block\"><span class=\"text-sm font-medium\">🔓 No auth required - we trust you! (This is a toy project for a job application, anyone can feel free to edit packs)</span></div><div class=\"mt-6\"><a href=\"/order\" class=\"btn btn-outline btn-lg border-white text-white hover:bg-white hover:text-purple-600 hover:shadow-lg transform hover:scale-105 transition-all\">🛒 Back to Order Page</a> <a href=\"/admin/versions\" class=\"btn btn-outline btn-lg border-white text-white hover:bg-white hover:text-purple-600 hover:shadow-lg transform hover:scale-105 transition-all\">🕰️ Pack History</a></div></div></div><!-- Success Message -->")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -73,7 +76,216 @@ func adminPage(packs models.Packs, success bool) templ.Component {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<!-- Pack Management Form Section --><div class=\"bg-gradient-to-br from-purple-50 to-pink-50 py-16\"><div class=\"container mx-auto px-4\"><div class=\"text-center mb-12\"><div class=\"text-6xl mb-6 animate-spin\" style=\"animation-duration: 4s;\">⚙️</div><h2 class=\"text-4xl font-bold text-gray-800 mb-4\">Manage Pack Sizes</h2><p class=\"text-xl text-gray-600\">View current packs and add new balloon pack sizes</p></div><div class=\"max-w-4xl mx-auto\"><div class=\"card bg-white shadow-2xl border-2 border-purple-200\"><div class=\"card-body\"><form id=\"adminForm\" class=\"space-y-8\" action=\"/admin\" method=\"post\"><!-- Hidden inputs for all current packs (will be managed by JavaScript) --><div id=\"packInputs\"></div><!-- Current Packs Display --><div><h3 class=\"text-2xl font-bold text-center mb-6 text-purple-600\">📦 Current Pack Sizes</h3><div class=\"max-w-6xl mx-auto mb-6\"><div id=\"packsDisplay\" class=\"flex flex-wrap justify-center gap-4 mb-4\"><!-- Packs will be dynamically populated here --></div><div id=\"emptyState\" class=\"text-center text-gray-500 mb-4\" style=\"display: none;\"><div class=\"text-4xl mb-2\">📭</div><p class=\"text-lg\">No pack sizes configured yet. Add some below!</p></div></div></div><!-- Add New Pack Section --><div><h3 class=\"text-2xl font-bold text-center mb-6 text-purple-600\">➕ Add New Pack Size</h3><div class=\"form-control\"><label class=\"label\"><span class=\"label-text text-lg font-medium\">New pack size (number of balloons)</span></label><div class=\"input-group justify-center text-center p-4\"><input type=\"number\" id=\"newPackSize\" placeholder=\"Enter pack size...\" class=\"input input-bordered input-lg w-full max-w-xs text-center text-2xl font-bold\" min=\"1\" max=\"1000000\"></div><label class=\"label\"><span class=\"label-text-alt text-gray-500\">Enter a positive number for the new pack size</span></label></div><div class=\"text-center mt-4\"><button type=\"button\" id=\"addPackBtn\" class=\"btn btn-secondary btn-md\"><span class=\"text-xl mr-2\">➕</span> Add to List</button></div></div><!-- Quick Add Buttons --><div><h3 class=\"text-2xl font-bold text-center mb-6 text-purple-600\">⚡ Quick Add Popular Sizes</h3><div class=\"grid grid-cols-2 md:grid-cols-4 gap-4 mb-6\"><button type=\"button\" class=\"quick-add-btn btn btn-outline btn-secondary btn-lg p-6 flex flex-col items-center justify-center hover:scale-105 transform transition-all\" data-size=\"100\"><span class=\"font-bold\">100</span> <span class=\"text-xs\">Mini Pack</span></button> <button type=\"button\" class=\"quick-add-btn btn btn-outline btn-secondary btn-lg p-6 flex flex-col items-center justify-center hover:scale-105 transform transition-all\" data-size=\"250\"><span class=\"font-bold\">250</span> <span class=\"text-xs\">Less Mini Pack</span></button> <button type=\"button\" class=\"quick-add-btn btn btn-outline btn-secondary btn-lg p-6 flex flex-col items-center justify-center hover:scale-105 transform transition-all\" data-size=\"500\"><span class=\"font-bold\">500</span> <span class=\"text-xs\">Party Pack</span></button> <button type=\"button\" class=\"quick-add-btn btn btn-outline btn-secondary btn-lg p-6 flex flex-col items-center justify-center hover:scale-105 transform transition-all\" data-size=\"1000\"><span class=\"font-bold\">1000</span> <span class=\"text-xs\">Event Pack</span></button> <button type=\"button\" class=\"quick-add-btn btn btn-outline btn-secondary btn-lg p-6 flex flex-col items-center justify-center hover:scale-105 transform transition-all\" data-size=\"2500\"><span class=\"font-bold\">2500</span> <span class=\"text-xs\">Mega Pack</span></button></div></div><!-- Submit Button --><div class=\"text-center\"><button type=\"submit\" id=\"submitPack\" class=\"btn btn-primary btn-lg text-white shadow-lg hover:shadow-xl transform hover:scale-105 transition-all\"><span class=\"text-2xl mr-2\">💾</span> Save Pack Configuration <span class=\"text-2xl ml-2 animate-bounce\">⚙️</span></button><p class=\"text-sm text-gray-500 mt-4\">* New pack sizes will be available immediately for customers to order</p></div></form></div></div></div></div></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<!-- Upcoming Changes -->")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		if len(upcoming) > 0 {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<div class=\"bg-white py-8\"><div class=\"container mx-auto px-4 max-w-4xl\"><h2 class=\"text-2xl font-bold text-center mb-4 text-purple-600\">📅 Upcoming Pack Changes</h2>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			for _, version := range upcoming {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "<div class=\"alert bg-yellow-50 border-2 border-yellow-200 mb-2\"><div><div class=\"font-bold\">From ")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var3 string
+				templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(version.EffectiveFrom.Format("2006-01-02 15:04"))
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `app/pages/admin.templ`, Line: 60, Col: 63}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, " UTC: ")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var4 string
+				templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(formatPackSizes(models.PackSizes(version.Packs)))
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `app/pages/admin.templ`, Line: 60, Col: 121}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "</div><div class=\"text-sm opacity-75\">Version ")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var5 string
+				templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("%d", version.ID))
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `app/pages/admin.templ`, Line: 63, Col: 47}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, ", scheduled by ")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var6 string
+				templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinStringErrs(createdBy(version.CreatedBy))
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `app/pages/admin.templ`, Line: 63, Col: 94}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, " on ")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var7 string
+				templ_7745c5c3_Var7, templ_7745c5c3_Err = templ.JoinStringErrs(version.CreatedAt.Format("2006-01-02 15:04"))
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `app/pages/admin.templ`, Line: 63, Col: 146}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var7))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "</div></div></div>")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "</div></div>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "<!-- Orders Awaiting Approval -->")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		if len(awaitingApproval) > 0 {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "<div class=\"bg-white py-8\"><div class=\"container mx-auto px-4 max-w-4xl\"><h2 class=\"text-2xl font-bold text-center mb-4 text-purple-600\">✋ Orders Waiting for Approval</h2><p class=\"text-sm text-gray-500 text-center mb-4\">These orders ship more than their overshoot limit allows. Approved orders go on to packing, rejected ones put their packs back in stock.</p>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			for _, order := range awaitingApproval {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 14, "<div class=\"alert bg-yellow-50 border-2 border-yellow-200 mb-2 flex justify-between\"><div><div class=\"font-bold\"><a href=\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var8 templ.SafeURL
+				templ_7745c5c3_Var8, templ_7745c5c3_Err = templ.JoinURLErrs(templ.SafeURL("/order/" + order.PublicID))
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `app/pages/admin.templ`, Line: 81, Col: 59}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var8))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 15, "\" class=\"link\">")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var9 string
+				templ_7745c5c3_Var9, templ_7745c5c3_Err = templ.JoinStringErrs(order.PublicID)
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `app/pages/admin.templ`, Line: 81, Col: 91}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var9))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 16, "</a></div><div class=\"text-sm opacity-75\">Requested ")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var10 string
+				templ_7745c5c3_Var10, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("%d", order.RequestedItemCount))
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `app/pages/admin.templ`, Line: 84, Col: 63}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var10))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 17, ", ships ")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var11 string
+				templ_7745c5c3_Var11, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("%d", order.ShippedItemCount))
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `app/pages/admin.templ`, Line: 84, Col: 116}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var11))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 18, " (")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var12 string
+				templ_7745c5c3_Var12, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("%d", order.ShippedItemCount-order.RequestedItemCount))
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `app/pages/admin.templ`, Line: 85, Col: 77}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var12))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 19, " extra) | Placed ")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var13 string
+				templ_7745c5c3_Var13, templ_7745c5c3_Err = templ.JoinStringErrs(order.CreatedAt.Format("2006-01-02 15:04"))
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `app/pages/admin.templ`, Line: 85, Col: 140}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var13))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 20, "</div></div><div class=\"flex gap-2\"><form action=\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var14 templ.SafeURL
+				templ_7745c5c3_Var14, templ_7745c5c3_Err = templ.JoinURLErrs(templ.SafeURL("/admin/orders/" + order.PublicID + "/approve"))
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `app/pages/admin.templ`, Line: 89, Col: 83}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var14))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 21, "\" method=\"post\"><button type=\"submit\" class=\"btn btn-success btn-sm text-white\">Approve</button></form><form action=\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var15 templ.SafeURL
+				templ_7745c5c3_Var15, templ_7745c5c3_Err = templ.JoinURLErrs(templ.SafeURL("/admin/orders/" + order.PublicID + "/reject"))
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `app/pages/admin.templ`, Line: 92, Col: 82}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var15))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 22, "\" method=\"post\"><button type=\"submit\" class=\"btn btn-outline btn-error btn-sm\">Reject</button></form></div></div>")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 23, "</div></div>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 24, "<!-- Pack Management Form Section --><div class=\"bg-gradient-to-br from-purple-50 to-pink-50 py-16\"><div class=\"container mx-auto px-4\"><div class=\"text-center mb-12\"><div class=\"text-6xl mb-6 animate-spin\" style=\"animation-duration: 4s;\">⚙️</div><h2 class=\"text-4xl font-bold text-gray-800 mb-4\">Manage Pack Sizes</h2><p class=\"text-xl text-gray-600\">View current packs and add new balloon pack sizes</p></div><div class=\"max-w-4xl mx-auto\"><div class=\"card bg-white shadow-2xl border-2 border-purple-200\"><div class=\"card-body\"><form id=\"adminForm\" class=\"space-y-8\" action=\"/admin\" method=\"post\"><!-- Current Packs Display, every pack has its own inputs (managed by JavaScript) --><div><h3 class=\"text-2xl font-bold text-center mb-6 text-purple-600\">📦 Current Pack Sizes</h3><p class=\"text-center text-gray-500 mb-6\">Costs are in cents, weights in grams and dimensions in millimeters</p><div class=\"max-w-6xl mx-auto mb-6\"><div id=\"packsDisplay\" class=\"grid grid-cols-1 md:grid-cols-2 gap-4 mb-4\"><!-- Packs will be dynamically populated here --></div><div id=\"emptyState\" class=\"text-center text-gray-500 mb-4\" style=\"display: none;\"><div class=\"text-4xl mb-2\">📭</div><p class=\"text-lg\">No pack sizes configured yet. Add some below!</p></div></div></div><!-- Add New Pack Section --><div><h3 class=\"text-2xl font-bold text-center mb-6 text-purple-600\">➕ Add New Pack Size</h3><div class=\"form-control\"><label class=\"label\"><span class=\"label-text text-lg font-medium\">New pack size (number of balloons)</span></label><div class=\"input-group justify-center text-center p-4\"><input type=\"number\" id=\"newPackSize\" placeholder=\"Enter pack size...\" class=\"input input-bordered input-lg w-full max-w-xs text-center text-2xl font-bold\" min=\"1\" max=\"1000000\"></div><label class=\"label\"><span class=\"label-text-alt text-gray-500\">Enter a positive number for the new pack size</span></label></div><div class=\"text-center mt-4\"><button type=\"button\" id=\"addPackBtn\" class=\"btn btn-secondary btn-md\"><span class=\"text-xl mr-2\">➕</span> Add to List</button></div></div><!-- Quick Add Buttons --><div><h3 class=\"text-2xl font-bold text-center mb-6 text-purple-600\">⚡ Quick Add Popular Sizes</h3><div class=\"grid grid-cols-2 md:grid-cols-4 gap-4 mb-6\"><button type=\"button\" class=\"quick-add-btn btn btn-outline btn-secondary btn-lg p-6 flex flex-col items-center justify-center hover:scale-105 transform transition-all\" data-size=\"100\"><span class=\"font-bold\">100</span> <span class=\"text-xs\">Mini Pack</span></button> <button type=\"button\" class=\"quick-add-btn btn btn-outline btn-secondary btn-lg p-6 flex flex-col items-center justify-center hover:scale-105 transform transition-all\" data-size=\"250\"><span class=\"font-bold\">250</span> <span class=\"text-xs\">Less Mini Pack</span></button> <button type=\"button\" class=\"quick-add-btn btn btn-outline btn-secondary btn-lg p-6 flex flex-col items-center justify-center hover:scale-105 transform transition-all\" data-size=\"500\"><span class=\"font-bold\">500</span> <span class=\"text-xs\">Party Pack</span></button> <button type=\"button\" class=\"quick-add-btn btn btn-outline btn-secondary btn-lg p-6 flex flex-col items-center justify-center hover:scale-105 transform transition-all\" data-size=\"1000\"><span class=\"font-bold\">1000</span> <span class=\"text-xs\">Event Pack</span></button> <button type=\"button\" class=\"quick-add-btn btn btn-outline btn-secondary btn-lg p-6 flex flex-col items-center justify-center hover:scale-105 transform transition-all\" data-size=\"2500\"><span class=\"font-bold\">2500</span> <span class=\"text-xs\">Mega Pack</span></button></div></div><!-- Pack Check, filled in by JavaScript from /api/packs/analyze whenever the packs change --><div><h3 class=\"text-2xl font-bold text-center mb-6 text-purple-600\">🔍 Pack Check</h3><div id=\"packAnalysis\" class=\"max-w-2xl mx-auto text-gray-600\"></div></div><!-- What-if, past orders replayed with the packs above by /api/packs/simulate --><div><h3 class=\"text-2xl font-bold text-center mb-6 text-purple-600\">📊 Replay Past Orders</h3><p class=\"text-center text-gray-500 mb-4\">See how these packs would have done on every order so far, nothing is saved</p><div class=\"text-center mb-4\"><button type=\"button\" id=\"simulateBtn\" class=\"btn btn-secondary btn-md\"><span class=\"text-xl mr-2\">🔮</span> Replay Orders</button></div><div id=\"packSimulation\" class=\"max-w-2xl mx-auto text-gray-600\"></div></div><!-- Suggestions from the item counts of past orders, by /api/packs/recommend --><div><h3 class=\"text-2xl font-bold text-center mb-6 text-purple-600\">💡 Suggest Packs</h3><p class=\"text-center text-gray-500 mb-4\">Let past orders pick the pack sizes, empty fields use defaults</p><div class=\"grid grid-cols-2 md:grid-cols-4 gap-2 max-w-2xl mx-auto mb-4\"><label class=\"form-control\"><span class=\"label-text text-xs text-gray-600\">At most this many sizes</span> <input type=\"number\" id=\"suggestMaxSizes\" min=\"1\" max=\"8\" placeholder=\"5\" class=\"input input-bordered input-sm\"></label> <label class=\"form-control\"><span class=\"label-text text-xs text-gray-600\">Smallest size</span> <input type=\"number\" id=\"suggestMinSize\" min=\"1\" placeholder=\"1\" class=\"input input-bordered input-sm\"></label> <label class=\"form-control\"><span class=\"label-text text-xs text-gray-600\">Largest size</span> <input type=\"number\" id=\"suggestMaxSize\" min=\"1\" placeholder=\"largest order\" class=\"input input-bordered input-sm\"></label> <label class=\"form-control\"><span class=\"label-text text-xs text-gray-600\">Best at</span> <select id=\"suggestGoal\" class=\"select select-bordered select-sm\"><option value=\"overshoot\">Fewest extra balloons</option> <option value=\"cost\">Lowest cost</option></select></label> <label class=\"form-control suggest-cost\" style=\"display: none;\"><span class=\"label-text text-xs text-gray-600\">Cost of an extra balloon (¢)</span> <input type=\"number\" id=\"suggestItemCost\" min=\"0\" value=\"1\" class=\"input input-bordered input-sm\"></label> <label class=\"form-control suggest-cost\" style=\"display: none;\"><span class=\"label-text text-xs text-gray-600\">Cost of a pack (¢)</span> <input type=\"number\" id=\"suggestPackCost\" min=\"0\" value=\"50\" class=\"input input-bordered input-sm\"></label></div><div class=\"text-center mb-4\"><button type=\"button\" id=\"suggestBtn\" class=\"btn btn-secondary btn-md\"><span class=\"text-xl mr-2\">💡</span> Suggest Packs</button></div><div id=\"packSuggestion\" class=\"max-w-2xl mx-auto text-gray-600 text-center\"></div></div><!-- Submit Button --><div class=\"form-control max-w-xs mx-auto\"><label class=\"label\"><span class=\"label-text font-medium\">Your name, kept with this version of the packs</span></label> <input type=\"text\" name=\"createdBy\" placeholder=\"e.g. Jane\" class=\"input input-bordered\"></div><div class=\"form-control max-w-xs mx-auto\"><label class=\"label\"><span class=\"label-text font-medium\">Takes effect from (UTC), empty for right away</span></label> <input type=\"datetime-local\" name=\"effectiveFrom\" class=\"input input-bordered\"></div><div class=\"text-center\"><button type=\"submit\" id=\"submitPack\" class=\"btn btn-primary btn-lg text-white shadow-lg hover:shadow-xl transform hover:scale-105 transition-all\"><span class=\"text-2xl mr-2\">💾</span> Save Pack Configuration <span class=\"text-2xl ml-2 animate-bounce\">⚙️</span></button><p class=\"text-sm text-gray-500 mt-4\">* New pack sizes will be available immediately for customers to order</p></div></form></div></div></div></div></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -81,7 +293,7 @@ func adminPage(packs models.Packs, success bool) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<script>\n        // Interactive admin form functionality\n        document.addEventListener('DOMContentLoaded', function() {\n            const quickAddButtons = document.querySelectorAll('.quick-add-btn');\n            const newPackSizeInput = document.getElementById('newPackSize');\n            const addPackBtn = document.getElementById('addPackBtn');\n            const packsDisplay = document.getElementById('packsDisplay');\n            const emptyState = document.getElementById('emptyState');\n            const packInputsContainer = document.getElementById('packInputs');\n            \n            // Initialize with server-provided packs\n            let currentPacks = JSON.parse(document.getElementById('packs').textContent);\n            \n            function getPackIcon(size) {\n                if (size <= 500) return '🎈';\n                if (size <= 1000) return '🎈🎈';\n                if (size <= 2000) return '🎈🎈🎈';\n                return '🎈🎈🎈🎈';\n            }\n            \n            function getPackLabel(size) {\n                if (size <= 500) return 'Party';\n                if (size <= 1000) return 'Event';\n                if (size <= 2000) return 'Mega';\n                return 'Ultimate';\n            }\n            \n            function renderPacks() {\n                // Sort packs numerically\n                currentPacks.sort((a, b) => parseInt(a) - parseInt(b));\n                \n                // Clear display\n                packsDisplay.innerHTML = '';\n                packInputsContainer.innerHTML = '';\n                \n                if (currentPacks.length === 0) {\n                    emptyState.style.display = 'block';\n                    return;\n                }\n                \n                emptyState.style.display = 'none';\n                \n                // Render pack badges\n                currentPacks.forEach((pack, index) => {\n                    const size = parseInt(pack);\n                    \n                    // Create display badge\n                    const badge = document.createElement('div');\n                    badge.className = 'badge badge-lg bg-gradient-to-r from-blue-400 to-purple-500 text-white p-4 shadow-lg transform hover:scale-105 transition-all relative group';\n                    badge.innerHTML = `\n                        <span class=\"text-lg mr-2\">${getPackIcon(size)}</span>\n                        <span class=\"font-bold text-lg\">${size}</span>\n                        <span class=\"text-sm ml-2 opacity-90\">${getPackLabel(size)}</span>\n                        <button type=\"button\" class=\"ml-2 text-red-300 hover:text-red-100 font-bold opacity-0 group-hover:opacity-100 transition-opacity\" onclick=\"removePack(${index})\">×</button>\n                    `;\n                    packsDisplay.appendChild(badge);\n                    \n                    // Create hidden input\n                    const input = document.createElement('input');\n                    input.type = 'hidden';\n                    input.name = 'packs';\n                    input.value = pack;\n                    packInputsContainer.appendChild(input);\n                });\n            }\n            \n            // Global function to remove pack\n            window.removePack = function(index) {\n                currentPacks.splice(index, 1);\n                renderPacks();\n            };\n            \n            function addPack(size) {\n                const sizeStr = size.toString();\n                if (currentPacks.includes(sizeStr)) {\n                    alert('This pack size already exists!');\n                    return false;\n                }\n                currentPacks.push(sizeStr);\n                renderPacks();\n                return true;\n            }\n            \n            // Handle quick add buttons\n            quickAddButtons.forEach(button => {\n                button.addEventListener('click', function() {\n                    const size = parseInt(this.dataset.size);\n                    if (addPack(size)) {\n                        newPackSizeInput.value = '';\n                        // Reset visual state\n                        quickAddButtons.forEach(btn => {\n                            btn.classList.remove('btn-secondary');\n                            btn.classList.add('btn-outline');\n                        });\n                    }\n                });\n            });\n            \n            // Handle add pack button\n            addPackBtn.addEventListener('click', function() {\n                const size = parseInt(newPackSizeInput.value) || 0;\n                if (size <= 0) {\n                    alert('Please enter a valid pack size (greater than 0)!');\n                    return;\n                }\n                if (addPack(size)) {\n                    newPackSizeInput.value = '';\n                    // Reset quick add buttons\n                    quickAddButtons.forEach(btn => {\n                        btn.classList.remove('btn-secondary');\n                        btn.classList.add('btn-outline');\n                    });\n                }\n            });\n            \n            // Handle Enter key in input\n            newPackSizeInput.addEventListener('keypress', function(e) {\n                if (e.key === 'Enter') {\n                    e.preventDefault();\n                    addPackBtn.click();\n                }\n            });\n\n            // Handle quick add buttons visual feedback\n            quickAddButtons.forEach(button => {\n                button.addEventListener('click', function() {\n                    // Visual feedback\n                    this.classList.add('btn-secondary');\n                    this.classList.remove('btn-outline');\n                    \n                    // Reset other buttons\n                    quickAddButtons.forEach(btn => {\n                        if (btn !== this) {\n                            btn.classList.remove('btn-secondary');\n                            btn.classList.add('btn-outline');\n                        }\n                    });\n                    \n                    // Set input value\n                    newPackSizeInput.value = this.dataset.size;\n                });\n            });\n\n            // Reset quick add buttons when typing in input\n            newPackSizeInput.addEventListener('input', function() {\n                quickAddButtons.forEach(btn => {\n                    btn.classList.remove('btn-secondary');\n                    btn.classList.add('btn-outline');\n                });\n            });\n\n            // Handle form submission\n            document.getElementById('adminForm').addEventListener('submit', function(e) {\n                if (currentPacks.length === 0) {\n                    e.preventDefault();\n                    alert('Please add at least one pack size!');\n                    return;\n                }\n                // Form will submit with all current packs as hidden inputs\n            });\n            \n            // Initial render\n            renderPacks();\n        });\n    </script>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 25, "<script>\n        // Interactive admin form functionality\n        document.addEventListener('DOMContentLoaded', function() {\n            const quickAddButtons = document.querySelectorAll('.quick-add-btn');\n            const newPackSizeInput = document.getElementById('newPackSize');\n            const addPackBtn = document.getElementById('addPackBtn');\n            const packsDisplay = document.getElementById('packsDisplay');\n            const emptyState = document.getElementById('emptyState');\n            \n            // Initialize with server-provided packs\n            let currentPacks = JSON.parse(document.getElementById('packs').textContent) || [];\n            \n            function getPackIcon(size) {\n                if (size <= 500) return '🎈';\n                if (size <= 1000) return '🎈🎈';\n                if (size <= 2000) return '🎈🎈🎈';\n                return '🎈🎈🎈🎈';\n            }\n            \n            function getPackLabel(size) {\n                if (size <= 500) return 'Party';\n                if (size <= 1000) return 'Event';\n                if (size <= 2000) return 'Mega';\n                return 'Ultimate';\n            }\n\n            // editable detail fields of a pack, read and written through get/set so nested dimensions work too\n            const packFields = [\n                { name: 'sku', label: 'SKU', type: 'text', get: p => p.sku, set: (p, v) => p.sku = v },\n                { name: 'name', label: 'Display name', type: 'text', get: p => p.name, set: (p, v) => p.name = v },\n                { name: 'unitCost', label: 'Unit cost (¢)', type: 'number', get: p => p.unitCost, set: (p, v) => p.unitCost = parseInt(v) || 0 },\n                { name: 'tareWeight', label: 'Tare weight (g)', type: 'number', get: p => p.tareWeight, set: (p, v) => p.tareWeight = parseInt(v) || 0 },\n                { name: 'length', label: 'Length (mm)', type: 'number', get: p => p.dimensions.length, set: (p, v) => p.dimensions.length = parseInt(v) || 0 },\n                { name: 'width', label: 'Width (mm)', type: 'number', get: p => p.dimensions.width, set: (p, v) => p.dimensions.width = parseInt(v) || 0 },\n                { name: 'height', label: 'Height (mm)', type: 'number', get: p => p.dimensions.height, set: (p, v) => p.dimensions.height = parseInt(v) || 0 },\n                // empty stock means it is not tracked\n                { name: 'stock', label: 'Stock (empty = unlimited)', type: 'number', get: p => p.stock, set: (p, v) => p.stock = v === '' ? null : (parseInt(v) || 0) },\n                // disabled packs keep their details but orders don't use them\n                { name: 'disabled', label: 'Status', type: 'select', options: [['false', 'Enabled'], ['true', 'Disabled']], get: p => String(!!p.disabled), set: (p, v) => p.disabled = v === 'true' },\n            ];\n            \n            function renderPacks() {\n                // Sort packs numerically\n                currentPacks.sort((a, b) => a.size - b.size);\n                \n                // Clear display\n                packsDisplay.innerHTML = '';\n                scheduleAnalysis();\n                \n                if (currentPacks.length === 0) {\n                    emptyState.style.display = 'block';\n                    return;\n                }\n                \n                emptyState.style.display = 'none';\n                \n                // Render pack cards, built with DOM calls so names and SKUs are never parsed as html\n                currentPacks.forEach((pack, index) => {\n                    const card = document.createElement('div');\n                    card.className = 'card bg-gradient-to-r from-blue-50 to-purple-50 border-2 border-purple-200 shadow-lg p-4';\n\n                    const header = document.createElement('div');\n                    header.className = 'flex items-center justify-between mb-3';\n                    const title = document.createElement('span');\n                    title.className = 'font-bold text-lg text-purple-700';\n                    title.textContent = `${getPackIcon(pack.size)} ${pack.size} ${getPackLabel(pack.size)}`;\n                    const removeButton = document.createElement('button');\n                    removeButton.type = 'button';\n                    removeButton.className = 'btn btn-ghost btn-sm text-red-500';\n                    removeButton.textContent = '×';\n                    removeButton.addEventListener('click', () => removePack(index));\n                    header.append(title, removeButton);\n                    card.appendChild(header);\n\n                    const sizeInput = document.createElement('input');\n                    sizeInput.type = 'hidden';\n                    sizeInput.name = 'packs';\n                    sizeInput.value = pack.size;\n                    card.appendChild(sizeInput);\n\n                    const grid = document.createElement('div');\n                    grid.className = 'grid grid-cols-2 gap-2';\n                    packFields.forEach(field => {\n                        const label = document.createElement('label');\n                        label.className = 'form-control';\n                        const labelText = document.createElement('span');\n                        labelText.className = 'label-text text-xs text-gray-600';\n                        labelText.textContent = field.label;\n                        const input = document.createElement(field.type === 'select' ? 'select' : 'input');\n                        if (field.type === 'select') {\n                            input.className = 'select select-bordered select-sm';\n                            field.options.forEach(([value, text]) => input.add(new Option(text, value)));\n                        } else {\n                            input.type = field.type;\n                            input.className = 'input input-bordered input-sm';\n                        }\n                        input.name = field.name;\n                        input.value = field.get(pack) ?? '';\n                        if (field.type === 'number') {\n                            input.min = '0';\n                        }\n                        input.addEventListener('input', () => {\n                            field.set(pack, input.value);\n                            scheduleAnalysis();\n                        });\n                        label.append(labelText, input);\n                        grid.appendChild(label);\n                    });\n                    card.appendChild(grid);\n\n                    packsDisplay.appendChild(card);\n                });\n            }\n\n            // checks the packs as they are now, a moment after the last change\n            const packAnalysis = document.getElementById('packAnalysis');\n            let analysisTimer;\n            function scheduleAnalysis() {\n                clearTimeout(analysisTimer);\n                analysisTimer = setTimeout(analyzePacks, 300);\n            }\n\n            async function analyzePacks() {\n                packAnalysis.replaceChildren();\n                if (!currentPacks.some(pack => !pack.disabled)) {\n                    packAnalysis.textContent = 'Add or enable a pack to check the set.';\n                    return;\n                }\n                try {\n                    const response = await fetch('/api/packs/analyze', {\n                        method: 'POST',\n                        headers: { 'Content-Type': 'application/json' },\n                        body: JSON.stringify({ packs: currentPacks }),\n                    });\n                    const result = await response.json();\n                    if (!result.success) {\n                        packAnalysis.textContent = result.errorMessage;\n                        return;\n                    }\n                    renderAnalysis(result.data);\n                } catch (err) {\n                    packAnalysis.textContent = 'Could not check the packs right now.';\n                }\n            }\n\n            const packSimulation = document.getElementById('packSimulation');\n            document.getElementById('simulateBtn').addEventListener('click', async function() {\n                packSimulation.textContent = 'Replaying orders...';\n                try {\n                    const response = await fetch('/api/packs/simulate', {\n                        method: 'POST',\n                        headers: { 'Content-Type': 'application/json' },\n                        body: JSON.stringify({ packs: currentPacks }),\n                    });\n                    const result = await response.json();\n                    if (!result.success) {\n                        packSimulation.textContent = result.errorMessage;\n                        return;\n                    }\n                    renderSimulation(result.data);\n                } catch (err) {\n                    packSimulation.textContent = 'Could not replay the orders right now.';\n                }\n            });\n\n            const packSuggestion = document.getElementById('packSuggestion');\n            const suggestGoal = document.getElementById('suggestGoal');\n            suggestGoal.addEventListener('change', () => {\n                document.querySelectorAll('.suggest-cost').forEach(field => field.style.display = suggestGoal.value === 'cost' ? '' : 'none');\n            });\n            document.getElementById('suggestBtn').addEventListener('click', async function() {\n                const number = id => parseInt(document.getElementById(id).value) || 0;\n                const request = {\n                    maxSizes: number('suggestMaxSizes'),\n                    minSize: number('suggestMinSize'),\n                    maxSize: number('suggestMaxSize'),\n                    goal: suggestGoal.value,\n                };\n                if (request.goal === 'cost') {\n                    request.itemCost = number('suggestItemCost');\n                    request.packCost = number('suggestPackCost');\n                }\n                packSuggestion.textContent = 'Looking through past orders...';\n                try {\n                    const response = await fetch('/api/packs/recommend', {\n                        method: 'POST',\n                        headers: { 'Content-Type': 'application/json' },\n                        body: JSON.stringify(request),\n                    });\n                    const result = await response.json();\n                    if (!result.success) {\n                        packSuggestion.textContent = result.errorMessage;\n                        return;\n                    }\n                    renderSuggestion(result.data, request.goal);\n                } catch (err) {\n                    packSuggestion.textContent = 'Could not suggest packs right now.';\n                }\n            });\n\n            function renderSuggestion(recommendation, goal) {\n                packSuggestion.replaceChildren();\n                const describe = score => `${score.overshoot} extra balloons in ${score.packs} packs` +\n                    (goal === 'cost' ? `, costing ${formatCents(score.cost)}` : '');\n\n                const sizes = document.createElement('p');\n                sizes.className = 'text-2xl font-bold text-purple-700 mb-2';\n                sizes.textContent = recommendation.packs.join(', ');\n                const score = document.createElement('p');\n                score.textContent = `On ${recommendation.orders} past orders: ${describe(recommendation.score)}`;\n                packSuggestion.append(sizes, score);\n                if (recommendation.current) {\n                    const current = document.createElement('p');\n                    current.className = 'text-sm mb-2';\n                    current.textContent = `The live packs: ${describe(recommendation.current)}`;\n                    packSuggestion.appendChild(current);\n                }\n\n                // sizes that are already configured keep their details\n                const useButton = document.createElement('button');\n                useButton.type = 'button';\n                useButton.className = 'btn btn-outline btn-secondary btn-sm mt-2';\n                useButton.textContent = 'Use these sizes';\n                useButton.addEventListener('click', () => {\n                    currentPacks = recommendation.packs.map(size => currentPacks.find(pack => pack.size === size) ??\n                        { size: size, sku: '', name: '', unitCost: 0, tareWeight: 0, dimensions: { length: 0, width: 0, height: 0 }, stock: null, disabled: false });\n                    renderPacks();\n                });\n                packSuggestion.appendChild(useButton);\n            }\n\n            const formatCents = cents => `$${(cents / 100).toFixed(2)}`;\n            const formatPacks = packs => Object.entries(packs).map(([size, count]) => `${count}x${size}`).join(' + ');\n\n            function renderSimulation(simulation) {\n                packSimulation.replaceChildren();\n                if (simulation.orders === 0) {\n                    packSimulation.textContent = 'There are no orders to replay yet.';\n                    return;\n                }\n\n                const summary = document.createElement('p');\n                summary.className = 'text-center mb-4';\n                summary.textContent = `${simulation.orders} orders replayed${simulation.truncated ? ' (only the oldest ones)' : ''}: ` +\n                    `${simulation.better} better, ${simulation.worse} worse, ${simulation.unchanged} unchanged.`;\n                packSimulation.appendChild(summary);\n\n                const rows = [\n                    ['Balloons shipped', t => t.shippedItems],\n                    ['Extra balloons', t => t.overshoot],\n                    ['Packs', t => t.packs],\n                    ['Packaging cost', t => formatCents(t.packagingCost)],\n                ];\n                const table = document.createElement('table');\n                table.className = 'table table-zebra w-full mb-4';\n                table.createTHead().insertRow().append(...['', 'Actually', 'With these packs'].map(text => {\n                    const th = document.createElement('th');\n                    th.textContent = text;\n                    return th;\n                }));\n                const body = table.createTBody();\n                rows.forEach(([label, value]) => {\n                    const row = body.insertRow();\n                    row.insertCell().textContent = label;\n                    row.insertCell().textContent = value(simulation.actual);\n                    row.insertCell().textContent = value(simulation.simulated);\n                });\n                packSimulation.appendChild(table);\n\n                if (simulation.largestChanges.length > 0) {\n                    const heading = document.createElement('p');\n                    heading.className = 'font-bold mb-2';\n                    heading.textContent = 'Orders that would change the most';\n                    packSimulation.appendChild(heading);\n                }\n                simulation.largestChanges.forEach(change => {\n                    const item = document.createElement('div');\n                    item.className = 'text-sm mb-1';\n                    item.textContent = `Order ${change.orderId}, ${change.requestedItemCount} balloons: ` +\n                        `${formatPacks(change.actualPacks)} (${change.actual.overshoot} extra) → ` +\n                        `${formatPacks(change.simulatedPacks)} (${change.simulated.overshoot} extra)`;\n                    packSimulation.appendChild(item);\n                });\n            }\n\n            function renderAnalysis(analysis) {\n                const summary = document.createElement('p');\n                summary.className = 'text-center mb-4';\n                summary.textContent = `For orders of ${analysis.from} to ${analysis.to} balloons, ` +\n                    `${analysis.averageOvershoot.toFixed(1)} extra balloons are sent on average, ` +\n                    `at worst ${analysis.worstOvershoot} (for ${analysis.worstOvershootAt}).`;\n                packAnalysis.appendChild(summary);\n\n                if (analysis.warnings.length === 0) {\n                    const ok = document.createElement('div');\n                    ok.className = 'alert bg-green-50 border-2 border-green-200';\n                    ok.textContent = '✅ Looks good, every pack pulls its weight.';\n                    packAnalysis.appendChild(ok);\n                    return;\n                }\n                analysis.warnings.forEach(warning => {\n                    const alert = document.createElement('div');\n                    alert.className = 'alert bg-yellow-50 border-2 border-yellow-200 mb-2';\n                    alert.textContent = `⚠️ ${warning.charAt(0).toUpperCase()}${warning.slice(1)}`;\n                    packAnalysis.appendChild(alert);\n                });\n            }\n            \n            function removePack(index) {\n                currentPacks.splice(index, 1);\n                renderPacks();\n            }\n            \n            function addPack(size) {\n                if (currentPacks.some(pack => pack.size === size)) {\n                    alert('This pack size already exists!');\n                    return false;\n                }\n                currentPacks.push({ size: size, sku: '', name: '', unitCost: 0, tareWeight: 0, dimensions: { length: 0, width: 0, height: 0 }, stock: null, disabled: false });\n                renderPacks();\n                return true;\n            }\n            \n            // Handle quick add buttons\n            quickAddButtons.forEach(button => {\n                button.addEventListener('click', function() {\n                    const size = parseInt(this.dataset.size);\n                    if (addPack(size)) {\n                        newPackSizeInput.value = '';\n                        // Reset visual state\n                        quickAddButtons.forEach(btn => {\n                            btn.classList.remove('btn-secondary');\n                            btn.classList.add('btn-outline');\n                        });\n                    }\n                });\n            });\n            \n            // Handle add pack button\n            addPackBtn.addEventListener('click', function() {\n                const size = parseInt(newPackSizeInput.value) || 0;\n                if (size <= 0) {\n                    alert('Please enter a valid pack size (greater than 0)!');\n                    return;\n                }\n                if (addPack(size)) {\n                    newPackSizeInput.value = '';\n                    // Reset quick add buttons\n                    quickAddButtons.forEach(btn => {\n                        btn.classList.remove('btn-secondary');\n                        btn.classList.add('btn-outline');\n                    });\n                }\n            });\n            \n            // Handle Enter key in input\n            newPackSizeInput.addEventListener('keypress', function(e) {\n                if (e.key === 'Enter') {\n                    e.preventDefault();\n                    addPackBtn.click();\n                }\n            });\n\n            // Handle quick add buttons visual feedback\n            quickAddButtons.forEach(button => {\n                button.addEventListener('click', function() {\n                    // Visual feedback\n                    this.classList.add('btn-secondary');\n                    this.classList.remove('btn-outline');\n                    \n                    // Reset other buttons\n                    quickAddButtons.forEach(btn => {\n                        if (btn !== this) {\n                            btn.classList.remove('btn-secondary');\n                            btn.classList.add('btn-outline');\n                        }\n                    });\n                    \n                    // Set input value\n                    newPackSizeInput.value = this.dataset.size;\n                });\n            });\n\n            // Reset quick add buttons when typing in input\n            newPackSizeInput.addEventListener('input', function() {\n                quickAddButtons.forEach(btn => {\n                    btn.classList.remove('btn-secondary');\n                    btn.classList.add('btn-outline');\n                });\n            });\n\n            // Handle form submission\n            document.getElementById('adminForm').addEventListener('submit', function(e) {\n                if (currentPacks.length === 0) {\n                    e.preventDefault();\n                    alert('Please add at least one pack size!');\n                    return;\n                }\n                // Form will submit with all current packs as hidden inputs\n            });\n            \n            // Initial render\n            renderPacks();\n        });\n    </script>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -0,0 +1,107 @@
+package pages
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/irreal/order-packs/models"
+)
+
+// cents as dollars, e.g. 1020 -> $10.20
+func formatCents(cents int) string {
+	return fmt.Sprintf("$%d.%02d", cents/100, cents%100)
+}
+
+// grams as kilograms, e.g. 1250 -> 1.25 kg
+func formatGrams(grams int) string {
+	return fmt.Sprintf("%.2f kg", float64(grams)/1000)
+}
+
+// pack sizes of an order, largest first, so the contents don't shuffle around between renders
+func sortedPacks(packs map[models.Pack]int) []models.Pack {
+	sizes := slices.Sorted(maps.Keys(packs))
+	slices.Reverse(sizes)
+	return sizes
+}
+
+// orders with lines ship their products' packs, listings don't load the lines so they only get a pointer to the order.
+// backorders are the only other orders without packs
+func packedPerProduct(order *models.Order) bool {
+	return len(order.Packs) == 0 && order.BackorderOf == "" && order.ShippedItemCount > 0
+}
+
+// statuses offered by the order history filter, in lifecycle order
+var historyStatuses = []models.OrderStatus{
+	models.OrderStatusNew,
+	models.OrderStatusPending,
+	models.OrderStatusPacked,
+	models.OrderStatusShipped,
+	models.OrderStatusCancelled,
+	models.OrderStatusBackordered,
+	models.OrderStatusNeedsApproval,
+	models.OrderStatusRejected,
+}
+
+// sorts offered by the order history page, the first one is the default
+var historySorts = []struct {
+	sort  models.OrderSort
+	label string
+}{
+	{models.OrderSortNewest, "Newest first"},
+	{models.OrderSortOldest, "Oldest first"},
+	{models.OrderSortRequestedDesc, "Most requested"},
+	{models.OrderSortRequestedAsc, "Least requested"},
+	{models.OrderSortShippedDesc, "Most shipped"},
+	{models.OrderSortShippedAsc, "Least shipped"},
+}
+
+// everything about a pack on one line, for pack history
+func describePackDetails(pack models.PackDetails) string {
+	stock := "unlimited stock"
+	if pack.Stock != nil {
+		stock = fmt.Sprintf("%d in stock", *pack.Stock)
+	}
+	if pack.Disabled {
+		stock += " | disabled"
+	}
+	return fmt.Sprintf("%s | %s | %s | %d g | %dx%dx%d mm | %s",
+		cmp.Or(pack.SKU, "no SKU"), cmp.Or(pack.Name, "no name"), formatCents(pack.UnitCost), pack.TareWeight,
+		pack.Dimensions.Length, pack.Dimensions.Width, pack.Dimensions.Height, stock)
+}
+
+// who saved a pack set version, they don't have to say
+func createdBy(name string) string {
+	return cmp.Or(name, "someone")
+}
+
+// pack sizes as a list, e.g. 250, 500 and 1000
+func formatPackSizes(sizes models.Packs) string {
+	names := make([]string, len(sizes))
+	for i, size := range sizes {
+		names[i] = fmt.Sprint(size)
+	}
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+// a product by its name, or its SKU when it has none
+func productLabel(product *models.Product) string {
+	return cmp.Or(product.Name, product.SKU)
+}
+
+// the limits packs were split into containers by, e.g. at most 10 packs and 20.00 kg each
+func describeContainerLimits(limits models.ContainerConfig) string {
+	var parts []string
+	if limits.MaxPacks > 0 {
+		parts = append(parts, fmt.Sprintf("%d packs", limits.MaxPacks))
+	}
+	if limits.MaxWeight > 0 {
+		parts = append(parts, formatGrams(limits.MaxWeight))
+	}
+	return "at most " + strings.Join(parts, " and ") + " each"
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/irreal/order-packs/models"
+	"github.com/irreal/order-packs/products"
+	"github.com/irreal/order-packs/utils"
+)
+
+func (a *App) handleGetProducts(w http.ResponseWriter, r *http.Request) {
+	products, err := a.productsService.GetProducts()
+	if err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	utils.WriteAPISuccessResponse(w, products)
+}
+
+func (a *App) handleGetProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := readProductID(r)
+	if err != nil {
+		writeProductErrorResponse(w, err)
+		return
+	}
+
+	product, err := a.productsService.GetProduct(id)
+	if err != nil {
+		writeProductErrorResponse(w, err)
+		return
+	}
+	utils.WriteAPISuccessResponse(w, product)
+}
+
+// packs can be given as plain sizes or with their details, like the warehouse packs
+func (a *App) handleCreateProduct(w http.ResponseWriter, r *http.Request) {
+	var product models.Product
+	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, "invalid JSON format")
+		return
+	}
+
+	created, err := a.productsService.CreateProduct(product)
+	if err != nil {
+		fmt.Fprintf(a.stderr, "error creating product: %v\n", err)
+		writeProductErrorResponse(w, err)
+		return
+	}
+	utils.WriteAPISuccessResponse(w, created)
+}
+
+func (a *App) handleUpdateProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := readProductID(r)
+	if err != nil {
+		writeProductErrorResponse(w, err)
+		return
+	}
+
+	var product models.Product
+	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, "invalid JSON format")
+		return
+	}
+
+	updated, err := a.productsService.UpdateProduct(id, product)
+	if err != nil {
+		fmt.Fprintf(a.stderr, "error updating product: %v\n", err)
+		writeProductErrorResponse(w, err)
+		return
+	}
+	utils.WriteAPISuccessResponse(w, updated)
+}
+
+// ids that aren't numbers can't belong to a product
+func readProductID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", products.ProductNotFoundError, r.PathValue("id"))
+	}
+	return id, nil
+}
+
+func writeProductErrorResponse(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, products.ProductNotFoundError):
+		utils.WriteAPIErrorResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, products.InvalidProductError):
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, products.ProductExistsError):
+		utils.WriteAPIErrorResponse(w, http.StatusConflict, err.Error())
+	default:
+		utils.WriteAPIErrorResponse(w, http.StatusInternalServerError, "internal server error")
+	}
+}
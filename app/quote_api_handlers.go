@@ -0,0 +1,99 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/irreal/order-packs/models"
+	"github.com/irreal/order-packs/utils"
+)
+
+// keeps a single batch request from tying up the server
+const maxQuoteBatchSize = 1000
+
+func (a *App) handleGetQuote(w http.ResponseWriter, r *http.Request) {
+	itemCountStr := r.URL.Query().Get("itemCount")
+	itemCount, err := strconv.Atoi(itemCountStr)
+	if err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid itemCount: %q", itemCountStr))
+		return
+	}
+
+	quoteRequest := models.OrderRequest{
+		ItemCount:   itemCount,
+		Objective:   models.Objective(r.URL.Query().Get("objective")),
+		Fulfillment: models.Fulfillment(r.URL.Query().Get("fulfillment")),
+	}
+	if err := readAlternativesParam(r, &quoteRequest); err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	packs, err := a.packsService.GetPackDetails()
+	if err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	quote, err := a.orderService.Quote(quoteRequest, packs)
+	if err != nil {
+		fmt.Fprintf(a.stderr, "error calculating quote: %v\n", err)
+		writeOrderErrorResponse(w, err)
+		return
+	}
+
+	utils.WriteAPISuccessResponse(w, quote)
+}
+
+// quotes a list of order requests against the same pack set, all or nothing
+func (a *App) handleBatchQuote(w http.ResponseWriter, r *http.Request) {
+	var quoteRequests []models.OrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&quoteRequests); err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON request: %v", err))
+		return
+	}
+	if len(quoteRequests) == 0 {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, "At least one quote request is required")
+		return
+	}
+	if len(quoteRequests) > maxQuoteBatchSize {
+		utils.WriteAPIErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("At most %d quote requests are allowed", maxQuoteBatchSize))
+		return
+	}
+
+	packs, err := a.packsService.GetPackDetails()
+	if err != nil {
+		utils.WriteAPIErrorResponse(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	quotes := make([]*models.Quote, 0, len(quoteRequests))
+	for i, quoteRequest := range quoteRequests {
+		quote, err := a.orderService.Quote(quoteRequest, packs)
+		if err != nil {
+			fmt.Fprintf(a.stderr, "error calculating quote %d: %v\n", i, err)
+			writeOrderErrorResponse(w, fmt.Errorf("quote request %d: %w", i, err))
+			return
+		}
+		quotes = append(quotes, quote)
+	}
+
+	utils.WriteAPISuccessResponse(w, quotes)
+}
+
+// optional alternatives=k query parameter, overrides the one in the body
+func readAlternativesParam(r *http.Request, request *models.OrderRequest) error {
+	alternativesStr := r.URL.Query().Get("alternatives")
+	if alternativesStr == "" {
+		return nil
+	}
+
+	alternatives, err := strconv.Atoi(alternativesStr)
+	if err != nil {
+		return fmt.Errorf("Invalid alternatives: %q", alternativesStr)
+	}
+	request.Alternatives = alternatives
+	return nil
+}
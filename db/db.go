@@ -7,42 +7,40 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/irreal/order-packs/models"
+	"github.com/irreal/order-packs/orders"
+	"github.com/irreal/order-packs/packs"
+	"github.com/irreal/order-packs/products"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type DB struct {
-	conn *sql.DB
+	conn *conn
 }
 
-// creates a new database connection and initialize the schema
+// opens the database and migrates it to the latest schema, a new database also gets sample data
 func NewDB(dbPath string) (*DB, error) {
-
-	// make sure dir exists
-	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
-		return nil, fmt.Errorf("failed to create database directory: %w", err)
-	}
-
 	dbExists := true
 	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
 		dbExists = false
 	}
 
-	conn, err := sql.Open("sqlite3", dbPath+"?_foreign_keys=on")
+	db, err := Open(dbPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, err
 	}
 
-	db := &DB{conn: conn}
+	if err := db.Migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	}
 
-	// if we just created the db, create schema and init
 	if !dbExists {
-		if err := db.initSchema(); err != nil {
-			return nil, fmt.Errorf("failed to initialize schema: %w", err)
-		}
-		if err := db.seedData(); err != nil {
+		if err := db.Seed(); err != nil {
+			db.Close()
 			return nil, fmt.Errorf("failed to seed data: %w", err)
 		}
 		log.Println("Database initialized with schema and sample data")
@@ -51,69 +49,70 @@ func NewDB(dbPath string) (*DB, error) {
 	return db, nil
 }
 
-func (db *DB) Close() error {
-	return db.conn.Close()
+// opens the database as it is, without migrating it
+func Open(dbPath string) (*DB, error) {
+
+	// make sure dir exists
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	sqlConn, err := sql.Open("sqlite3", dbPath+"?_foreign_keys=on")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	return New(sqlConn, Dialect{}), nil
 }
 
-func (db *DB) initSchema() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS packs (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		size INTEGER NOT NULL UNIQUE,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS orders (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		requested_item_count INTEGER NOT NULL,
-		shipped_item_count INTEGER NOT NULL,
-		packs_json TEXT NOT NULL,
-		status TEXT NOT NULL,
-		created_at DATETIME NOT NULL,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_orders_created_at ON orders(created_at DESC);
-	`
-
-	_, err := db.conn.Exec(schema)
-	return err
+// the repositories on a connection opened elsewhere, their sql rewritten for dialect.
+// migrating the schema is up to whoever opened it, Migrate only knows the sqlite one
+func New(sqlConn *sql.DB, dialect Dialect) *DB {
+	return &DB{conn: &conn{DB: sqlConn, dialect: dialect}}
 }
 
-// seed db with sample data
-func (db *DB) seedData() error {
+func (db *DB) Close() error {
+	return db.conn.Close()
+}
 
-	packSizes := []int{250, 500, 1000, 2000, 5000}
-	for _, size := range packSizes {
-		_, err := db.conn.Exec("INSERT INTO packs (size) VALUES (?)", size)
-		if err != nil {
-			return fmt.Errorf("failed to insert pack size %d: %w", size, err)
-		}
-	}
+// seed a new database with sample data
+func (db *DB) Seed() error {
 
-	samplePacks := map[models.Pack]int{
-		250: 1,
+	samplePackDetails := []models.PackDetails{
+		{Size: 250, SKU: "BLN-RED-0250", Name: "Party Pack", UnitCost: 40, TareWeight: 30, Dimensions: models.Dimensions{Length: 200, Width: 150, Height: 50}},
+		{Size: 500, SKU: "BLN-RED-0500", Name: "Party Pack XL", UnitCost: 60, TareWeight: 50, Dimensions: models.Dimensions{Length: 250, Width: 200, Height: 80}},
+		{Size: 1000, SKU: "BLN-RED-1000", Name: "Event Pack", UnitCost: 100, TareWeight: 90, Dimensions: models.Dimensions{Length: 300, Width: 250, Height: 120}},
+		{Size: 2000, SKU: "BLN-RED-2000", Name: "Mega Pack", UnitCost: 180, TareWeight: 160, Dimensions: models.Dimensions{Length: 400, Width: 300, Height: 200}},
+		{Size: 5000, SKU: "BLN-RED-5000", Name: "Ultimate Pack", UnitCost: 400, TareWeight: 350, Dimensions: models.Dimensions{Length: 600, Width: 400, Height: 300}},
 	}
-	packsJSON, err := json.Marshal(samplePacks)
-	if err != nil {
-		return fmt.Errorf("failed to marshal sample packs: %w", err)
+	now := time.Now().UTC()
+	sampleVersion := &models.PackSetVersion{CreatedAt: now, CreatedBy: "sample data", EffectiveFrom: now, ActivatedAt: &now, Packs: samplePackDetails}
+	if err := db.SavePacks(sampleVersion); err != nil {
+		return fmt.Errorf("failed to insert sample packs: %w", err)
 	}
 
-	_, err = db.conn.Exec(`
-		INSERT INTO orders (requested_item_count, shipped_item_count, packs_json, status, created_at) 
-		VALUES (?, ?, ?, ?, ?)`,
-		100, 250, string(packsJSON), string(models.OrderStatusNew), time.Now())
-	if err != nil {
+	sampleOrder := &models.Order{
+		PublicID:           models.NewPublicOrderID(),
+		RequestedItemCount: 100,
+		ShippedItemCount:   250,
+		Packs:              map[models.Pack]int{250: 1},
+		PackagingCost:      40,
+		ShipmentWeight:     30,
+		PackSetVersion:     sampleVersion.ID,
+		Status:             models.OrderStatusNew,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+	if err := db.SaveOrder(sampleOrder); err != nil {
 		return fmt.Errorf("failed to insert sample order: %w", err)
 	}
 
 	return nil
 }
 
-// load all packs from db
+// load the sizes orders can use, disabled packs are left out
 func (db *DB) GetPacks() (models.Packs, error) {
-	rows, err := db.conn.Query("SELECT size FROM packs ORDER BY size")
+	rows, err := db.conn.Query("SELECT size FROM packs WHERE NOT disabled ORDER BY size")
 	if err != nil {
 		return nil, fmt.Errorf("failed to query packs: %w", err)
 	}
@@ -131,74 +130,1209 @@ func (db *DB) GetPacks() (models.Packs, error) {
 	return packs, nil
 }
 
-// replace all packs with new set
-func (db *DB) SavePacks(packs models.Packs) error {
+// load all packs with everything we know about them
+func (db *DB) GetPackDetails() ([]models.PackDetails, error) {
+	return livePacks(db.conn)
+}
+
+// the live packs with the version they were set by, 0 before one was activated.
+// read in one query so a pack change can't come in between
+func (db *DB) GetLivePackSet() ([]models.PackDetails, int64, error) {
+	rows, err := db.conn.Query(`
+		SELECT (SELECT id FROM pack_set_versions WHERE activated_at IS NOT NULL ORDER BY effective_from DESC, id DESC LIMIT 1), ` + packDetailsColumns + ` 
+		FROM packs 
+		ORDER BY size`)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query packs: %w", err)
+	}
+	defer rows.Close()
+
+	var packs []models.PackDetails
+	var versionID sql.NullInt64
+	for rows.Next() {
+		pack, err := scanPackDetails(rows, &versionID)
+		if err != nil {
+			return nil, 0, err
+		}
+		packs = append(packs, pack)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to query packs: %w", err)
+	}
+
+	return packs, versionID.Int64, nil
+}
+
+func livePacks(q querier) ([]models.PackDetails, error) {
+	rows, err := q.Query(`
+		SELECT ` + packDetailsColumns + ` 
+		FROM packs 
+		ORDER BY size`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query packs: %w", err)
+	}
+	defer rows.Close()
+
+	var packs []models.PackDetails
+	for rows.Next() {
+		pack, err := scanPackDetails(rows)
+		if err != nil {
+			return nil, err
+		}
+		packs = append(packs, pack)
+	}
+
+	return packs, nil
+}
+
+// keep the version, and when it's activated replace all packs with its set
+func (db *DB) SavePacks(version *models.PackSetVersion) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := tx.lockPacks(); err != nil {
+		return err
+	}
+
+	versionID, err := insertPackSetVersionWithPacks(tx, version)
+	if err != nil {
+		return err
+	}
+
+	if version.ActivatedAt != nil {
+		if err := replaceLivePacks(tx, version.Packs); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	version.ID = versionID
+	return nil
+}
+
+// reads the live packs and saves the updated ones in the same transaction, so concurrent changes don't get lost
+func (db *DB) UpdatePacks(version *models.PackSetVersion, update func(live []models.PackDetails) ([]models.PackDetails, error)) error {
 	tx, err := db.conn.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	_, err = tx.Exec("DELETE FROM packs")
+	if err := tx.lockPacks(); err != nil {
+		return err
+	}
+
+	live, err := livePacks(tx)
 	if err != nil {
+		return err
+	}
+	packs, err := update(live)
+	if err != nil {
+		return err
+	}
+
+	version.Packs = packs
+	versionID, err := insertPackSetVersionWithPacks(tx, version)
+	if err != nil {
+		return err
+	}
+	if err := replaceLivePacks(tx, packs); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	version.ID = versionID
+	return nil
+}
+
+func insertPackSetVersionWithPacks(tx *transaction, version *models.PackSetVersion) (int64, error) {
+	versionID, err := insertPackSetVersion(tx, version)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, pack := range version.Packs {
+		_, err = tx.Exec(`
+			INSERT INTO pack_set_version_packs (version_id, size, sku, name, unit_cost, tare_weight, length, width, height, stock, disabled) 
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			versionID, int(pack.Size), pack.SKU, pack.Name, pack.UnitCost, pack.TareWeight,
+			pack.Dimensions.Length, pack.Dimensions.Width, pack.Dimensions.Height, pack.Stock, pack.Disabled)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert pack size %d into version: %w", int(pack.Size), err)
+		}
+	}
+	return versionID, nil
+}
+
+func replaceLivePacks(tx *transaction, packs []models.PackDetails) error {
+	if _, err := tx.Exec("DELETE FROM packs"); err != nil {
 		return fmt.Errorf("failed to delete existing packs: %w", err)
 	}
 
 	for _, pack := range packs {
-		_, err = tx.Exec("INSERT INTO packs (size) VALUES (?)", int(pack))
+		_, err := tx.Exec(`
+			INSERT INTO packs (size, sku, name, unit_cost, tare_weight, length, width, height, stock, disabled) 
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			int(pack.Size), pack.SKU, pack.Name, pack.UnitCost, pack.TareWeight,
+			pack.Dimensions.Length, pack.Dimensions.Width, pack.Dimensions.Height, pack.Stock, pack.Disabled)
 		if err != nil {
-			return fmt.Errorf("failed to insert pack size %d: %w", int(pack), err)
+			return fmt.Errorf("failed to insert pack size %d: %w", int(pack.Size), err)
 		}
 	}
-
-	return tx.Commit()
+	return nil
 }
 
-// add new order
-func (db *DB) SaveOrder(order *models.Order) error {
-	packsJSON, err := json.Marshal(order.Packs)
+// Makes the newest scheduled version that is due live, unless a newer one is live already.
+// Every due version is marked as activated either way, so this only does work once per change
+func (db *DB) ActivatePackSets(now time.Time) (bool, error) {
+	// cheap check first, this runs every time packs are read
+	var due bool
+	err := db.conn.QueryRow("SELECT EXISTS (SELECT 1 FROM pack_set_versions WHERE activated_at IS NULL AND effective_from <= ?)", now.UTC()).Scan(&due)
 	if err != nil {
-		return fmt.Errorf("failed to marshal packs: %w", err)
+		return false, fmt.Errorf("failed to check scheduled pack sets: %w", err)
+	}
+	if !due {
+		return false, nil
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := tx.lockPacks(); err != nil {
+		return false, err
+	}
+
+	var dueID int64
+	var dueFrom time.Time
+	err = tx.QueryRow(`
+		SELECT id, effective_from FROM pack_set_versions 
+		WHERE activated_at IS NULL AND effective_from <= ? 
+		ORDER BY effective_from DESC, id DESC LIMIT 1`, now.UTC()).Scan(&dueID, &dueFrom)
+	if err == sql.ErrNoRows {
+		// someone else got to it first
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get scheduled pack set: %w", err)
+	}
+
+	var liveID int64
+	var liveFrom time.Time
+	err = tx.QueryRow(`
+		SELECT id, effective_from FROM pack_set_versions 
+		WHERE activated_at IS NOT NULL 
+		ORDER BY effective_from DESC, id DESC LIMIT 1`).Scan(&liveID, &liveFrom)
+	if err != nil && err != sql.ErrNoRows {
+		return false, fmt.Errorf("failed to get live pack set: %w", err)
+	}
+	newer := err == sql.ErrNoRows || dueFrom.After(liveFrom) || (dueFrom.Equal(liveFrom) && dueID > liveID)
+
+	if _, err := tx.Exec("UPDATE pack_set_versions SET activated_at = ? WHERE activated_at IS NULL AND effective_from <= ?", now.UTC(), now.UTC()); err != nil {
+		return false, fmt.Errorf("failed to activate pack sets: %w", err)
+	}
+
+	if newer {
+		packs, err := versionPacks(tx, dueID)
+		if err != nil {
+			return false, err
+		}
+
+		// the set's own stock is applied, sizes that stay and were scheduled without one keep what they have now
+		for i, pack := range packs {
+			if pack.Stock != nil {
+				continue
+			}
+			var stock sql.NullInt64
+			err := tx.QueryRow("SELECT stock FROM packs WHERE size = ?", int(pack.Size)).Scan(&stock)
+			if err == sql.ErrNoRows {
+				continue
+			}
+			if err != nil {
+				return false, fmt.Errorf("failed to get stock of pack %d: %w", int(pack.Size), err)
+			}
+			packs[i].Stock = nil
+			if stock.Valid {
+				level := int(stock.Int64)
+				packs[i].Stock = &level
+			}
+		}
+
+		if err := replaceLivePacks(tx, packs); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	return newer, nil
+}
 
-	_, err = db.conn.Exec(`
-		INSERT INTO orders (requested_item_count, shipped_item_count, packs_json, status, created_at) 
+func insertPackSetVersion(tx *transaction, version *models.PackSetVersion) (int64, error) {
+	var activatedAt *time.Time
+	if version.ActivatedAt != nil {
+		utc := version.ActivatedAt.UTC()
+		activatedAt = &utc
+	}
+	id, err := tx.insert(`
+		INSERT INTO pack_set_versions (created_at, created_by, rolled_back_from, effective_from, activated_at) 
 		VALUES (?, ?, ?, ?, ?)`,
-		order.RequestedItemCount, order.ShippedItemCount, string(packsJSON), string(order.Status), order.CreatedAt)
+		version.CreatedAt.UTC(), version.CreatedBy, version.RolledBackFrom, version.EffectiveFrom.UTC(), activatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert pack set version: %w", err)
+	}
+	return id, nil
+}
 
-	return err
+// every pack set version with its packs, newest first
+func (db *DB) GetPackSetVersions() ([]*models.PackSetVersion, error) {
+	rows, err := db.conn.Query("SELECT " + packSetVersionColumns + " FROM pack_set_versions ORDER BY id DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pack set versions: %w", err)
+	}
+	defer rows.Close()
+
+	versions := []*models.PackSetVersion{}
+	byID := make(map[int64]*models.PackSetVersion)
+	for rows.Next() {
+		version, err := scanPackSetVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+		byID[version.ID] = version
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query pack set versions: %w", err)
+	}
+
+	packRows, err := db.conn.Query(`
+		SELECT version_id, ` + packDetailsColumns + ` 
+		FROM pack_set_version_packs 
+		ORDER BY version_id, size`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pack set version packs: %w", err)
+	}
+	defer packRows.Close()
+
+	for packRows.Next() {
+		var versionID int64
+		pack, err := scanPackDetails(packRows, &versionID)
+		if err != nil {
+			return nil, err
+		}
+		if version, found := byID[versionID]; found {
+			version.Packs = append(version.Packs, pack)
+		}
+	}
+
+	return versions, packRows.Err()
 }
 
-// get data for web ui
-func (db *DB) GetLast10Orders() ([]*models.Order, error) {
+// fails with PackSetVersionNotFoundError if there is no such version
+func (db *DB) GetPackSetVersion(id int64) (*models.PackSetVersion, error) {
+	rows, err := db.conn.Query("SELECT "+packSetVersionColumns+" FROM pack_set_versions WHERE id = ?", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pack set version: %w", err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to query pack set version: %w", err)
+		}
+		return nil, fmt.Errorf("%w: %d", packs.PackSetVersionNotFoundError, id)
+	}
+	version, err := scanPackSetVersion(rows)
+	if err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if version.Packs, err = versionPacks(db.conn, id); err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// the version at the given time is the newest one to take effect by then, scheduled or not
+func (db *DB) GetPackSetVersionAt(at time.Time) (*models.PackSetVersion, error) {
+	var id int64
+	err := db.conn.QueryRow(`
+		SELECT id FROM pack_set_versions 
+		WHERE effective_from <= ? 
+		ORDER BY effective_from DESC, id DESC LIMIT 1`, at.UTC()).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: no packs at %s", packs.PackSetVersionNotFoundError, at.Format(time.RFC3339))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pack set version: %w", err)
+	}
+	return db.GetPackSetVersion(id)
+}
+
+// versions waiting to go live, soonest first
+func (db *DB) GetScheduledPackSets() ([]*models.PackSetVersion, error) {
 	rows, err := db.conn.Query(`
-		SELECT requested_item_count, shipped_item_count, packs_json, status, created_at 
-		FROM orders 
-		ORDER BY created_at DESC 
-		LIMIT 10`)
+		SELECT ` + packSetVersionColumns + ` FROM pack_set_versions 
+		WHERE activated_at IS NULL 
+		ORDER BY effective_from, id`)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query orders: %w", err)
+		return nil, fmt.Errorf("failed to query scheduled pack sets: %w", err)
 	}
 	defer rows.Close()
 
-	var orders []*models.Order
+	scheduled := []*models.PackSetVersion{}
 	for rows.Next() {
-		var order models.Order
-		var packsJSON string
-		var statusStr string
+		version, err := scanPackSetVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		scheduled = append(scheduled, version)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query scheduled pack sets: %w", err)
+	}
+	rows.Close()
+
+	for _, version := range scheduled {
+		if version.Packs, err = versionPacks(db.conn, version.ID); err != nil {
+			return nil, err
+		}
+	}
+	return scheduled, nil
+}
+
+// querier is what db.conn and transactions have in common
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func versionPacks(q querier, versionID int64) ([]models.PackDetails, error) {
+	rows, err := q.Query(`
+		SELECT `+packDetailsColumns+` 
+		FROM pack_set_version_packs 
+		WHERE version_id = ? 
+		ORDER BY size`, versionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pack set version packs: %w", err)
+	}
+	defer rows.Close()
+
+	packs := []models.PackDetails{}
+	for rows.Next() {
+		pack, err := scanPackDetails(rows)
+		if err != nil {
+			return nil, err
+		}
+		packs = append(packs, pack)
+	}
+	return packs, rows.Err()
+}
+
+const packSetVersionColumns = "id, created_at, created_by, rolled_back_from, effective_from, activated_at"
+
+func scanPackSetVersion(rows *sql.Rows) (*models.PackSetVersion, error) {
+	version := &models.PackSetVersion{Packs: []models.PackDetails{}}
+	var rolledBackFrom sql.NullInt64
+	var activatedAt sql.NullTime
+	err := rows.Scan(&version.ID, &version.CreatedAt, &version.CreatedBy, &rolledBackFrom, &version.EffectiveFrom, &activatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan pack set version: %w", err)
+	}
+	if rolledBackFrom.Valid {
+		version.RolledBackFrom = &rolledBackFrom.Int64
+	}
+	if activatedAt.Valid {
+		version.ActivatedAt = &activatedAt.Time
+	}
+	return version, nil
+}
+
+const packDetailsColumns = "size, sku, name, unit_cost, tare_weight, length, width, height, stock, disabled"
+
+// one row of pack details, leading is scanned into first for queries that select more columns up front
+func scanPackDetails(rows *sql.Rows, leading ...any) (models.PackDetails, error) {
+	var pack models.PackDetails
+	var stock sql.NullInt64
+	dest := append(leading, &pack.Size, &pack.SKU, &pack.Name, &pack.UnitCost, &pack.TareWeight,
+		&pack.Dimensions.Length, &pack.Dimensions.Width, &pack.Dimensions.Height, &stock, &pack.Disabled)
+	if err := rows.Scan(dest...); err != nil {
+		return pack, fmt.Errorf("failed to scan pack: %w", err)
+	}
+	if stock.Valid {
+		level := int(stock.Int64)
+		pack.Stock = &level
+	}
+	return pack, nil
+}
+
+// add new order, taking its packs out of stock in the same transaction.
+// times are saved in UTC, so they compare correctly as text when filtering history
+func (db *DB) SaveOrder(order *models.Order) error {
+	packsJSON, err := json.Marshal(order.Packs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal packs: %w", err)
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-		err := rows.Scan(&order.RequestedItemCount, &order.ShippedItemCount, &packsJSON, &statusStr, &order.CreatedAt)
+	// packs without tracked stock are unlimited, the others need enough left.
+	// orders with lines have no packs here, product packs don't come out of the warehouse stock
+	for pack, count := range order.Packs {
+		result, err := tx.Exec(`
+			UPDATE packs SET stock = stock - ?, updated_at = CURRENT_TIMESTAMP 
+			WHERE size = ? AND stock IS NOT NULL AND stock >= ?`,
+			count, int(pack), count)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan order: %w", err)
+			return fmt.Errorf("failed to update stock of pack %d: %w", int(pack), err)
+		}
+		if affected, err := result.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to update stock of pack %d: %w", int(pack), err)
+		} else if affected > 0 {
+			continue
 		}
 
-		if err := json.Unmarshal([]byte(packsJSON), &order.Packs); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal packs: %w", err)
+		var untracked bool
+		err = tx.QueryRow("SELECT stock IS NULL FROM packs WHERE size = ?", int(pack)).Scan(&untracked)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to check stock of pack %d: %w", int(pack), err)
+		}
+		if !untracked {
+			return fmt.Errorf("%w of size %d", orders.InsufficientStockError, int(pack))
 		}
+	}
 
-		order.Status = models.OrderStatus(statusStr)
-		orders = append(orders, &order)
+	var explanationJSON sql.NullString
+	if order.Explanation != nil {
+		data, err := json.Marshal(order.Explanation)
+		if err != nil {
+			return fmt.Errorf("failed to marshal explanation: %w", err)
+		}
+		explanationJSON = sql.NullString{String: string(data), Valid: true}
 	}
 
-	return orders, nil
+	var shipmentJSON sql.NullString
+	if order.Shipment != nil {
+		data, err := json.Marshal(order.Shipment)
+		if err != nil {
+			return fmt.Errorf("failed to marshal shipment: %w", err)
+		}
+		shipmentJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
+	var packagingJSON sql.NullString
+	if order.Packaging != nil {
+		data, err := json.Marshal(order.Packaging)
+		if err != nil {
+			return fmt.Errorf("failed to marshal packaging: %w", err)
+		}
+		packagingJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
+	// the version the order was calculated with, none for lines, their products' packs aren't versioned
+	packSetVersion := sql.NullInt64{Int64: order.PackSetVersion, Valid: order.PackSetVersion != 0}
+
+	order.ID, err = tx.insert(`
+		INSERT INTO orders (public_id, requested_item_count, shipped_item_count, packs_json, packaging_cost, shipment_weight, status, created_at, updated_at, explanation_json, pack_set_version, shipment_json, packaging_json) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		order.PublicID, order.RequestedItemCount, order.ShippedItemCount, string(packsJSON), order.PackagingCost, order.ShipmentWeight, string(order.Status), order.CreatedAt.UTC(), order.UpdatedAt.UTC(), explanationJSON, packSetVersion, shipmentJSON, packagingJSON)
+	if err != nil {
+		return err
+	}
+
+	for pack, count := range order.Packs {
+		if _, err := tx.Exec("INSERT INTO order_packs (order_id, pack_size, count) VALUES (?, ?, ?)", order.ID, int(pack), count); err != nil {
+			return fmt.Errorf("failed to save packs of order: %w", err)
+		}
+	}
+
+	for i, line := range order.Lines {
+		linePacksJSON, err := json.Marshal(line.Packs)
+		if err != nil {
+			return fmt.Errorf("failed to marshal packs of line %d: %w", i+1, err)
+		}
+		_, err = tx.Exec(`
+			INSERT INTO order_lines (order_id, line, product_id, product_sku, product_name, requested_item_count, shipped_item_count, packs_json, packaging_cost, shipment_weight) 
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			order.ID, i+1, line.ProductID, line.ProductSKU, line.ProductName, line.RequestedItemCount, line.ShippedItemCount, string(linePacksJSON), line.PackagingCost, line.ShipmentWeight)
+		if err != nil {
+			return fmt.Errorf("failed to save line %d of order: %w", i+1, err)
+		}
+	}
+
+	// the backorder has no packs yet, only the items still owed
+	if backorder := order.Backorder; backorder != nil {
+		backorder.ID, err = tx.insert(`
+			INSERT INTO orders (public_id, requested_item_count, shipped_item_count, packs_json, status, created_at, updated_at, backorder_of) 
+			VALUES (?, ?, ?, '{}', ?, ?, ?, ?)`,
+			backorder.PublicID, backorder.RequestedItemCount, backorder.ShippedItemCount, string(backorder.Status), backorder.CreatedAt.UTC(), backorder.UpdatedAt.UTC(), order.ID)
+		if err != nil {
+			return fmt.Errorf("failed to save backorder: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// get data for web ui
+func (db *DB) GetLast10Orders() ([]*models.Order, error) {
+	rows, err := db.conn.Query(`
+		SELECT ` + orderListColumns + ` 
+		FROM orders 
+		ORDER BY created_at DESC 
+		LIMIT 10`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orders: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []*models.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
+// columns scanOrder reads, backorder_of comes as the public ID of the order the backorder holds the rest of
+const orderListColumns = `id, public_id, requested_item_count, shipped_item_count, packs_json, packaging_cost, shipment_weight, status, created_at, updated_at, pack_set_version, 
+		(SELECT public_id FROM orders o WHERE o.id = orders.backorder_of)`
+
+// one row of an order listing, without the explanation
+func scanOrder(rows *sql.Rows) (*models.Order, error) {
+	var order models.Order
+	var packsJSON string
+	var statusStr string
+	var packSetVersion sql.NullInt64
+	var backorderOf sql.NullString
+
+	err := rows.Scan(&order.ID, &order.PublicID, &order.RequestedItemCount, &order.ShippedItemCount, &packsJSON, &order.PackagingCost, &order.ShipmentWeight, &statusStr, &order.CreatedAt, &order.UpdatedAt, &packSetVersion, &backorderOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan order: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(packsJSON), &order.Packs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal packs: %w", err)
+	}
+
+	order.Status = models.OrderStatus(statusStr)
+	order.PackSetVersion = packSetVersion.Int64
+	order.BackorderOf = backorderOf.String
+	return &order, nil
+}
+
+// a single order, with its explanation
+func (db *DB) GetOrder(id int64) (*models.Order, error) {
+	order, err := db.getOrder("id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %d", orders.OrderNotFoundError, id)
+	}
+	return order, err
+}
+
+func (db *DB) GetOrderByPublicID(publicID string) (*models.Order, error) {
+	order, err := db.getOrder("public_id = ?", publicID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", orders.OrderNotFoundError, publicID)
+	}
+	return order, err
+}
+
+// returns sql.ErrNoRows as is, so callers can say which order was missing
+func (db *DB) getOrder(where string, arg any) (*models.Order, error) {
+	var order models.Order
+	var packsJSON string
+	var statusStr string
+	var packSetVersion sql.NullInt64
+	var explanationJSON sql.NullString
+	var shipmentJSON sql.NullString
+	var packagingJSON sql.NullString
+	var backorderOf sql.NullString
+
+	err := db.conn.QueryRow(`
+		SELECT id, public_id, requested_item_count, shipped_item_count, packs_json, packaging_cost, shipment_weight, status, created_at, updated_at, pack_set_version, explanation_json, shipment_json, packaging_json, 
+		(SELECT public_id FROM orders o WHERE o.id = orders.backorder_of) 
+		FROM orders 
+		WHERE `+where, arg).
+		Scan(&order.ID, &order.PublicID, &order.RequestedItemCount, &order.ShippedItemCount, &packsJSON, &order.PackagingCost, &order.ShipmentWeight, &statusStr, &order.CreatedAt, &order.UpdatedAt, &packSetVersion, &explanationJSON, &shipmentJSON, &packagingJSON, &backorderOf)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(packsJSON), &order.Packs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal packs: %w", err)
+	}
+
+	// orders placed before explanations were kept don't have one
+	if explanationJSON.Valid {
+		order.Explanation = &models.Explanation{}
+		if err := json.Unmarshal([]byte(explanationJSON.String), order.Explanation); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal explanation: %w", err)
+		}
+	}
+
+	// only orders placed while containers had limits were split
+	if shipmentJSON.Valid {
+		order.Shipment = &models.Shipment{}
+		if err := json.Unmarshal([]byte(shipmentJSON.String), order.Shipment); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal shipment: %w", err)
+		}
+	}
+
+	// only orders placed while there were case types got a plan
+	if packagingJSON.Valid {
+		order.Packaging = &models.PackagingPlan{}
+		if err := json.Unmarshal([]byte(packagingJSON.String), order.Packaging); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal packaging: %w", err)
+		}
+	}
+
+	order.Status = models.OrderStatus(statusStr)
+	order.PackSetVersion = packSetVersion.Int64
+	order.BackorderOf = backorderOf.String
+
+	if order.Lines, err = orderLines(db.conn, order.ID); err != nil {
+		return nil, err
+	}
+	if order.Backorder, err = db.backorder(order.ID); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// the backorder holding the rest of an order, nil when the order shipped in full
+func (db *DB) backorder(orderID int64) (*models.Order, error) {
+	rows, err := db.conn.Query(`
+		SELECT `+orderListColumns+` 
+		FROM orders 
+		WHERE backorder_of = ?`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query backorder: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	return scanOrder(rows)
+}
+
+// lines of a multi-line order in the order they were given, none for single count orders
+func orderLines(q querier, orderID int64) ([]models.OrderLine, error) {
+	rows, err := q.Query(`
+		SELECT product_id, product_sku, product_name, requested_item_count, shipped_item_count, packs_json, packaging_cost, shipment_weight 
+		FROM order_lines 
+		WHERE order_id = ? 
+		ORDER BY line`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order lines: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []models.OrderLine
+	for rows.Next() {
+		var line models.OrderLine
+		var packsJSON string
+		err := rows.Scan(&line.ProductID, &line.ProductSKU, &line.ProductName, &line.RequestedItemCount, &line.ShippedItemCount, &packsJSON, &line.PackagingCost, &line.ShipmentWeight)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order line: %w", err)
+		}
+		if err := json.Unmarshal([]byte(packsJSON), &line.Packs); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal packs of order line: %w", err)
+		}
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
+
+// status change and, for cancelled and rejected orders, putting packs back in stock happen together
+func (db *DB) UpdateOrderStatus(order *models.Order, status models.OrderStatus) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	updatedAt := time.Now().UTC()
+	result, err := tx.Exec(`
+		UPDATE orders SET status = ?, updated_at = ? 
+		WHERE id = ? AND status = ?`,
+		string(status), updatedAt, order.ID, string(order.Status))
+	if err != nil {
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to update order status: %w", err)
+	} else if affected == 0 {
+		return fmt.Errorf("%w: order %d is no longer %s", orders.OrderStatusChangedError, order.ID, order.Status)
+	}
+
+	// product packs of multi-line orders never came out of stock
+	if (status == models.OrderStatusCancelled || status == models.OrderStatusRejected) && len(order.Lines) == 0 {
+		for pack, count := range order.Packs {
+			_, err := tx.Exec(`
+				UPDATE packs SET stock = stock + ?, updated_at = CURRENT_TIMESTAMP 
+				WHERE size = ? AND stock IS NOT NULL`,
+				count, int(pack))
+			if err != nil {
+				return fmt.Errorf("failed to restock pack %d: %w", int(pack), err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	order.Status = status
+	order.UpdatedAt = updatedAt
+	return nil
+}
+
+// sort column and direction of every history sort, newest and oldest go by id which follows creation
+var orderSorts = map[models.OrderSort]struct {
+	column     string
+	descending bool
+}{
+	models.OrderSortNewest:        {"", true},
+	models.OrderSortOldest:        {"", false},
+	models.OrderSortRequestedDesc: {"requested_item_count", true},
+	models.OrderSortRequestedAsc:  {"requested_item_count", false},
+	models.OrderSortShippedDesc:   {"shipped_item_count", true},
+	models.OrderSortShippedAsc:    {"shipped_item_count", false},
+}
+
+// how many orders asked for each item count, cancelled ones too, they were still asked for.
+// multi-line orders are left out, their products have their own packs, and so are backorders, their items were asked for once already
+func (db *DB) GetOrderDemand() ([]models.OrderDemand, error) {
+	rows, err := db.conn.Query(`
+		SELECT requested_item_count, COUNT(*) FROM orders 
+		WHERE NOT EXISTS (SELECT 1 FROM order_lines ol WHERE ol.order_id = orders.id) AND backorder_of IS NULL 
+		GROUP BY requested_item_count 
+		ORDER BY requested_item_count`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order demand: %w", err)
+	}
+	defer rows.Close()
+
+	demand := []models.OrderDemand{}
+	for rows.Next() {
+		var d models.OrderDemand
+		if err := rows.Scan(&d.ItemCount, &d.Orders); err != nil {
+			return nil, fmt.Errorf("failed to scan order demand: %w", err)
+		}
+		demand = append(demand, d)
+	}
+	return demand, rows.Err()
+}
+
+// order history, one page at a time. orders come without their explanation, like GetLast10Orders
+func (db *DB) ListOrders(query models.OrderQuery) ([]*models.Order, error) {
+	sort, known := orderSorts[query.Sort]
+	if !known {
+		return nil, fmt.Errorf("unknown order sort %q", query.Sort)
+	}
+
+	var where []string
+	var args []any
+
+	if len(query.Statuses) > 0 {
+		placeholders := make([]string, len(query.Statuses))
+		for i, status := range query.Statuses {
+			placeholders[i] = "?"
+			args = append(args, string(status))
+		}
+		where = append(where, "status IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	if !query.CreatedFrom.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, query.CreatedFrom.UTC())
+	}
+	if !query.CreatedTo.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, query.CreatedTo.UTC())
+	}
+
+	ranges := []struct {
+		condition string
+		value     int
+	}{
+		{"requested_item_count >= ?", query.MinRequested},
+		{"requested_item_count <= ?", query.MaxRequested},
+		{"shipped_item_count >= ?", query.MinShipped},
+		{"shipped_item_count <= ?", query.MaxShipped},
+	}
+	for _, r := range ranges {
+		if r.value != 0 {
+			where = append(where, r.condition)
+			args = append(args, r.value)
+		}
+	}
+
+	if query.PackSize != 0 {
+		where = append(where, "EXISTS (SELECT 1 FROM order_packs op WHERE op.order_id = orders.id AND op.pack_size = ?)")
+		args = append(args, int(query.PackSize))
+	}
+	if query.SingleCountOnly {
+		where = append(where, "NOT EXISTS (SELECT 1 FROM order_lines ol WHERE ol.order_id = orders.id) AND backorder_of IS NULL")
+	}
+
+	// keyset pagination, rows strictly after the cursor in sort order
+	comparison, direction := ">", "ASC"
+	if sort.descending {
+		comparison, direction = "<", "DESC"
+	}
+	orderBy := "id " + direction
+	if sort.column != "" {
+		orderBy = sort.column + " " + direction + ", " + orderBy
+	}
+	if query.After != nil {
+		if sort.column == "" {
+			where = append(where, "id "+comparison+" ?")
+			args = append(args, query.After.ID)
+		} else {
+			where = append(where, fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", sort.column, comparison))
+			args = append(args, query.After.Value, query.After.Value, query.After.ID)
+		}
+	}
+
+	sql := `
+		SELECT ` + orderListColumns + ` 
+		FROM orders`
+	if len(where) > 0 {
+		sql += " WHERE " + strings.Join(where, " AND ")
+	}
+	sql += " ORDER BY " + orderBy + " LIMIT ?"
+	args = append(args, query.Limit)
+
+	rows, err := db.conn.Query(sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []*models.Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, rows.Err()
+}
+
+// claims the key for a new request, or returns the record already holding it.
+// expired keys and unfinished ones whose lease ran out are cleared out first, so they are free again
+func (db *DB) ReserveIdempotencyKey(record *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		DELETE FROM idempotency_keys 
+		WHERE expires_at <= ? OR (status_code = 0 AND locked_until <= ?)`,
+		record.CreatedAt.UTC(), record.CreatedAt.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	// a request with the same key at the same time waits here until the first one commits, then inserts nothing
+	result, err := tx.Exec(`
+		INSERT INTO idempotency_keys (key, request_hash, created_at, expires_at, locked_until) 
+		VALUES (?, ?, ?, ?, ?) 
+		ON CONFLICT(key) DO NOTHING`,
+		record.Key, record.RequestHash, record.CreatedAt.UTC(), record.ExpiresAt.UTC(), record.LockedUntil.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert idempotency key: %w", err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check idempotency key insert: %w", err)
+	}
+
+	var existing *models.IdempotencyRecord
+	if inserted == 0 {
+		existing = &models.IdempotencyRecord{Key: record.Key}
+		err := tx.QueryRow(`
+			SELECT request_hash, status_code, response_body, created_at, expires_at, locked_until 
+			FROM idempotency_keys 
+			WHERE key = ?`, record.Key).
+			Scan(&existing.RequestHash, &existing.StatusCode, &existing.ResponseBody, &existing.CreatedAt, &existing.ExpiresAt, &existing.LockedUntil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return existing, nil
+}
+
+func (db *DB) CompleteIdempotencyKey(key string, statusCode int, responseBody []byte) error {
+	_, err := db.conn.Exec("UPDATE idempotency_keys SET status_code = ?, response_body = ? WHERE key = ?", statusCode, responseBody, key)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) ReleaseIdempotencyKey(key string) error {
+	if _, err := db.conn.Exec("DELETE FROM idempotency_keys WHERE key = ?", key); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) GetProducts() ([]*models.Product, error) {
+	rows, err := db.conn.Query("SELECT id, sku, name, created_at, updated_at FROM products ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query products: %w", err)
+	}
+	defer rows.Close()
+
+	products := []*models.Product{}
+	for rows.Next() {
+		product := &models.Product{}
+		if err := rows.Scan(&product.ID, &product.SKU, &product.Name, &product.CreatedAt, &product.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan product: %w", err)
+		}
+		products = append(products, product)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query products: %w", err)
+	}
+	rows.Close()
+
+	for _, product := range products {
+		if product.Packs, err = productPacks(db.conn, product.ID); err != nil {
+			return nil, err
+		}
+	}
+	return products, nil
+}
+
+func (db *DB) GetProduct(id int64) (*models.Product, error) {
+	product := &models.Product{}
+	err := db.conn.QueryRow("SELECT id, sku, name, created_at, updated_at FROM products WHERE id = ?", id).
+		Scan(&product.ID, &product.SKU, &product.Name, &product.CreatedAt, &product.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %d", products.ProductNotFoundError, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product: %w", err)
+	}
+
+	if product.Packs, err = productPacks(db.conn, id); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+func productPacks(q querier, productID int64) ([]models.PackDetails, error) {
+	rows, err := q.Query(`
+		SELECT `+packDetailsColumns+` 
+		FROM product_packs 
+		WHERE product_id = ? 
+		ORDER BY size`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product packs: %w", err)
+	}
+	defer rows.Close()
+
+	packs := []models.PackDetails{}
+	for rows.Next() {
+		pack, err := scanPackDetails(rows)
+		if err != nil {
+			return nil, err
+		}
+		packs = append(packs, pack)
+	}
+	return packs, rows.Err()
+}
+
+func (db *DB) CreateProduct(product *models.Product) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkProductSKU(tx, product); err != nil {
+		return err
+	}
+	product.ID, err = tx.insert("INSERT INTO products (sku, name, created_at, updated_at) VALUES (?, ?, ?, ?)",
+		product.SKU, product.Name, product.CreatedAt.UTC(), product.UpdatedAt.UTC())
+	if err != nil {
+		return productSKUError(tx, product, fmt.Errorf("failed to insert product: %w", err))
+	}
+
+	if err := insertProductPacks(tx, product); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// the product row and its packs are replaced together
+func (db *DB) UpdateProduct(product *models.Product) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkProductSKU(tx, product); err != nil {
+		return err
+	}
+	result, err := tx.Exec("UPDATE products SET sku = ?, name = ?, updated_at = ? WHERE id = ?",
+		product.SKU, product.Name, product.UpdatedAt.UTC(), product.ID)
+	if err != nil {
+		return productSKUError(tx, product, fmt.Errorf("failed to update product: %w", err))
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to update product: %w", err)
+	} else if affected == 0 {
+		return fmt.Errorf("%w: %d", products.ProductNotFoundError, product.ID)
+	}
+
+	if _, err := tx.Exec("DELETE FROM product_packs WHERE product_id = ?", product.ID); err != nil {
+		return fmt.Errorf("failed to delete product packs: %w", err)
+	}
+	if err := insertProductPacks(tx, product); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// fails with ProductExistsError if another product has the SKU, the unique index would only give a generic error
+func checkProductSKU(tx *transaction, product *models.Product) error {
+	var taken bool
+	err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM products WHERE sku = ? AND id != ?)", product.SKU, product.ID).Scan(&taken)
+	if err != nil {
+		return fmt.Errorf("failed to check product sku: %w", err)
+	}
+	if taken {
+		return fmt.Errorf("%w: %s", products.ProductExistsError, product.SKU)
+	}
+	return nil
+}
+
+// another product can take the SKU between checkProductSKU and the write, that's a unique violation.
+// any other error is returned as is
+func productSKUError(tx *transaction, product *models.Product, err error) error {
+	if tx.uniqueViolation(err) {
+		return fmt.Errorf("%w: %s", products.ProductExistsError, product.SKU)
+	}
+	return err
+}
+
+func insertProductPacks(tx *transaction, product *models.Product) error {
+	for _, pack := range product.Packs {
+		_, err := tx.Exec(`
+			INSERT INTO product_packs (product_id, size, sku, name, unit_cost, tare_weight, length, width, height, stock, disabled) 
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			product.ID, int(pack.Size), pack.SKU, pack.Name, pack.UnitCost, pack.TareWeight,
+			pack.Dimensions.Length, pack.Dimensions.Width, pack.Dimensions.Height, pack.Stock, pack.Disabled)
+		if err != nil {
+			return fmt.Errorf("failed to insert pack size %d of product: %w", int(pack.Size), err)
+		}
+	}
+	return nil
+}
+
+// case and pallet types in the order they were saved
+func (db *DB) GetPackaging() (*models.PackagingHierarchy, error) {
+	hierarchy := &models.PackagingHierarchy{Cases: []models.CaseType{}, Pallets: []models.PalletType{}}
+
+	rows, err := db.conn.Query("SELECT sku, name, pack_size, packs FROM case_types ORDER BY position")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query case types: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var caseType models.CaseType
+		if err := rows.Scan(&caseType.SKU, &caseType.Name, &caseType.PackSize, &caseType.Packs); err != nil {
+			return nil, fmt.Errorf("failed to scan case type: %w", err)
+		}
+		hierarchy.Cases = append(hierarchy.Cases, caseType)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query case types: %w", err)
+	}
+
+	palletRows, err := db.conn.Query("SELECT sku, name, case_sku, cases FROM pallet_types ORDER BY position")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pallet types: %w", err)
+	}
+	defer palletRows.Close()
+	for palletRows.Next() {
+		var pallet models.PalletType
+		if err := palletRows.Scan(&pallet.SKU, &pallet.Name, &pallet.CaseSKU, &pallet.Cases); err != nil {
+			return nil, fmt.Errorf("failed to scan pallet type: %w", err)
+		}
+		hierarchy.Pallets = append(hierarchy.Pallets, pallet)
+	}
+	return hierarchy, palletRows.Err()
+}
+
+// replaces every case and pallet type in one transaction
+func (db *DB) SavePackaging(hierarchy *models.PackagingHierarchy) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// pallets point at cases, so they go first
+	if _, err := tx.Exec("DELETE FROM pallet_types"); err != nil {
+		return fmt.Errorf("failed to delete pallet types: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM case_types"); err != nil {
+		return fmt.Errorf("failed to delete case types: %w", err)
+	}
+
+	for i, caseType := range hierarchy.Cases {
+		_, err := tx.Exec("INSERT INTO case_types (position, sku, name, pack_size, packs) VALUES (?, ?, ?, ?, ?)",
+			i, caseType.SKU, caseType.Name, int(caseType.PackSize), caseType.Packs)
+		if err != nil {
+			return fmt.Errorf("failed to insert case type %s: %w", caseType.SKU, err)
+		}
+	}
+	for i, pallet := range hierarchy.Pallets {
+		_, err := tx.Exec("INSERT INTO pallet_types (position, sku, name, case_sku, cases) VALUES (?, ?, ?, ?, ?)",
+			i, pallet.SKU, pallet.Name, pallet.CaseSKU, pallet.Cases)
+		if err != nil {
+			return fmt.Errorf("failed to insert pallet type %s: %w", pallet.SKU, err)
+		}
+	}
+
+	return tx.Commit()
 }
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The repository's sql is written for sqlite, a dialect is what another database does differently.
+// postgres.DB runs the same queries with its own dialect
+type Dialect struct {
+	// placeholders are numbered, $1, $2, ..., the queries' ? are rewritten to them
+	NumberedPlaceholders bool
+	// new ids come back from INSERT ... RETURNING id, there is no LastInsertId
+	ReturningID bool
+	// runs first in every transaction that replaces the live packs, or reads them to change them.
+	// empty when the database makes writers take turns already, like sqlite does
+	LockPacks string
+	// reports whether err is a unique index violation, e.g. a concurrent write taking a product's SKU.
+	// nil when checking first is enough
+	UniqueViolation func(err error) bool
+}
+
+// rewrites the ? placeholders for the dialect, the queries don't have a ? anywhere else
+func (d Dialect) rebind(query string) string {
+	if !d.NumberedPlaceholders {
+		return query
+	}
+	var rebound strings.Builder
+	n := 0
+	for _, part := range strings.SplitAfter(query, "?") {
+		if strings.HasSuffix(part, "?") {
+			n++
+			part = strings.TrimSuffix(part, "?") + "$" + strconv.Itoa(n)
+		}
+		rebound.WriteString(part)
+	}
+	return rebound.String()
+}
+
+// the connection with every query rebound for its dialect
+type conn struct {
+	*sql.DB
+	dialect Dialect
+}
+
+func (c *conn) Exec(query string, args ...any) (sql.Result, error) {
+	return c.DB.Exec(c.dialect.rebind(query), args...)
+}
+
+func (c *conn) Query(query string, args ...any) (*sql.Rows, error) {
+	return c.DB.Query(c.dialect.rebind(query), args...)
+}
+
+func (c *conn) QueryRow(query string, args ...any) *sql.Row {
+	return c.DB.QueryRow(c.dialect.rebind(query), args...)
+}
+
+func (c *conn) Begin() (*transaction, error) {
+	sqlTx, err := c.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &transaction{Tx: sqlTx, dialect: c.dialect}, nil
+}
+
+// a transaction with every query rebound for its dialect
+type transaction struct {
+	*sql.Tx
+	dialect Dialect
+}
+
+func (t *transaction) Exec(query string, args ...any) (sql.Result, error) {
+	return t.Tx.Exec(t.dialect.rebind(query), args...)
+}
+
+func (t *transaction) Query(query string, args ...any) (*sql.Rows, error) {
+	return t.Tx.Query(t.dialect.rebind(query), args...)
+}
+
+func (t *transaction) QueryRow(query string, args ...any) *sql.Row {
+	return t.Tx.QueryRow(t.dialect.rebind(query), args...)
+}
+
+// runs an INSERT and returns the id of the new row
+func (t *transaction) insert(query string, args ...any) (int64, error) {
+	var id int64
+	if t.dialect.ReturningID {
+		err := t.QueryRow(query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+
+	result, err := t.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (t *transaction) lockPacks() error {
+	if t.dialect.LockPacks == "" {
+		return nil
+	}
+	if _, err := t.Exec(t.dialect.LockPacks); err != nil {
+		return fmt.Errorf("failed to lock packs: %w", err)
+	}
+	return nil
+}
+
+func (t *transaction) uniqueViolation(err error) bool {
+	return t.dialect.UniqueViolation != nil && t.dialect.UniqueViolation(err)
+}
@@ -0,0 +1,45 @@
+package db
+
+import "testing"
+
+func TestDialect_Rebind(t *testing.T) {
+	tests := []struct {
+		name     string
+		dialect  Dialect
+		query    string
+		expected string
+	}{
+		{
+			name:     "sqlite keeps the query",
+			dialect:  Dialect{},
+			query:    "UPDATE packs SET stock = stock - ? WHERE size = ? AND stock >= ?",
+			expected: "UPDATE packs SET stock = stock - ? WHERE size = ? AND stock >= ?",
+		},
+		{
+			name:     "numbered placeholders",
+			dialect:  Dialect{NumberedPlaceholders: true},
+			query:    "UPDATE packs SET stock = stock - ? WHERE size = ? AND stock >= ?",
+			expected: "UPDATE packs SET stock = stock - $1 WHERE size = $2 AND stock >= $3",
+		},
+		{
+			name:     "no placeholders",
+			dialect:  Dialect{NumberedPlaceholders: true},
+			query:    "SELECT size FROM packs",
+			expected: "SELECT size FROM packs",
+		},
+		{
+			name:     "placeholder at the start",
+			dialect:  Dialect{NumberedPlaceholders: true},
+			query:    "?, ?",
+			expected: "$1, $2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := tt.dialect.rebind(tt.query); actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,565 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/irreal/order-packs/models"
+)
+
+// A versioned schema change. Every migration runs in its own transaction and is recorded in schema_migrations,
+// down undoes exactly what up did.
+//
+// Databases used to be upgraded in place on every start, before migrations were versioned. So migrations 1 to 12
+// check what is already there and only make the changes that are missing,
+// that's how those databases get their first schema_migrations rows. Later migrations can trust the recorded version.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+	down    func(tx *sql.Tx) error
+}
+
+// in version order, versions start at 1 and have no gaps. only ever append, a released migration never changes
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create packs and orders",
+		up: execMigration(`
+		CREATE TABLE IF NOT EXISTS packs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			size INTEGER NOT NULL UNIQUE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS orders (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			requested_item_count INTEGER NOT NULL,
+			shipped_item_count INTEGER NOT NULL,
+			packs_json TEXT NOT NULL,
+			status TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_orders_created_at ON orders(created_at DESC);
+		`),
+		down: execMigration(`
+		DROP TABLE orders;
+		DROP TABLE packs;
+		`),
+	},
+	{
+		version: 2,
+		name:    "add pack details",
+		up: addColumns("packs",
+			column{"sku", "TEXT NOT NULL DEFAULT ''"},
+			column{"name", "TEXT NOT NULL DEFAULT ''"},
+			column{"unit_cost", "INTEGER NOT NULL DEFAULT 0"},
+			column{"tare_weight", "INTEGER NOT NULL DEFAULT 0"},
+			column{"length", "INTEGER NOT NULL DEFAULT 0"},
+			column{"width", "INTEGER NOT NULL DEFAULT 0"},
+			column{"height", "INTEGER NOT NULL DEFAULT 0"},
+			column{"stock", "INTEGER"},
+			column{"disabled", "BOOLEAN NOT NULL DEFAULT 0"},
+		),
+		down: dropColumns("packs", "sku", "name", "unit_cost", "tare_weight", "length", "width", "height", "stock", "disabled"),
+	},
+	{
+		version: 3,
+		name:    "add packaging cost and shipment weight to orders",
+		up: addColumns("orders",
+			column{"packaging_cost", "INTEGER NOT NULL DEFAULT 0"},
+			column{"shipment_weight", "INTEGER NOT NULL DEFAULT 0"},
+		),
+		down: dropColumns("orders", "packaging_cost", "shipment_weight"),
+	},
+	{
+		version: 4,
+		name:    "add order explanations",
+		up:      addColumns("orders", column{"explanation_json", "TEXT"}),
+		down:    dropColumns("orders", "explanation_json"),
+	},
+	{
+		version: 5,
+		name:    "add public order ids",
+		up: func(tx *sql.Tx) error {
+			if err := addColumns("orders", column{"public_id", "TEXT"})(tx); err != nil {
+				return err
+			}
+			if err := backfillPublicOrderIDs(tx); err != nil {
+				return err
+			}
+			// sqlite can't add a unique column, the index does the same job
+			return execMigration("CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_public_id ON orders(public_id)")(tx)
+		},
+		down: func(tx *sql.Tx) error {
+			if err := execMigration("DROP INDEX idx_orders_public_id")(tx); err != nil {
+				return err
+			}
+			return dropColumns("orders", "public_id")(tx)
+		},
+	},
+	{
+		version: 6,
+		name:    "add order history indexes and order packs",
+		// order history filters and sorts, every sort is broken by id so it's part of the indexes
+		up: execMigration(`
+		CREATE INDEX IF NOT EXISTS idx_orders_status ON orders(status, id);
+		CREATE INDEX IF NOT EXISTS idx_orders_requested_item_count ON orders(requested_item_count, id);
+		CREATE INDEX IF NOT EXISTS idx_orders_shipped_item_count ON orders(shipped_item_count, id);
+
+		-- packs of every order as rows, packs_json can't be indexed
+		CREATE TABLE IF NOT EXISTS order_packs (
+			order_id INTEGER NOT NULL REFERENCES orders(id),
+			pack_size INTEGER NOT NULL,
+			count INTEGER NOT NULL,
+			PRIMARY KEY (order_id, pack_size)
+		);
+		CREATE INDEX IF NOT EXISTS idx_order_packs_pack_size ON order_packs(pack_size, order_id);
+
+		INSERT INTO order_packs (order_id, pack_size, count)
+		SELECT o.id, CAST(p.key AS INTEGER), p.value
+		FROM orders o, json_each(o.packs_json) p
+		WHERE NOT EXISTS (SELECT 1 FROM order_packs op WHERE op.order_id = o.id);
+		`),
+		down: execMigration(`
+		DROP TABLE order_packs;
+		DROP INDEX idx_orders_status;
+		DROP INDEX idx_orders_requested_item_count;
+		DROP INDEX idx_orders_shipped_item_count;
+		`),
+	},
+	{
+		version: 7,
+		name:    "add pack set versions",
+		// every save of the packs is kept as a version, the packs table only has the live set
+		up: func(tx *sql.Tx) error {
+			err := execMigration(`
+			CREATE TABLE IF NOT EXISTS pack_set_versions (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				created_at DATETIME NOT NULL,
+				created_by TEXT NOT NULL DEFAULT '',
+				rolled_back_from INTEGER REFERENCES pack_set_versions(id),
+				effective_from DATETIME,
+				activated_at DATETIME
+			);
+
+			CREATE TABLE IF NOT EXISTS pack_set_version_packs (
+				version_id INTEGER NOT NULL REFERENCES pack_set_versions(id),
+				size INTEGER NOT NULL,
+				sku TEXT NOT NULL DEFAULT '',
+				name TEXT NOT NULL DEFAULT '',
+				unit_cost INTEGER NOT NULL DEFAULT 0,
+				tare_weight INTEGER NOT NULL DEFAULT 0,
+				length INTEGER NOT NULL DEFAULT 0,
+				width INTEGER NOT NULL DEFAULT 0,
+				height INTEGER NOT NULL DEFAULT 0,
+				stock INTEGER,
+				disabled BOOLEAN NOT NULL DEFAULT 0,
+				PRIMARY KEY (version_id, size)
+			);
+			`)(tx)
+			if err != nil {
+				return err
+			}
+			if err := addColumns("pack_set_version_packs", column{"disabled", "BOOLEAN NOT NULL DEFAULT 0"})(tx); err != nil {
+				return err
+			}
+			// versions saved before changes could be scheduled took effect right away
+			if err := addColumns("pack_set_versions", column{"effective_from", "DATETIME"}, column{"activated_at", "DATETIME"})(tx); err != nil {
+				return err
+			}
+			if err := addColumns("orders", column{"pack_set_version", "INTEGER REFERENCES pack_set_versions(id)"})(tx); err != nil {
+				return err
+			}
+			err = execMigration(`
+			UPDATE pack_set_versions SET effective_from = created_at, activated_at = created_at WHERE effective_from IS NULL;
+			CREATE INDEX IF NOT EXISTS idx_pack_set_versions_effective_from ON pack_set_versions(effective_from, id);
+			`)(tx)
+			if err != nil {
+				return err
+			}
+			return backfillPackSetVersion(tx)
+		},
+		down: func(tx *sql.Tx) error {
+			if err := dropColumns("orders", "pack_set_version")(tx); err != nil {
+				return err
+			}
+			return execMigration(`
+			DROP TABLE pack_set_version_packs;
+			DROP TABLE pack_set_versions;
+			`)(tx)
+		},
+	},
+	{
+		version: 8,
+		name:    "add idempotency keys",
+		// responses of requests sent with an Idempotency-Key, status_code is 0 until the first request is done.
+		// locked_until is when a request that hasn't finished by then is presumed dead
+		up: execMigration(`
+		CREATE TABLE IF NOT EXISTS idempotency_keys (
+			key TEXT PRIMARY KEY,
+			request_hash TEXT NOT NULL,
+			status_code INTEGER NOT NULL DEFAULT 0,
+			response_body BLOB,
+			created_at DATETIME NOT NULL,
+			expires_at DATETIME NOT NULL,
+			locked_until DATETIME NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+		`),
+		down: execMigration("DROP TABLE idempotency_keys"),
+	},
+	{
+		version: 9,
+		name:    "add products and order lines",
+		// products have their own packs, multi-line orders keep every line next to the totals in orders
+		up: execMigration(`
+		CREATE TABLE IF NOT EXISTS products (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			sku TEXT NOT NULL UNIQUE,
+			name TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS product_packs (
+			product_id INTEGER NOT NULL REFERENCES products(id),
+			size INTEGER NOT NULL,
+			sku TEXT NOT NULL DEFAULT '',
+			name TEXT NOT NULL DEFAULT '',
+			unit_cost INTEGER NOT NULL DEFAULT 0,
+			tare_weight INTEGER NOT NULL DEFAULT 0,
+			length INTEGER NOT NULL DEFAULT 0,
+			width INTEGER NOT NULL DEFAULT 0,
+			height INTEGER NOT NULL DEFAULT 0,
+			stock INTEGER,
+			disabled BOOLEAN NOT NULL DEFAULT 0,
+			PRIMARY KEY (product_id, size)
+		);
+
+		CREATE TABLE IF NOT EXISTS order_lines (
+			order_id INTEGER NOT NULL REFERENCES orders(id),
+			line INTEGER NOT NULL,
+			product_id INTEGER NOT NULL REFERENCES products(id),
+			product_sku TEXT NOT NULL,
+			product_name TEXT NOT NULL,
+			requested_item_count INTEGER NOT NULL,
+			shipped_item_count INTEGER NOT NULL,
+			packs_json TEXT NOT NULL,
+			packaging_cost INTEGER NOT NULL,
+			shipment_weight INTEGER NOT NULL,
+			PRIMARY KEY (order_id, line)
+		);
+		`),
+		down: execMigration(`
+		DROP TABLE order_lines;
+		DROP TABLE product_packs;
+		DROP TABLE products;
+		`),
+	},
+	{
+		version: 10,
+		name:    "add order shipments",
+		up:      addColumns("orders", column{"shipment_json", "TEXT"}),
+		down:    dropColumns("orders", "shipment_json"),
+	},
+	{
+		version: 11,
+		name:    "add cases and pallets",
+		// packs go in cases and cases on pallets, see orders.PlanPackaging
+		up: func(tx *sql.Tx) error {
+			err := execMigration(`
+			CREATE TABLE IF NOT EXISTS case_types (
+				position INTEGER PRIMARY KEY,
+				sku TEXT NOT NULL UNIQUE,
+				name TEXT NOT NULL DEFAULT '',
+				pack_size INTEGER NOT NULL,
+				packs INTEGER NOT NULL
+			);
+
+			CREATE TABLE IF NOT EXISTS pallet_types (
+				position INTEGER PRIMARY KEY,
+				sku TEXT NOT NULL UNIQUE,
+				name TEXT NOT NULL DEFAULT '',
+				case_sku TEXT NOT NULL REFERENCES case_types(sku),
+				cases INTEGER NOT NULL
+			);
+			`)(tx)
+			if err != nil {
+				return err
+			}
+			return addColumns("orders", column{"packaging_json", "TEXT"})(tx)
+		},
+		down: func(tx *sql.Tx) error {
+			if err := dropColumns("orders", "packaging_json")(tx); err != nil {
+				return err
+			}
+			return execMigration(`
+			DROP TABLE pallet_types;
+			DROP TABLE case_types;
+			`)(tx)
+		},
+	},
+	{
+		version: 12,
+		name:    "add backorders",
+		up: func(tx *sql.Tx) error {
+			if err := addColumns("orders", column{"backorder_of", "INTEGER REFERENCES orders(id)"})(tx); err != nil {
+				return err
+			}
+			return execMigration("CREATE INDEX IF NOT EXISTS idx_orders_backorder_of ON orders(backorder_of)")(tx)
+		},
+		down: func(tx *sql.Tx) error {
+			if err := execMigration("DROP INDEX idx_orders_backorder_of")(tx); err != nil {
+				return err
+			}
+			return dropColumns("orders", "backorder_of")(tx)
+		},
+	},
+}
+
+// the schema version this build works with
+var LatestSchemaVersion = migrations[len(migrations)-1].version
+
+// Applies every migration that hasn't been applied yet, oldest first
+func (db *DB) Migrate() error {
+	return db.MigrateTo(LatestSchemaVersion)
+}
+
+// Applies or undoes migrations until the schema is at version, 0 undoes all of them.
+// Fails without changing anything when the database was migrated by a newer build
+func (db *DB) MigrateTo(version int) error {
+	if version < 0 || version > LatestSchemaVersion {
+		return fmt.Errorf("unknown schema version %d, the latest is %d", version, LatestSchemaVersion)
+	}
+
+	current, err := db.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if current > LatestSchemaVersion {
+		return fmt.Errorf("database schema version %d is newer than this build knows, the latest is %d", current, LatestSchemaVersion)
+	}
+
+	for i := current; i < version; i++ {
+		m := migrations[i]
+		if err := db.runMigration(m, m.up, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+			m.version, m.name, time.Now().UTC()); err != nil {
+			return fmt.Errorf("failed to apply migration %d (%s): %w", m.version, m.name, err)
+		}
+		log.Printf("Applied migration %d: %s", m.version, m.name)
+	}
+
+	for i := current - 1; i >= version; i-- {
+		m := migrations[i]
+		if err := db.runMigration(m, m.down, "DELETE FROM schema_migrations WHERE version = ?", m.version); err != nil {
+			return fmt.Errorf("failed to undo migration %d (%s): %w", m.version, m.name, err)
+		}
+		log.Printf("Undid migration %d: %s", m.version, m.name)
+	}
+	return nil
+}
+
+// the change and its schema_migrations record go in together
+func (db *DB) runMigration(m migration, change func(tx *sql.Tx) error, record string, args ...any) error {
+	tx, err := db.conn.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := change(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(record, args...); err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+	return tx.Commit()
+}
+
+// the version of the last applied migration, 0 for a database that was never migrated
+func (db *DB) SchemaVersion() (int, error) {
+	if err := db.createMigrationsTable(); err != nil {
+		return 0, err
+	}
+
+	var version int
+	if err := db.conn.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// every migration this build knows, oldest first
+func (db *DB) MigrationStatus() ([]models.MigrationStatus, error) {
+	if err := db.createMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan migration: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query migrations: %w", err)
+	}
+
+	statuses := make([]models.MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		statuses[i] = models.MigrationStatus{Version: m.version, Name: m.name}
+		if appliedAt, found := applied[m.version]; found {
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+	return statuses, nil
+}
+
+func (db *DB) createMigrationsTable() error {
+	_, err := db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at DATETIME NOT NULL
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema migrations table: %w", err)
+	}
+	return nil
+}
+
+func execMigration(statements string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(statements)
+		return err
+	}
+}
+
+type column struct {
+	name       string
+	definition string
+}
+
+// columns that are already there are left alone, see migration
+func addColumns(table string, columns ...column) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, c := range columns {
+			if err := addColumnIfMissing(tx, table, c.name, c.definition); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func dropColumns(table string, columns ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, c := range columns {
+			if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, c)); err != nil {
+				return fmt.Errorf("failed to drop column %s.%s: %w", table, c, err)
+			}
+		}
+		return nil
+	}
+}
+
+func addColumnIfMissing(tx *sql.Tx, table, column, definition string) error {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			columnType string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultVal, &primaryKey); err != nil {
+			return fmt.Errorf("failed to scan column of %s: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+// orders placed before public ids existed get one
+func backfillPublicOrderIDs(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT id FROM orders WHERE public_id IS NULL")
+	if err != nil {
+		return fmt.Errorf("failed to query orders without public id: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan order id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query orders without public id: %w", err)
+	}
+
+	for _, id := range ids {
+		if _, err := tx.Exec("UPDATE orders SET public_id = ? WHERE id = ?", models.NewPublicOrderID(), id); err != nil {
+			return fmt.Errorf("failed to set public id of order %d: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// packs saved before versioning become the first version, so every live set has one
+func backfillPackSetVersion(tx *sql.Tx) error {
+	var versions, packs int
+	err := tx.QueryRow("SELECT (SELECT COUNT(*) FROM pack_set_versions), (SELECT COUNT(*) FROM packs)").Scan(&versions, &packs)
+	if err != nil {
+		return fmt.Errorf("failed to count pack set versions: %w", err)
+	}
+	if versions > 0 || packs == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	versionID, err := insertPackSetVersion(&transaction{Tx: tx}, &models.PackSetVersion{CreatedAt: now, CreatedBy: "before versioning", EffectiveFrom: now, ActivatedAt: &now})
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO pack_set_version_packs (version_id, size, sku, name, unit_cost, tare_weight, length, width, height, stock, disabled)
+		SELECT ?, size, sku, name, unit_cost, tare_weight, length, width, height, stock, disabled FROM packs`, versionID)
+	if err != nil {
+		return fmt.Errorf("failed to backfill pack set version: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package db
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/irreal/order-packs/models"
+)
+
+// the schema databases had before any upgrades, like data/app.db
+const baselineSchema = `
+CREATE TABLE packs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	size INTEGER NOT NULL UNIQUE,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE orders (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	requested_item_count INTEGER NOT NULL,
+	shipped_item_count INTEGER NOT NULL,
+	packs_json TEXT NOT NULL,
+	status TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_orders_created_at ON orders(created_at DESC);
+`
+
+func newBaselineDB(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "app.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.conn.Exec(baselineSchema); err != nil {
+		t.Fatalf("failed to create baseline schema: %v", err)
+	}
+	_, err = db.conn.Exec(`
+		INSERT INTO packs (size) VALUES (250), (500), (1000);
+		INSERT INTO orders (requested_item_count, shipped_item_count, packs_json, status, created_at) VALUES
+			(251, 500, '{"500":1}', 'new', '2024-01-01 10:00:00'),
+			(1250, 1250, '{"250":1,"1000":1}', 'shipped', '2024-01-02 10:00:00');
+	`)
+	if err != nil {
+		t.Fatalf("failed to insert baseline data: %v", err)
+	}
+	return path
+}
+
+func newMigratedDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := NewDB(newBaselineDB(t))
+	if err != nil {
+		t.Fatalf("failed to migrate baseline database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func schemaVersion(t *testing.T, db *DB) int {
+	t.Helper()
+	version, err := db.SchemaVersion()
+	if err != nil {
+		t.Fatalf("failed to read schema version: %v", err)
+	}
+	return version
+}
+
+func countRows(t *testing.T, db *DB, query string) int {
+	t.Helper()
+	var count int
+	if err := db.conn.QueryRow(query).Scan(&count); err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+	return count
+}
+
+func TestMigrateBaselineDatabase(t *testing.T) {
+	db := newMigratedDB(t)
+
+	if version := schemaVersion(t, db); version != LatestSchemaVersion {
+		t.Errorf("expected schema version %d, got %d", LatestSchemaVersion, version)
+	}
+
+	packs, err := db.GetPacks()
+	if err != nil {
+		t.Fatalf("failed to get packs: %v", err)
+	}
+	if !reflect.DeepEqual(packs, models.Packs{250, 500, 1000}) {
+		t.Errorf("expected packs %v, got %v", models.Packs{250, 500, 1000}, packs)
+	}
+
+	versions, err := db.GetPackSetVersions()
+	if err != nil {
+		t.Fatalf("failed to get pack set versions: %v", err)
+	}
+	if len(versions) != 1 || versions[0].CreatedBy != "before versioning" {
+		t.Errorf("expected the packs to become the first pack set version, got %v", versions)
+	}
+
+	orders, err := db.GetLast10Orders()
+	if err != nil {
+		t.Fatalf("failed to get orders: %v", err)
+	}
+	if len(orders) != 2 {
+		t.Fatalf("expected 2 orders, got %d", len(orders))
+	}
+	expected := []struct {
+		requested int
+		shipped   int
+		packs     map[models.Pack]int
+		status    models.OrderStatus
+	}{
+		{1250, 1250, map[models.Pack]int{250: 1, 1000: 1}, models.OrderStatusShipped},
+		{251, 500, map[models.Pack]int{500: 1}, models.OrderStatusNew},
+	}
+	for i, order := range orders {
+		if order.RequestedItemCount != expected[i].requested || order.ShippedItemCount != expected[i].shipped ||
+			!reflect.DeepEqual(order.Packs, expected[i].packs) || order.Status != expected[i].status {
+			t.Errorf("order %d changed, got %+v", order.ID, order)
+		}
+		if order.PublicID == "" {
+			t.Errorf("expected order %d to get a public id", order.ID)
+		}
+	}
+
+	if count := countRows(t, db, "SELECT COUNT(*) FROM order_packs"); count != 3 {
+		t.Errorf("expected 3 order packs, got %d", count)
+	}
+}
+
+func TestMigrateNewDatabase(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	if version := schemaVersion(t, db); version != LatestSchemaVersion {
+		t.Errorf("expected schema version %d, got %d", LatestSchemaVersion, version)
+	}
+	if count := countRows(t, db, "SELECT COUNT(*) FROM packs"); count == 0 {
+		t.Error("expected a new database to get sample packs")
+	}
+}
+
+// databases upgraded before migrations were versioned have the whole schema but no schema_migrations rows
+func TestMigrateUnversionedDatabase(t *testing.T) {
+	db := newMigratedDB(t)
+	if _, err := db.conn.Exec("DELETE FROM schema_migrations"); err != nil {
+		t.Fatalf("failed to forget migrations: %v", err)
+	}
+
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if version := schemaVersion(t, db); version != LatestSchemaVersion {
+		t.Errorf("expected schema version %d, got %d", LatestSchemaVersion, version)
+	}
+	if count := countRows(t, db, "SELECT COUNT(*) FROM orders"); count != 2 {
+		t.Errorf("expected 2 orders, got %d", count)
+	}
+	if count := countRows(t, db, "SELECT COUNT(*) FROM order_packs"); count != 3 {
+		t.Errorf("expected the order packs not to be backfilled twice, got %d", count)
+	}
+	if count := countRows(t, db, "SELECT COUNT(*) FROM pack_set_versions"); count != 1 {
+		t.Errorf("expected the pack set version not to be backfilled twice, got %d", count)
+	}
+}
+
+func TestMigrateTo(t *testing.T) {
+	db := newMigratedDB(t)
+
+	tests := []struct {
+		name           string
+		version        int
+		expectedTables int
+	}{
+		{name: "down to public ids", version: 5, expectedTables: 2},
+		{name: "up to pack set versions", version: 7, expectedTables: 5},
+		{name: "down to nothing", version: 0, expectedTables: 0},
+		{name: "up to the first migration", version: 1, expectedTables: 2},
+		{name: "up to latest", version: LatestSchemaVersion, expectedTables: 11},
+		{name: "down to packs details", version: 2, expectedTables: 2},
+		{name: "again to latest", version: LatestSchemaVersion, expectedTables: 11},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := db.MigrateTo(tt.version); err != nil {
+				t.Fatalf("failed to migrate to %d: %v", tt.version, err)
+			}
+			if version := schemaVersion(t, db); version != tt.version {
+				t.Errorf("expected schema version %d, got %d", tt.version, version)
+			}
+			tables := countRows(t, db, `SELECT COUNT(*) FROM sqlite_master
+				WHERE type = 'table' AND name NOT IN ('schema_migrations', 'sqlite_sequence')`)
+			if tables != tt.expectedTables {
+				t.Errorf("expected %d tables, got %d", tt.expectedTables, tables)
+			}
+		})
+	}
+}
+
+// going down and back up loses only what the undone migrations added
+func TestMigrateDownKeepsOlderData(t *testing.T) {
+	db := newMigratedDB(t)
+
+	if err := db.MigrateTo(4); err != nil {
+		t.Fatalf("failed to migrate down: %v", err)
+	}
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("failed to migrate up: %v", err)
+	}
+
+	orders, err := db.GetLast10Orders()
+	if err != nil {
+		t.Fatalf("failed to get orders: %v", err)
+	}
+	if len(orders) != 2 {
+		t.Fatalf("expected 2 orders, got %d", len(orders))
+	}
+	for _, order := range orders {
+		if order.PublicID == "" {
+			t.Errorf("expected order %d to get a public id again", order.ID)
+		}
+	}
+	if count := countRows(t, db, "SELECT COUNT(*) FROM packs"); count != 3 {
+		t.Errorf("expected 3 packs, got %d", count)
+	}
+}
+
+func TestMigrateToUnknownVersion(t *testing.T) {
+	db := newMigratedDB(t)
+
+	for _, version := range []int{-1, LatestSchemaVersion + 1} {
+		if err := db.MigrateTo(version); err == nil {
+			t.Errorf("expected migrating to %d to fail", version)
+		}
+	}
+
+	// a newer build migrated the database
+	_, err := db.conn.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+		LatestSchemaVersion+1, "from the future", time.Now().UTC())
+	if err != nil {
+		t.Fatalf("failed to record migration: %v", err)
+	}
+	if err := db.Migrate(); err == nil {
+		t.Error("expected migrating a newer database to fail")
+	}
+	if version := schemaVersion(t, db); version != LatestSchemaVersion+1 {
+		t.Errorf("expected the schema version to stay %d, got %d", LatestSchemaVersion+1, version)
+	}
+}
+
+func TestMigrationStatus(t *testing.T) {
+	db := newMigratedDB(t)
+	if err := db.MigrateTo(3); err != nil {
+		t.Fatalf("failed to migrate down: %v", err)
+	}
+
+	statuses, err := db.MigrationStatus()
+	if err != nil {
+		t.Fatalf("failed to get migration status: %v", err)
+	}
+	if len(statuses) != LatestSchemaVersion {
+		t.Fatalf("expected %d migrations, got %d", LatestSchemaVersion, len(statuses))
+	}
+	for i, status := range statuses {
+		if status.Version != i+1 || status.Name == "" {
+			t.Errorf("unexpected migration %+v", status)
+		}
+		if applied := status.AppliedAt != nil; applied != (status.Version <= 3) {
+			t.Errorf("migration %d: expected applied %v, got %v", status.Version, status.Version <= 3, applied)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/irreal/order-packs/repotest"
+)
+
+func TestRepositoryConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repository {
+		db, err := Open(filepath.Join(t.TempDir(), "app.db"))
+		if err != nil {
+			t.Fatalf("failed to open database: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		// migrated but without the sample data NewDB adds
+		if err := db.Migrate(); err != nil {
+			t.Fatalf("failed to migrate database: %v", err)
+		}
+		return db
+	})
+}
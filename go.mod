@@ -3,8 +3,10 @@ module github.com/irreal/order-packs
 go 1.25.1
 
 require (
-	github.com/a-h/templ v0.3.943 // indirect
-	github.com/goforj/godump v1.6.0 // indirect
-	github.com/joho/godotenv v1.5.1 // indirect
-	github.com/mattn/go-sqlite3 v1.14.32 // indirect
+	github.com/a-h/templ v0.3.943
+	github.com/joho/godotenv v1.5.1
+	github.com/lib/pq v1.12.3
+	github.com/mattn/go-sqlite3 v1.14.32
 )
+
+require github.com/goforj/godump v1.6.0 // indirect
@@ -0,0 +1,7 @@
+package idempotency
+
+import "fmt"
+
+var InvalidKeyError = fmt.Errorf("idempotency key is not valid")
+var KeyReusedError = fmt.Errorf("idempotency key was already used for a different request")
+var RequestInProgressError = fmt.Errorf("a request with this idempotency key is still in progress")
@@ -0,0 +1,87 @@
+package idempotency
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/irreal/order-packs/models"
+)
+
+// longest key accepted, clients usually send uuids
+const MaxKeyLength = 255
+
+// how long keys are remembered when not configured
+const DefaultTTL = 24 * time.Hour
+
+// how long a request holds its key. no request takes nearly this long, so one that does crashed before it could finish
+const Lease = time.Minute
+
+type Service struct {
+	ttl   time.Duration
+	lease time.Duration
+	repo  KeyRepository
+	now   func() time.Time
+}
+
+type KeyRepository interface {
+	// saves record unless its key is already taken by a record that hasn't expired by record.CreatedAt, that one is returned instead.
+	// a record that isn't completed only takes the key while it's locked. expired and abandoned records can be dropped along the way
+	ReserveIdempotencyKey(record *models.IdempotencyRecord) (*models.IdempotencyRecord, error)
+	// stores the response of a reserved key
+	CompleteIdempotencyKey(key string, statusCode int, responseBody []byte) error
+	// forgets a reserved key, so the request can be tried again
+	ReleaseIdempotencyKey(key string) error
+}
+
+func NewService(ttl time.Duration, repo KeyRepository) *Service {
+	return &Service{
+		ttl:   ttl,
+		lease: Lease,
+		repo:  repo,
+		now:   time.Now,
+	}
+}
+
+// Claims key for a request. Returns nil when the request is new and should be handled, finish it with Complete or Release.
+// Returns the stored record when the same request was already handled, its response should be replayed.
+// Fails with KeyReusedError when the key was used for a different request,
+// and with RequestInProgressError when the first request with the key hasn't finished yet.
+// A first request that didn't finish within its Lease doesn't hold the key anymore, the next one takes it over
+func (s *Service) Begin(key string, requestHash string) (*models.IdempotencyRecord, error) {
+	if key == "" || len(key) > MaxKeyLength {
+		return nil, fmt.Errorf("%w: must be between 1 and %d characters", InvalidKeyError, MaxKeyLength)
+	}
+
+	now := s.now().UTC()
+	existing, err := s.repo.ReserveIdempotencyKey(&models.IdempotencyRecord{
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.ttl),
+		LockedUntil: now.Add(s.lease),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, nil
+	}
+
+	if existing.RequestHash != requestHash {
+		return nil, KeyReusedError
+	}
+	if !existing.Completed() {
+		return nil, RequestInProgressError
+	}
+	return existing, nil
+}
+
+// stores the response to replay for the key
+func (s *Service) Complete(key string, statusCode int, responseBody []byte) error {
+	return s.repo.CompleteIdempotencyKey(key, statusCode, responseBody)
+}
+
+// lets the key be used again, for requests that failed in a way a retry could fix
+func (s *Service) Release(key string) error {
+	return s.repo.ReleaseIdempotencyKey(key)
+}
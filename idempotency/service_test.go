@@ -0,0 +1,160 @@
+package idempotency
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/irreal/order-packs/models"
+)
+
+type MockKeyRepository struct {
+	records map[string]*models.IdempotencyRecord
+}
+
+func NewMockKeyRepository() *MockKeyRepository {
+	return &MockKeyRepository{
+		records: make(map[string]*models.IdempotencyRecord),
+	}
+}
+
+func (m *MockKeyRepository) ReserveIdempotencyKey(record *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	if existing, ok := m.records[record.Key]; ok && existing.ExpiresAt.After(record.CreatedAt) &&
+		(existing.Completed() || existing.LockedUntil.After(record.CreatedAt)) {
+		return existing, nil
+	}
+	m.records[record.Key] = record
+	return nil, nil
+}
+
+func (m *MockKeyRepository) CompleteIdempotencyKey(key string, statusCode int, responseBody []byte) error {
+	m.records[key].StatusCode = statusCode
+	m.records[key].ResponseBody = responseBody
+	return nil
+}
+
+func (m *MockKeyRepository) ReleaseIdempotencyKey(key string) error {
+	delete(m.records, key)
+	return nil
+}
+
+func TestService_Begin(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		// runs before the request under test, with the service's clock at now
+		setup      func(s *Service)
+		later      time.Duration
+		key        string
+		hash       string
+		wantReplay bool
+		wantErr    error
+	}{
+		{
+			name: "new key",
+			key:  "abc",
+			hash: "one",
+		},
+		{
+			name: "same request again replays",
+			setup: func(s *Service) {
+				s.Begin("abc", "one")
+				s.Complete("abc", 200, []byte("done"))
+			},
+			later:      time.Hour,
+			key:        "abc",
+			hash:       "one",
+			wantReplay: true,
+		},
+		{
+			name: "different request with the same key",
+			setup: func(s *Service) {
+				s.Begin("abc", "one")
+				s.Complete("abc", 200, []byte("done"))
+			},
+			key:     "abc",
+			hash:    "two",
+			wantErr: KeyReusedError,
+		},
+		{
+			name: "first request still running",
+			setup: func(s *Service) {
+				s.Begin("abc", "one")
+			},
+			later:   Lease - time.Second,
+			key:     "abc",
+			hash:    "one",
+			wantErr: RequestInProgressError,
+		},
+		{
+			name: "released key can be used again",
+			setup: func(s *Service) {
+				s.Begin("abc", "one")
+				s.Release("abc")
+			},
+			key:  "abc",
+			hash: "two",
+		},
+		{
+			name: "first request died, the retry takes the key over",
+			setup: func(s *Service) {
+				s.Begin("abc", "one")
+			},
+			later: Lease,
+			key:   "abc",
+			hash:  "one",
+		},
+		{
+			name: "expired key can be used again",
+			setup: func(s *Service) {
+				s.Begin("abc", "one")
+				s.Complete("abc", 200, []byte("done"))
+			},
+			later: 2 * time.Hour,
+			key:   "abc",
+			hash:  "two",
+		},
+		{
+			name:    "empty key",
+			key:     "",
+			hash:    "one",
+			wantErr: InvalidKeyError,
+		},
+		{
+			name:    "key too long",
+			key:     strings.Repeat("k", MaxKeyLength+1),
+			hash:    "one",
+			wantErr: InvalidKeyError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService(2*time.Hour-time.Minute, NewMockKeyRepository())
+			service.now = func() time.Time { return now }
+			if tt.setup != nil {
+				tt.setup(service)
+			}
+			service.now = func() time.Time { return now.Add(tt.later) }
+
+			record, err := service.Begin(tt.key, tt.hash)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Begin() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Begin() unexpected error = %v", err)
+			}
+			if (record != nil) != tt.wantReplay {
+				t.Fatalf("Begin() = %+v, want replay %v", record, tt.wantReplay)
+			}
+			if tt.wantReplay && (record.StatusCode != 200 || string(record.ResponseBody) != "done") {
+				t.Errorf("Begin() replays %d %q, want 200 %q", record.StatusCode, record.ResponseBody, "done")
+			}
+		})
+	}
+}
@@ -18,16 +18,21 @@ func main() {
 	// load env variables from .env at root
 	godotenv.Load()
 
-	if err := run(os.Stdin, os.Stdout, os.Stderr, os.Getenv); err != nil {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr, os.Getenv); err != nil {
 		log.Fatal(err)
 	}
 }
 
 // separate run from main so that we can invoke run with dummy streams and env values during testing
-func run(stdin io.Reader, stdout, stderr io.Writer, configGetter func(key string) string) error {
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer, configGetter func(key string) string) error {
 
 	application := app.NewApp(stdin, stdout, stderr, configGetter)
 
+	// `migrate ...` manages the schema and exits, anything else serves the app
+	if len(args) > 0 && args[0] == "migrate" {
+		return application.Migrate(args[1:])
+	}
+
 	if err := application.Initialize(); err != nil {
 		return fmt.Errorf("failed to initialize app: %w", err)
 	}
@@ -0,0 +1,17 @@
+package models
+
+// Why an order got the packs it did, in plain words so support can show it to customers
+type Explanation struct {
+	Objective Objective `json:"objective"`
+	// the reasoning, one sentence per step
+	Steps []string `json:"steps"`
+	// other packings that were considered, and why they lost
+	Rejected []RejectedPacking `json:"rejected"`
+}
+
+type RejectedPacking struct {
+	Packs      map[Pack]int `json:"packs"`
+	TotalItems int          `json:"totalItems"`
+	TotalPacks int          `json:"totalPacks"`
+	Reason     string       `json:"reason"`
+}
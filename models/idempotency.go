@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// A request made with an Idempotency-Key header and, once it's done, the response to replay for it
+type IdempotencyRecord struct {
+	Key string
+	// hash of the request, a retry has to send the same one
+	RequestHash string
+	// 0 while the first request is still being handled
+	StatusCode   int
+	ResponseBody []byte
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+	// the first request holds the key until then. one that hasn't finished by then is presumed dead, and a retry takes the key over
+	LockedUntil time.Time
+}
+
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
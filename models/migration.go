@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// One schema migration of a database and when it was applied, nil when it wasn't
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
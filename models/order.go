@@ -1,14 +1,112 @@
 package models
 
-import "time"
+import (
+	"crypto/rand"
+	"math"
+	"strings"
+	"time"
+)
 
 // The request is very simple, just an int, so this is overkill,
 // but in a real world app we might need to model the request with more details
 // and it warrants introducing it here, rather than like a DTO on the http handler level
 type OrderRequest struct {
-	ItemCount int `json:"itemCount"`
+	ItemCount int       `json:"itemCount"`
+	Objective Objective `json:"objective,omitempty"`
+	// how many ranked packings to return next to the chosen one, 0 for none
+	Alternatives int `json:"alternatives,omitempty"`
+	// products ordered, instead of ItemCount. every line is packed with its product's packs
+	Lines []OrderLineRequest `json:"lines,omitempty"`
+	// what to do when the packs in stock can't cover the order, or only by going over the overshoot limit
+	Fulfillment Fulfillment `json:"fulfillment,omitempty"`
+	// the customer's own limit, on top of the global one
+	OvershootLimit *OvershootLimit `json:"overshootLimit,omitempty"`
+	// what happens to an order over its limit, the global action when empty
+	OnOvershoot OvershootAction `json:"onOvershoot,omitempty"`
+}
+
+type OrderLineRequest struct {
+	ProductID int64 `json:"productId"`
+	ItemCount int   `json:"itemCount"`
+}
+
+// One product of a multi-line order, packed on its own. The product's SKU and name are kept as they were when ordered
+type OrderLine struct {
+	ProductID          int64        `json:"productId"`
+	ProductSKU         string       `json:"productSku"`
+	ProductName        string       `json:"productName"`
+	RequestedItemCount int          `json:"requestedItemCount"`
+	ShippedItemCount   int          `json:"shippedItemCount"`
+	Packs              map[Pack]int `json:"packs"`
+	PackagingCost      int          `json:"packagingCost"`  // in cents
+	ShipmentWeight     int          `json:"shipmentWeight"` // in grams
 }
 
+// What the calculator optimizes for. Every objective sends whole packs and at least the requested items
+type Objective string
+
+const (
+	// rules 2 and 3, least items first, then fewest packs. used when no objective is given
+	ObjectiveLeastItems Objective = "least-items"
+	// fewest packs first, even if more items are sent
+	ObjectiveFewestPacks Objective = "fewest-packs"
+	// lowest total packaging cost first, then least items, then fewest packs
+	ObjectiveLowestCost Objective = "lowest-cost"
+)
+
+// What happens to an order the packs can't cover without shipping too much or running out of stock
+type Fulfillment string
+
+const (
+	// ship everything at once, as far over the request as the packs need, or fail when stock is short.
+	// used when no fulfillment is given
+	FulfillmentOvership Fulfillment = "overship"
+	// ship the most items that fit in the request now and put the rest on a linked backorder
+	FulfillmentBackorder Fulfillment = "backorder"
+)
+
+// How far over its request an order may ship, a field left out has no limit. 0 allows no overshoot at all
+type OvershootLimit struct {
+	MaxItems   *int `json:"maxItems,omitempty"`
+	MaxPercent *int `json:"maxPercent,omitempty"` // of the requested items
+}
+
+func (l OvershootLimit) Enabled() bool {
+	return l.MaxItems != nil || l.MaxPercent != nil
+}
+
+// the most items an order for requestedCount may ship, false when nothing limits it
+func (l OvershootLimit) MaxShipped(requestedCount int) (int, bool) {
+	if !l.Enabled() {
+		return 0, false
+	}
+	// limits too big to add up don't limit anything
+	maxShipped := math.MaxInt
+	if l.MaxItems != nil && *l.MaxItems <= math.MaxInt-requestedCount {
+		maxShipped = requestedCount + *l.MaxItems
+	}
+	if l.MaxPercent != nil && *l.MaxPercent < math.MaxInt/max(requestedCount, 1) {
+		maxShipped = min(maxShipped, requestedCount+*l.MaxPercent*requestedCount/100)
+	}
+	return maxShipped, true
+}
+
+// true when shipping shippedCount items for requestedCount goes over either limit
+func (l OvershootLimit) Exceeded(requestedCount, shippedCount int) bool {
+	maxShipped, limited := l.MaxShipped(requestedCount)
+	return limited && shippedCount > maxShipped
+}
+
+// What happens to an order that would ship more than its overshoot limit allows
+type OvershootAction string
+
+const (
+	// the order isn't placed. used when no action is given
+	OvershootReject OvershootAction = "reject"
+	// the order is placed, but waits for an admin to approve or reject it before it's packed
+	OvershootApproval OvershootAction = "approval"
+)
+
 // Not really needed for the task, but an example to support a more realistic UI
 type OrderStatus string
 
@@ -17,12 +115,82 @@ const (
 	OrderStatusPending OrderStatus = "pending"
 	OrderStatusPacked  OrderStatus = "packed"
 	OrderStatusShipped OrderStatus = "shipped"
+	// can happen at any point before shipping
+	OrderStatusCancelled OrderStatus = "cancelled"
+	// the rest of an order that only shipped partly. it has no packs, it records the items still owed
+	OrderStatusBackordered OrderStatus = "backordered"
+	// over its overshoot limit, waits for an admin to approve it, which makes it new, or reject it
+	OrderStatusNeedsApproval OrderStatus = "needs_approval"
+	// turned down by an admin, its packs went back in stock
+	OrderStatusRejected OrderStatus = "rejected"
 )
 
 type Order struct {
+	ID                 int64        `json:"id"`
+	PublicID           string       `json:"publicId"` // random, safe to hand out to customers unlike the sequential ID
 	RequestedItemCount int          `json:"requestedItemCount"`
 	ShippedItemCount   int          `json:"shippedItemCount"`
 	Packs              map[Pack]int `json:"packs"`
+	PackagingCost      int          `json:"packagingCost"`  // in cents
+	ShipmentWeight     int          `json:"shipmentWeight"` // tare weight of the packs, in grams
 	Status             OrderStatus  `json:"status"`
 	CreatedAt          time.Time    `json:"createdAt"`
+	UpdatedAt          time.Time    `json:"updatedAt"` // last status change, same as CreatedAt until then
+	// pack set version the order was calculated with, 0 for orders placed before pack sets were versioned
+	PackSetVersion int64 `json:"packSetVersion,omitempty"`
+	// why the order got these packs. saved with the order, only loaded for a single order
+	Explanation *Explanation `json:"explanation,omitempty"`
+	// only when requested, returned with the new order but not saved
+	Alternatives []PackingAlternative `json:"alternatives,omitempty"`
+	// multi-line orders only, only loaded for a single order. the fields above are the totals of all lines,
+	// except Packs, which stays empty. the lines' packs are their products' and only mean something per line
+	Lines []OrderLine `json:"lines,omitempty"`
+	// the packs split into cartons or pallets, nil when containers have no limits. saved with the order, only loaded for a single order
+	Shipment *Shipment `json:"shipment,omitempty"`
+	// pallets, cases and loose packs, nil when there were no case types. saved with the order, only loaded for a single order
+	Packaging *PackagingPlan `json:"packaging,omitempty"`
+	// backorders only, public ID of the order this holds the rest of
+	BackorderOf string `json:"backorderOf,omitempty"`
+	// the order holding the items this one couldn't ship, saved together with it. only loaded for a single order
+	Backorder *Order `json:"backorder,omitempty"`
+}
+
+// What an order would look like with the current packs, without placing it
+type Quote struct {
+	RequestedItemCount int          `json:"requestedItemCount"`
+	Packs              map[Pack]int `json:"packs"`
+	TotalItems         int          `json:"totalItems"`
+	TotalPacks         int          `json:"totalPacks"`
+	Overshoot          int          `json:"overshoot"`      // items sent above the requested count
+	PackagingCost      int          `json:"packagingCost"`  // in cents
+	ShipmentWeight     int          `json:"shipmentWeight"` // tare weight of the packs, in grams
+	// only when requested
+	Alternatives []PackingAlternative `json:"alternatives,omitempty"`
+	// nil when containers have no limits
+	Shipment *Shipment `json:"shipment,omitempty"`
+	// items that would go on a backorder, only when the request asks for one
+	Backordered int `json:"backordered,omitempty"`
+	// over the overshoot limit, the order would wait for approval
+	NeedsApproval bool `json:"needsApproval,omitempty"`
+	// the objective's packing goes over the overshoot limit, these are the best packs within it instead
+	WithinLimit bool `json:"withinLimit,omitempty"`
+}
+
+// One of the ranked packings for an order, so packers can substitute packs they don't have at hand
+type PackingAlternative struct {
+	Packs          map[Pack]int `json:"packs"`
+	TotalItems     int          `json:"totalItems"`
+	TotalPacks     int          `json:"totalPacks"`
+	Overshoot      int          `json:"overshoot"`
+	PackagingCost  int          `json:"packagingCost"`
+	ShipmentWeight int          `json:"shipmentWeight"`
+	// as good as the best packing, which one gets picked is arbitrary
+	Optimal bool `json:"optimal"`
+}
+
+// public order IDs look like ord_ followed by 26 random characters
+const PublicOrderIDPrefix = "ord_"
+
+func NewPublicOrderID() string {
+	return PublicOrderIDPrefix + strings.ToLower(rand.Text())
 }
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// How order history is sorted. Every sort falls back to the order id, so pages are stable
+type OrderSort string
+
+const (
+	OrderSortNewest        OrderSort = "newest"
+	OrderSortOldest        OrderSort = "oldest"
+	OrderSortRequestedDesc OrderSort = "requested-desc"
+	OrderSortRequestedAsc  OrderSort = "requested-asc"
+	OrderSortShippedDesc   OrderSort = "shipped-desc"
+	OrderSortShippedAsc    OrderSort = "shipped-asc"
+)
+
+// Filters for order history, zero values don't filter
+type OrderQuery struct {
+	Statuses []OrderStatus
+	// created at or after From and before To
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// inclusive ranges
+	MinRequested int
+	MaxRequested int
+	MinShipped   int
+	MaxShipped   int
+	// only orders that use packs of this size
+	PackSize Pack
+	// leaves out orders with lines, their products have their own packs, and backorders, their items were asked for once already
+	SingleCountOnly bool
+	Sort            OrderSort
+	Limit           int
+	// continue after this order, taken from the previous page
+	After *OrderCursor
+}
+
+// Position of an order in a sorted history: the value it's sorted by and its id to break ties
+type OrderCursor struct {
+	Sort  OrderSort `json:"sort"`
+	Value int64     `json:"value"`
+	ID    int64     `json:"id"`
+}
+
+type OrderPage struct {
+	Orders []*Order `json:"orders"`
+	// pass as cursor to get the next page, empty on the last page
+	NextCursor string `json:"nextCursor,omitempty"`
+}
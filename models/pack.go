@@ -1,14 +1,165 @@
 package models
 
-// In a real world app, a pack would likely be a struct with more fields
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
 
-// type Pack struct {
-// 	Size int
-// 	...other fields
-// }
-
-// However, here we will declare a pack to be an int, as we only care about it's size,
-// and it makes it less awkward than a struct with a single field for primary key purposes such storing in maps
+// A pack is keyed by its size, which is all the calculator cares about.
+// Keeping it an int makes it less awkward than a struct for primary key purposes such storing in maps,
+// everything else we know about a pack lives in PackDetails
 type Pack int
 
 type Packs []Pack
+
+// details with only the size set, for packs we know nothing else about
+func (p Packs) Details() []PackDetails {
+	details := make([]PackDetails, len(p))
+	for i, pack := range p {
+		details[i] = PackDetails{Size: pack}
+	}
+	return details
+}
+
+// outer dimensions of a pack in millimeters
+type Dimensions struct {
+	Length int `json:"length"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+type PackDetails struct {
+	Size Pack   `json:"size"`
+	SKU  string `json:"sku"`
+	Name string `json:"name"`
+	// packaging cost of a single pack, in cents
+	UnitCost int `json:"unitCost"`
+	// weight of the empty pack, in grams
+	TareWeight int        `json:"tareWeight"`
+	Dimensions Dimensions `json:"dimensions"`
+	// packs of this size left in the warehouse, nil when stock is not tracked and the supply is unlimited
+	Stock *int `json:"stock"`
+	// set when a change gives stock as null, which stops tracking it. a nil Stock without it keeps the live stock
+	// of the size, stock is counted in the warehouse rather than configured, see WithLiveStock
+	ClearStock bool `json:"-"`
+	// disabled packs stay configured, but orders don't use them
+	Disabled bool `json:"disabled"`
+}
+
+// a pack can also be given as just its size, e.g. [250, 500] instead of [{"size": 250}, {"size": 500}]
+func (p *PackDetails) UnmarshalJSON(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] != '{' {
+		var size int
+		if err := json.Unmarshal(trimmed, &size); err != nil {
+			return err
+		}
+		*p = PackDetails{Size: Pack(size)}
+		return nil
+	}
+
+	// alias drops the method so we don't recurse
+	type packDetails PackDetails
+	if err := json.Unmarshal(data, (*packDetails)(p)); err != nil {
+		return err
+	}
+
+	// a missing stock and a null one both leave Stock nil
+	var stock struct {
+		Stock json.RawMessage `json:"stock"`
+	}
+	if err := json.Unmarshal(data, &stock); err != nil {
+		return err
+	}
+	p.ClearStock = string(stock.Stock) == "null"
+	return nil
+}
+
+func (p PackDetails) Validate() error {
+	if p.Size <= 0 {
+		return fmt.Errorf("pack size must be positive")
+	}
+	if p.UnitCost < 0 {
+		return fmt.Errorf("unit cost of pack %d must not be negative", p.Size)
+	}
+	if p.TareWeight < 0 {
+		return fmt.Errorf("tare weight of pack %d must not be negative", p.Size)
+	}
+	if p.Dimensions.Length < 0 || p.Dimensions.Width < 0 || p.Dimensions.Height < 0 {
+		return fmt.Errorf("dimensions of pack %d must not be negative", p.Size)
+	}
+	if p.Stock != nil && *p.Stock < 0 {
+		return fmt.Errorf("stock of pack %d must not be negative", p.Size)
+	}
+	return nil
+}
+
+// sizes of the given packs, in the same order
+func PackSizes(details []PackDetails) Packs {
+	sizes := make(Packs, len(details))
+	for i, pack := range details {
+		sizes[i] = pack.Size
+	}
+	return sizes
+}
+
+// stock levels of the packs that track stock, sizes missing from the map are unlimited
+func StockLevels(details []PackDetails) map[Pack]int {
+	stock := make(map[Pack]int)
+	for _, pack := range details {
+		if pack.Stock != nil {
+			stock[pack.Size] = *pack.Stock
+		}
+	}
+	return stock
+}
+
+// The packs with the live stock of their size where they leave Stock nil without ClearStock.
+// New sizes without stock aren't tracked. packs is not modified
+func WithLiveStock(packs []PackDetails, live []PackDetails) []PackDetails {
+	liveStock := make(map[Pack]*int, len(live))
+	for _, pack := range live {
+		liveStock[pack.Size] = pack.Stock
+	}
+
+	merged := make([]PackDetails, len(packs))
+	for i, pack := range packs {
+		if pack.Stock == nil && !pack.ClearStock {
+			pack.Stock = liveStock[pack.Size]
+		}
+		pack.ClearStock = false
+		merged[i] = pack
+	}
+	return merged
+}
+
+// the packs orders can use
+func EnabledPacks(details []PackDetails) []PackDetails {
+	enabled := make([]PackDetails, 0, len(details))
+	for _, pack := range details {
+		if !pack.Disabled {
+			enabled = append(enabled, pack)
+		}
+	}
+	return enabled
+}
+
+type PackOperationType string
+
+const (
+	// adds a new pack size, the size must not be configured yet
+	PackOperationAdd PackOperationType = "add"
+	// adds a pack size or replaces everything about an existing one
+	PackOperationPut     PackOperationType = "put"
+	PackOperationRemove  PackOperationType = "remove"
+	PackOperationEnable  PackOperationType = "enable"
+	PackOperationDisable PackOperationType = "disable"
+)
+
+// One change to the pack set. add and put take the whole pack, the others only its size
+type PackOperation struct {
+	Op   PackOperationType `json:"op"`
+	Pack PackDetails       `json:"pack"`
+	Size Pack              `json:"size"`
+}
@@ -0,0 +1,32 @@
+package models
+
+// A pack set to check before saving it. Every requested item count from From to To is calculated with it
+type PackAnalysisRequest struct {
+	Packs     []PackDetails `json:"packs"`
+	Objective Objective     `json:"objective,omitempty"`
+	// item count range to calculate, 0 picks a default
+	From int `json:"from,omitempty"`
+	To   int `json:"to,omitempty"`
+}
+
+// How a pack set would do, and what looks wrong with it
+type PackSetAnalysis struct {
+	// distinct enabled sizes that were analyzed, sorted
+	Packs Packs `json:"packs"`
+	From  int   `json:"from"`
+	To    int   `json:"to"`
+	// sizes given more than once
+	DuplicateSizes Packs `json:"duplicateSizes"`
+	// sizes the calculator never picked for any count in the range
+	UnusedSizes Packs `json:"unusedSizes"`
+	// sizes that are a sum of other sizes, they can save packs but never items
+	RedundantSizes Packs `json:"redundantSizes"`
+	// greatest common divisor of the sizes, every order is rounded up to a multiple of it
+	Granularity int `json:"granularity"`
+	// items sent above the requested count, the worst case is for the smallest count that hits it
+	WorstOvershoot   int     `json:"worstOvershoot"`
+	WorstOvershootAt int     `json:"worstOvershootAt"`
+	AverageOvershoot float64 `json:"averageOvershoot"`
+	// everything above that needs attention, in words
+	Warnings []string `json:"warnings"`
+}
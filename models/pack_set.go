@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// An immutable snapshot of the pack set, every save of the packs creates a new one
+type PackSetVersion struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	// whoever saved it, empty when they didn't say
+	CreatedBy string `json:"createdBy"`
+	// the older version this one restored, nil for regular saves
+	RolledBackFrom *int64 `json:"rolledBackFrom,omitempty"`
+	// when the packs take over, same as CreatedAt unless the change was scheduled ahead
+	EffectiveFrom time.Time `json:"effectiveFrom"`
+	// when the packs actually went live, nil while a scheduled change is still upcoming
+	ActivatedAt *time.Time `json:"activatedAt,omitempty"`
+	// packs as they were saved, stock is the level set at the time
+	Packs []PackDetails `json:"packs"`
+}
+
+// What changed from one pack set version to another
+type PackSetDiff struct {
+	From    int64         `json:"from"`
+	To      int64         `json:"to"`
+	Added   []PackDetails `json:"added"`
+	Removed []PackDetails `json:"removed"`
+	Changed []PackChange  `json:"changed"`
+}
+
+// A pack size in both versions with different details
+type PackChange struct {
+	Size   Pack        `json:"size"`
+	Before PackDetails `json:"before"`
+	After  PackDetails `json:"after"`
+}
@@ -0,0 +1,42 @@
+package models
+
+// A master case, it holds a fixed number of packs of one size and only ships full
+type CaseType struct {
+	SKU      string `json:"sku"`
+	Name     string `json:"name"`
+	PackSize Pack   `json:"packSize"`
+	Packs    int    `json:"packs"`
+}
+
+// A pallet, it holds a fixed number of cases of one type and only ships full
+type PalletType struct {
+	SKU     string `json:"sku"`
+	Name    string `json:"name"`
+	CaseSKU string `json:"caseSku"`
+	Cases   int    `json:"cases"`
+}
+
+// The packaging levels above packs, packs go in cases and cases on pallets
+type PackagingHierarchy struct {
+	Cases   []CaseType   `json:"cases"`
+	Pallets []PalletType `json:"pallets"`
+}
+
+// How the packs of an order go out: full pallets, cases that aren't on a pallet and packs that aren't in a case
+type PackagingPlan struct {
+	Pallets    []PlannedPallet `json:"pallets"`
+	Cases      []PlannedCase   `json:"cases"`
+	LoosePacks map[Pack]int    `json:"loosePacks"`
+	// pallets, loose cases and loose packs, the units handled on their own
+	TotalUnits int `json:"totalUnits"`
+}
+
+type PlannedPallet struct {
+	PalletType
+	Count int `json:"count"`
+}
+
+type PlannedCase struct {
+	CaseType
+	Count int `json:"count"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Something we sell, packed in its own pack sizes instead of the warehouse pack set.
+// Multi-line orders name products by ID, every line is packed with that product's packs
+type Product struct {
+	ID int64 `json:"id"`
+	// unique between products
+	SKU  string `json:"sku"`
+	Name string `json:"name"`
+	// stock isn't tracked for product packs, disabled ones stay configured but lines don't use them
+	Packs     []PackDetails `json:"packs"`
+	CreatedAt time.Time     `json:"createdAt"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+}
@@ -0,0 +1,46 @@
+package models
+
+// What a recommended pack set should be best at
+type RecommendationGoal string
+
+const (
+	// least items sent above the requested counts, then fewest packs. used when no goal is given
+	RecommendationGoalOvershoot RecommendationGoal = "overshoot"
+	// lowest ItemCost for every item sent over plus PackCost for every pack
+	RecommendationGoalCost RecommendationGoal = "cost"
+)
+
+// Limits for a pack set recommended from order history, zero values pick a default
+type PackRecommendationRequest struct {
+	// at most this many sizes
+	MaxSizes int                `json:"maxSizes,omitempty"`
+	MinSize  int                `json:"minSize,omitempty"`
+	MaxSize  int                `json:"maxSize,omitempty"`
+	Goal     RecommendationGoal `json:"goal,omitempty"`
+	// cost goal only, in cents
+	ItemCost int `json:"itemCost,omitempty"`
+	PackCost int `json:"packCost,omitempty"`
+}
+
+// How many orders asked for the same item count
+type OrderDemand struct {
+	ItemCount int `json:"itemCount"`
+	Orders    int `json:"orders"`
+}
+
+// How a pack set does on all past orders
+type PackSetScore struct {
+	Overshoot int `json:"overshoot"`
+	Packs     int `json:"packs"`
+	// cost goal only, in cents
+	Cost int `json:"cost"`
+}
+
+type PackRecommendation struct {
+	Packs Packs `json:"packs"`
+	// orders the recommendation is based on
+	Orders int          `json:"orders"`
+	Score  PackSetScore `json:"score"`
+	// the live packs on the same orders, nil when there are none
+	Current *PackSetScore `json:"current,omitempty"`
+}
@@ -0,0 +1,30 @@
+package models
+
+// What fits in a single carton or pallet, zero limits are unlimited
+type ContainerConfig struct {
+	MaxPacks int `json:"maxPacks,omitempty"`
+	// in grams, a pack weighs its tare weight plus ItemWeight for every item in it
+	MaxWeight  int `json:"maxWeight,omitempty"`
+	ItemWeight int `json:"itemWeight,omitempty"`
+}
+
+// splitting is off until there is a limit
+func (c ContainerConfig) Enabled() bool {
+	return c.MaxPacks > 0 || c.MaxWeight > 0
+}
+
+// How the packs of an order go out
+type Shipment struct {
+	TotalContainers int             `json:"totalContainers"`
+	Containers      []Container     `json:"containers"`
+	Limits          ContainerConfig `json:"limits"`
+}
+
+// Count containers holding the same packs, identical containers are listed once
+type Container struct {
+	Count int          `json:"count"`
+	Packs map[Pack]int `json:"packs"`
+	// of a single container
+	TotalPacks int `json:"totalPacks"`
+	Weight     int `json:"weight"` // in grams
+}
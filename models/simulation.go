@@ -0,0 +1,40 @@
+package models
+
+// A pack set to replay past orders against, nothing is saved
+type SimulationRequest struct {
+	Packs     []PackDetails `json:"packs"`
+	Objective Objective     `json:"objective,omitempty"`
+}
+
+// Sums over the replayed orders
+type SimulationTotals struct {
+	ShippedItems  int `json:"shippedItems"`
+	Overshoot     int `json:"overshoot"` // items sent above the requested counts
+	Packs         int `json:"packs"`
+	PackagingCost int `json:"packagingCost"` // in cents
+}
+
+// One replayed order, what it got and what it would have gotten
+type SimulatedOrder struct {
+	OrderID            int64            `json:"orderId"`
+	RequestedItemCount int              `json:"requestedItemCount"`
+	ActualPacks        map[Pack]int     `json:"actualPacks"`
+	SimulatedPacks     map[Pack]int     `json:"simulatedPacks"`
+	Actual             SimulationTotals `json:"actual"`
+	Simulated          SimulationTotals `json:"simulated"`
+}
+
+// How a pack set would have done on past orders compared to what actually shipped
+type Simulation struct {
+	Orders    int              `json:"orders"`
+	Actual    SimulationTotals `json:"actual"`
+	Simulated SimulationTotals `json:"simulated"`
+	// orders that would have shipped fewer items, or as many in fewer packs. Worse is the other way around
+	Better    int `json:"better"`
+	Worse     int `json:"worse"`
+	Unchanged int `json:"unchanged"`
+	// the orders that would have changed the most, biggest change in overshoot first
+	LargestChanges []SimulatedOrder `json:"largestChanges"`
+	// more orders matched than can be replayed at once, only the oldest were
+	Truncated bool `json:"truncated"`
+}
@@ -0,0 +1,280 @@
+package orders
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/irreal/order-packs/models"
+)
+
+// most alternatives returned for one order, ties with the best packing included
+const MaxAlternatives = 10
+
+// the alternatives dp keeps MaxAlternatives combinations per total, this caps its memory. bigger orders get a truncated list
+const maxAlternativeTableCells = 20_000_000
+
+// One of the ranked packings for an order
+type Alternative struct {
+	PackingCalculation
+	// tied with the best packing, the solver could have picked either
+	Optimal bool
+}
+
+// Ranked packings for an order, best first, using the solver's strategy to rank them.
+// Returns at least the count best packings (when that many exist) and every packing tied with the best one,
+// up to MaxAlternatives in total. Only packings where every pack is needed are considered,
+// dropping any pack would leave the order short.
+//
+// Big orders are first filled up with base packs, the ranked packings all share those,
+// see alternativesWindow for why that is safe. When what's left is still too big to rank every packing,
+// the list is cut short: the best packing, then the best packing of each of the next cheapest totals, see truncatedAlternatives.
+func (s *Solver) Alternatives(requestedCount int, count int) ([]Alternative, error) {
+	if requestedCount <= 0 {
+		return nil, fmt.Errorf("requested count must be greater than 0")
+	}
+	if count <= 0 || count > MaxAlternatives {
+		return nil, fmt.Errorf("alternatives count must be between 1 and %d", MaxAlternatives)
+	}
+
+	s.alternativesOnce.Do(s.buildAlternativesWindow)
+	basePacks := 0
+	if requestedCount > s.alternativesWindow+s.base {
+		basePacks = (requestedCount - s.alternativesWindow - 1) / s.base
+	}
+	remaining := requestedCount - basePacks*s.base
+
+	if (remaining+s.largest)*MaxAlternatives*len(s.packs) > maxAlternativeTableCells {
+		return s.truncatedAlternatives(requestedCount, count)
+	}
+	alternatives := s.alternativeCandidates(remaining, basePacks > 0)
+	if len(alternatives) == 0 {
+		return nil, fmt.Errorf("no packing found for %d items", requestedCount)
+	}
+
+	slices.SortStableFunc(alternatives, func(a, b rankedAlternative) int {
+		if a.cost != b.cost {
+			return a.cost - b.cost
+		}
+		if a.TotalItems != b.TotalItems {
+			return a.TotalItems - b.TotalItems
+		}
+		return a.TotalPacks - b.TotalPacks
+	})
+
+	result := make([]Alternative, 0, count)
+	for _, alternative := range alternatives {
+		tied := alternative.cost == alternatives[0].cost &&
+			alternative.TotalItems == alternatives[0].TotalItems &&
+			alternative.TotalPacks == alternatives[0].TotalPacks
+		if len(result) >= MaxAlternatives || (len(result) >= count && !tied) {
+			break
+		}
+
+		alternative.Optimal = tied
+		if basePacks > 0 {
+			alternative.Packs[models.Pack(s.base)] += basePacks
+			alternative.TotalItems += basePacks * s.base
+			alternative.TotalPacks += basePacks
+		}
+		result = append(result, alternative.Alternative)
+	}
+	return result, nil
+}
+
+// The best packing and the best packings of the next cheapest totals, from the solver's tables like the rivals in an explanation.
+// Other packings of the same totals, ties with the best one included, aren't known without the dp
+func (s *Solver) truncatedAlternatives(requestedCount, count int) ([]Alternative, error) {
+	best, err := s.Solve(requestedCount)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []Alternative{{PackingCalculation: *best, Optimal: true}}
+	for _, rival := range s.rivals(requestedCount, count) {
+		if len(result) >= count {
+			break
+		}
+		if rival.TotalItems != best.TotalItems {
+			result = append(result, Alternative{PackingCalculation: *rival})
+		}
+	}
+	return result, nil
+}
+
+// an alternative with the strategy's cost, which only matters for ranking
+type rankedAlternative struct {
+	Alternative
+	cost int
+}
+
+// k best dp over exact totals, adding one pack size at a time from the largest down.
+// a packing of total t is only needed if all of its packs are bigger than t - requestedCount,
+// so the candidates for t are taken right after the smallest such pack size is added
+// with withBase, packings without a base pack are skipped, the caller adds base packs on top which would make them wasteful.
+// the table has to fit in maxAlternativeTableCells
+func (s *Solver) alternativeCandidates(requestedCount int, withBase bool) []rankedAlternative {
+	packCount := len(s.packs)
+	maxTotal := requestedCount + s.largest - 1
+
+	// for every total up to MaxAlternatives entries, best first, each with its cost, pack count and count per pack size
+	sizes := make([]int, maxTotal+1)
+	costs := make([]int, (maxTotal+1)*MaxAlternatives)
+	packs := make([]int, (maxTotal+1)*MaxAlternatives)
+	counts := make([]int32, (maxTotal+1)*MaxAlternatives*packCount)
+	sizes[0] = 1
+
+	// merge buffer for a single total
+	mergedCosts := make([]int, MaxAlternatives)
+	mergedPacks := make([]int, MaxAlternatives)
+	mergedCounts := make([]int32, MaxAlternatives*packCount)
+
+	var candidates []rankedAlternative
+	for j := packCount - 1; j >= 0; j-- {
+		size := int(s.packs[j])
+		for t := size; t <= maxTotal; t++ {
+			from := t - size
+			merged := 0
+			a, b := 0, 0
+			for merged < MaxAlternatives && (a < sizes[t] || b < sizes[from]) {
+				// entries already at t use bigger packs, they win ties
+				takeA := b >= sizes[from]
+				if !takeA && a < sizes[t] {
+					costA, costB := costs[t*MaxAlternatives+a], costs[from*MaxAlternatives+b]+s.costs[j]
+					packsA, packsB := packs[t*MaxAlternatives+a], packs[from*MaxAlternatives+b]+1
+					takeA = costA < costB || (costA == costB && packsA <= packsB)
+				}
+
+				target := mergedCounts[merged*packCount : (merged+1)*packCount]
+				if takeA {
+					index := t*MaxAlternatives + a
+					mergedCosts[merged] = costs[index]
+					mergedPacks[merged] = packs[index]
+					copy(target, counts[index*packCount:(index+1)*packCount])
+					a++
+				} else {
+					index := from*MaxAlternatives + b
+					mergedCosts[merged] = costs[index] + s.costs[j]
+					mergedPacks[merged] = packs[index] + 1
+					copy(target, counts[index*packCount:(index+1)*packCount])
+					target[j]++
+					b++
+				}
+				merged++
+			}
+
+			sizes[t] = merged
+			copy(costs[t*MaxAlternatives:], mergedCosts[:merged])
+			copy(packs[t*MaxAlternatives:], mergedPacks[:merged])
+			copy(counts[t*MaxAlternatives*packCount:], mergedCounts[:merged*packCount])
+		}
+
+		// totals whose smallest allowed pack is this one
+		from := requestedCount
+		if j > 0 {
+			from = max(from, requestedCount+int(s.packs[j-1]))
+		}
+		for t := from; t < requestedCount+size && t <= maxTotal; t++ {
+			for r := range sizes[t] {
+				index := t*MaxAlternatives + r
+				if withBase && counts[index*packCount+s.baseIndex] == 0 {
+					continue
+				}
+				finalPacks := make(map[models.Pack]int)
+				for i, n := range counts[index*packCount : (index+1)*packCount] {
+					if n > 0 {
+						finalPacks[s.packs[i]] = int(n)
+					}
+				}
+				candidates = append(candidates, rankedAlternative{
+					Alternative: Alternative{PackingCalculation: PackingCalculation{
+						Packs:      finalPacks,
+						TotalItems: t,
+						TotalPacks: packs[index],
+					}},
+					cost: costs[index],
+				})
+			}
+		}
+	}
+
+	return candidates
+}
+
+// With the base pack B, a packing for a big order is some other packs O plus enough B packs to cover the rest.
+// Its total then only depends on the residue of O's sum, and among packings with the same residue
+// (and the same smallest pack, which decides whether every pack is needed) the ranking is by
+// O's extra cost and weight compared to B packs, the same as in buildResidueTables. That doesn't depend on the order,
+// so only the MaxAlternatives best O of each such group can ever be ranked, and the largest sum among those is the window.
+// Orders above it can set aside base packs down to the window without losing any of the ranked packings.
+func (s *Solver) buildAlternativesWindow() {
+	type entry struct{ cost, weight, sum int }
+	less := func(a, b entry) bool {
+		if a.cost != b.cost {
+			return a.cost < b.cost
+		}
+		if a.weight != b.weight {
+			return a.weight < b.weight
+		}
+		return a.sum < b.sum
+	}
+
+	// best combinations of other packs per residue, other pack sizes added from the largest down
+	// so every table is for packs at or above some size
+	previous := make([][]entry, s.base)
+	previous[0] = []entry{{}}
+	for j := len(s.packs) - 1; j >= 0; j-- {
+		pack := int(s.packs[j])
+		if pack == s.base {
+			continue
+		}
+		step := entry{cost: s.costs[j]*s.base - s.baseCost*pack, weight: s.base - pack, sum: pack}
+
+		// current[r] is the best of previous[r] and current[r - pack] plus one more pack.
+		// adding the pack walks residues in cycles, each is walked around until nothing improves, every step adds weight so it settles
+		current := slices.Clone(previous)
+		for start := range gcd(pack, s.base) {
+			for changed := true; changed; {
+				changed = false
+				for r := (start + pack) % s.base; ; r = (r + pack) % s.base {
+					from := current[(r-pack%s.base+s.base)%s.base]
+					merged := make([]entry, 0, MaxAlternatives)
+					a, b := 0, 0
+					for len(merged) < MaxAlternatives && (a < len(previous[r]) || b < len(from)) {
+						next := entry{}
+						if b < len(from) {
+							next = entry{from[b].cost + step.cost, from[b].weight + step.weight, from[b].sum + step.sum}
+						}
+						if b >= len(from) || (a < len(previous[r]) && !less(next, previous[r][a])) {
+							merged = append(merged, previous[r][a])
+							a++
+						} else {
+							merged = append(merged, next)
+							b++
+						}
+					}
+					if !slices.Equal(merged, current[r]) {
+						current[r] = merged
+						changed = true
+					}
+					if r == start {
+						break
+					}
+				}
+			}
+		}
+
+		for _, entries := range current {
+			for _, e := range entries {
+				s.alternativesWindow = max(s.alternativesWindow, e.sum)
+			}
+		}
+		previous = current
+	}
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
@@ -0,0 +1,253 @@
+package orders
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/irreal/order-packs/models"
+)
+
+func TestSolver_Alternatives(t *testing.T) {
+	solver, err := NewSolver([]models.Pack{250, 500, 1000, 2000, 5000})
+	if err != nil {
+		t.Fatalf("NewSolver() error = %v", err)
+	}
+
+	alternatives, err := solver.Alternatives(501, 3)
+	if err != nil {
+		t.Fatalf("Alternatives() error = %v", err)
+	}
+
+	expected := []struct {
+		packs   map[models.Pack]int
+		items   int
+		optimal bool
+	}{
+		{map[models.Pack]int{250: 1, 500: 1}, 750, true},
+		{map[models.Pack]int{250: 3}, 750, false},
+		{map[models.Pack]int{1000: 1}, 1000, false},
+	}
+
+	if len(alternatives) != len(expected) {
+		t.Fatalf("got %d alternatives, want %d: %+v", len(alternatives), len(expected), alternatives)
+	}
+	for i, want := range expected {
+		got := alternatives[i]
+		if got.TotalItems != want.items || got.Optimal != want.optimal || fmt.Sprint(got.Packs) != fmt.Sprint(want.packs) {
+			t.Errorf("alternative %d = %v (%d items, optimal %v), want %v (%d items, optimal %v)",
+				i, got.Packs, got.TotalItems, got.Optimal, want.packs, want.items, want.optimal)
+		}
+	}
+}
+
+func TestSolver_Alternatives_IncludesTies(t *testing.T) {
+	// 10 items fit 2x5 or 1x3 + 1x7, both with 2 packs
+	solver, err := NewSolver([]models.Pack{3, 5, 7})
+	if err != nil {
+		t.Fatalf("NewSolver() error = %v", err)
+	}
+
+	alternatives, err := solver.Alternatives(10, 1)
+	if err != nil {
+		t.Fatalf("Alternatives() error = %v", err)
+	}
+
+	if len(alternatives) != 2 {
+		t.Fatalf("got %d alternatives, want both tied packings: %+v", len(alternatives), alternatives)
+	}
+	for i, alternative := range alternatives {
+		if !alternative.Optimal || alternative.TotalItems != 10 || alternative.TotalPacks != 2 {
+			t.Errorf("alternative %d = %+v, want an optimal packing of 10 items in 2 packs", i, alternative)
+		}
+	}
+}
+
+// compares the ranking of every alternative with an enumeration of all packings,
+// across the point where big orders are filled up with base packs first
+func TestSolver_Alternatives_MatchesEnumeration(t *testing.T) {
+	tests := []struct {
+		name     string
+		packs    []models.Pack
+		strategy Strategy
+		// 0 to go past the window, enumerating every packing gets slow for bigger packs
+		maxCount int
+	}{
+		{"small coprime packs", []models.Pack{3, 5, 7}, LeastItemsStrategy{}, 0},
+		{"fewest packs", []models.Pack{3, 5, 7}, FewestPacksStrategy{}, 0},
+		{"lowest cost", []models.Pack{4, 6, 9}, LowestCostStrategy{Costs: map[models.Pack]int{4: 3, 6: 5, 9: 6}}, 0},
+		{"lowest cost with a free pack", []models.Pack{2, 5, 8}, LowestCostStrategy{Costs: map[models.Pack]int{2: 0, 5: 2, 8: 1}}, 0},
+		{"edge case packs", []models.Pack{23, 31, 53}, LeastItemsStrategy{}, 600},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			solver, err := NewStrategySolver(tt.packs, tt.strategy)
+			if err != nil {
+				t.Fatalf("NewStrategySolver() error = %v", err)
+			}
+
+			maxCount := tt.maxCount
+			if maxCount == 0 {
+				solver.alternativesOnce.Do(solver.buildAlternativesWindow)
+				maxCount = solver.alternativesWindow + 3*solver.base
+			}
+			for requestedCount := 1; requestedCount <= maxCount; requestedCount++ {
+				alternatives, err := solver.Alternatives(requestedCount, MaxAlternatives)
+				if err != nil {
+					t.Fatalf("Alternatives(%d) error = %v", requestedCount, err)
+				}
+
+				expected := enumerateAlternatives(solver, requestedCount)
+				if len(alternatives) != min(len(expected), MaxAlternatives) {
+					t.Fatalf("Alternatives(%d) returned %d, want %d", requestedCount, len(alternatives), min(len(expected), MaxAlternatives))
+				}
+
+				seen := make(map[string]bool)
+				for i, alternative := range alternatives {
+					got := rankAlternative(solver, alternative.Packs)
+					if got != expected[i] {
+						t.Fatalf("Alternatives(%d)[%d] = %+v %v, want %+v", requestedCount, i, got, alternative.Packs, expected[i])
+					}
+					if alternative.TotalItems != got.items || alternative.TotalPacks != got.packs {
+						t.Fatalf("Alternatives(%d)[%d] reports %d items in %d packs, packs add up to %d in %d",
+							requestedCount, i, alternative.TotalItems, alternative.TotalPacks, got.items, got.packs)
+					}
+					if alternative.Optimal != (got == expected[0]) {
+						t.Fatalf("Alternatives(%d)[%d].Optimal = %v", requestedCount, i, alternative.Optimal)
+					}
+					if key := fmt.Sprint(alternative.Packs); seen[key] {
+						t.Fatalf("Alternatives(%d) returned %v twice", requestedCount, alternative.Packs)
+					} else {
+						seen[key] = true
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSolver_Alternatives_ErrorCases(t *testing.T) {
+	solver, err := NewSolver([]models.Pack{250, 500})
+	if err != nil {
+		t.Fatalf("NewSolver() error = %v", err)
+	}
+
+	if _, err := solver.Alternatives(0, 3); err == nil {
+		t.Errorf("Alternatives() expected error for zero requested count")
+	}
+	if _, err := solver.Alternatives(10, 0); err == nil {
+		t.Errorf("Alternatives() expected error for zero alternatives")
+	}
+	if _, err := solver.Alternatives(10, MaxAlternatives+1); err == nil {
+		t.Errorf("Alternatives() expected error for too many alternatives")
+	}
+}
+
+type alternativeRank struct {
+	cost  int
+	items int
+	packs int
+}
+
+func rankAlternative(solver *Solver, packs map[models.Pack]int) alternativeRank {
+	var rank alternativeRank
+	for i, pack := range solver.packs {
+		rank.cost += solver.costs[i] * packs[pack]
+		rank.items += int(pack) * packs[pack]
+		rank.packs += packs[pack]
+	}
+	return rank
+}
+
+// every packing where each pack is needed, ranked
+func enumerateAlternatives(solver *Solver, requestedCount int) []alternativeRank {
+	var ranks []alternativeRank
+	counts := make([]int, len(solver.packs))
+
+	var enumerate func(i, total int)
+	enumerate = func(i, total int) {
+		if i == len(solver.packs) {
+			if total < requestedCount {
+				return
+			}
+			packs := make(map[models.Pack]int)
+			for j, count := range counts {
+				if count > 0 {
+					if total-int(solver.packs[j]) >= requestedCount {
+						return
+					}
+					packs[solver.packs[j]] = count
+				}
+			}
+			ranks = append(ranks, rankAlternative(solver, packs))
+			return
+		}
+		for counts[i] = 0; total+counts[i]*int(solver.packs[i]) < requestedCount+solver.largest; counts[i]++ {
+			enumerate(i+1, total+counts[i]*int(solver.packs[i]))
+		}
+		counts[i] = 0
+	}
+	enumerate(0, 0)
+
+	slices.SortFunc(ranks, func(a, b alternativeRank) int {
+		return cmp.Or(cmp.Compare(a.cost, b.cost), cmp.Compare(a.items, b.items), cmp.Compare(a.packs, b.packs))
+	})
+	return ranks
+}
+
+func TestSolver_Alternatives_BigOrder(t *testing.T) {
+	solver, err := NewSolver([]models.Pack{250, 500, 1000, 2000, 5000})
+	if err != nil {
+		t.Fatalf("NewSolver() error = %v", err)
+	}
+
+	alternatives, err := solver.Alternatives(300000001, 3)
+	if err != nil {
+		t.Fatalf("Alternatives() error = %v", err)
+	}
+
+	best, err := solver.Solve(300000001)
+	if err != nil {
+		t.Fatalf("Solve() error = %v", err)
+	}
+	if len(alternatives) != 3 || !alternatives[0].Optimal || fmt.Sprint(alternatives[0].Packs) != fmt.Sprint(best.Packs) {
+		t.Fatalf("Alternatives() = %+v, want 3 starting with %v", alternatives, best.Packs)
+	}
+
+	for i, alternative := range alternatives[1:] {
+		if alternative.TotalItems < alternatives[i].TotalItems ||
+			(alternative.TotalItems == alternatives[i].TotalItems && alternative.TotalPacks < alternatives[i].TotalPacks) {
+			t.Errorf("alternative %d (%d items, %d packs) ranks above the one before it", i+1, alternative.TotalItems, alternative.TotalPacks)
+		}
+	}
+}
+
+func TestSolver_Alternatives_TruncatedForLargeOrders(t *testing.T) {
+	// close sizes leave a window too big to rank every packing in
+	solver, err := NewSolver([]models.Pack{997, 1000})
+	if err != nil {
+		t.Fatalf("NewSolver() error = %v", err)
+	}
+	best, err := solver.Solve(12345678)
+	if err != nil {
+		t.Fatalf("Solve() error = %v", err)
+	}
+
+	alternatives, err := solver.Alternatives(12345678, 5)
+	if err != nil {
+		t.Fatalf("Alternatives() error = %v", err)
+	}
+	if len(alternatives) != 5 {
+		t.Fatalf("got %d alternatives, want 5: %+v", len(alternatives), alternatives)
+	}
+	if !alternatives[0].Optimal || alternatives[0].TotalItems != best.TotalItems || alternatives[0].TotalPacks != best.TotalPacks {
+		t.Errorf("first alternative = %+v, want the best packing %+v", alternatives[0], best)
+	}
+	for i, alternative := range alternatives[1:] {
+		if alternative.Optimal || alternative.TotalItems <= alternatives[i].TotalItems {
+			t.Errorf("alternative %d = %+v, want a worse packing shipping more than %d items", i+1, alternative, alternatives[i].TotalItems)
+		}
+	}
+}
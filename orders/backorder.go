@@ -0,0 +1,106 @@
+package orders
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/irreal/order-packs/models"
+)
+
+// Packs the most items it can without going over requestedCount, using at most stock[pack] packs of each size.
+// Sizes missing from stock are unlimited. Of the packings of that many items, the solver's strategy picks as usual.
+// The calculation has no packs when even the smallest pack is too big.
+func (s *Solver) SolveUnder(requestedCount int, stock map[models.Pack]int) (*PackingCalculation, error) {
+	if requestedCount <= 0 {
+		return nil, fmt.Errorf("requested count must be greater than 0")
+	}
+
+	nothing := &PackingCalculation{Packs: map[models.Pack]int{}}
+	// without the small order table small orders go straight to the dp, which finds the largest total itself
+	total := requestedCount
+	if !s.PerOrder() || requestedCount > s.bound {
+		total = s.largestExactTotal(requestedCount)
+		if total == 0 {
+			return nothing, nil
+		}
+
+		calculation := s.packExactly(total)
+		if fitsStock(calculation, stock) {
+			return calculation, nil
+		}
+	}
+
+	// the same total might still work with other packs, otherwise a smaller one
+	packs, stockItems, unlimited := s.boundedPacks(stock)
+	if len(packs) == 0 {
+		return nothing, nil
+	}
+
+	// with enough stock of the pivot a best packing is less than a pivot pack short of total,
+	// so it has all but the window in pivot packs, see stockPivot. at least a pivot pack is left for the dp
+	pivot, setAside := -1, 0
+	if index, window := stockPivot(packs, total); index >= 0 && total > window+packs[index].size {
+		pivot, setAside = index, (total-window-packs[index].size)/packs[index].size
+	}
+	packs, remainingCount := setAsidePacks(packs, pivot, setAside, total)
+
+	maxSize := remainingCount
+	if !unlimited {
+		maxSize = min(maxSize, stockItems-(total-remainingCount))
+	}
+	table, err := newBoundedTable(packs, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	for total := maxSize; total > 0; total-- {
+		if table.minCost[total] != math.MaxInt {
+			return withSetAside(table.packing(total), packs, pivot, setAside), nil
+		}
+	}
+	return nothing, nil
+}
+
+// the largest total up to count that whole packs add up to exactly, 0 when even the smallest pack is too big.
+// past the bound every residue that is reachable at all is reachable, so this checks at most a base pack of totals there
+func (s *Solver) largestExactTotal(count int) int {
+	for total := count; total > 0; total-- {
+		if total < len(s.minCost) {
+			if s.minCost[total] != math.MaxInt {
+				return total
+			}
+		} else if s.residueCost[total%s.base] != math.MaxInt {
+			return total
+		}
+	}
+	return 0
+}
+
+// the best packs for exactly total items, total has to be reachable
+func (s *Solver) packExactly(total int) *PackingCalculation {
+	if total < len(s.minCost) {
+		return s.packSmall(total)
+	}
+	return s.packLarge(total)
+}
+
+// the packs an order asking for a backorder ships now, fails with BackorderError when nothing would ship
+func partialPacking(solver *Solver, requestedCount int, stock map[models.Pack]int) (*PackingCalculation, error) {
+	calculation, err := solver.SolveUnder(requestedCount, stock)
+	if err != nil {
+		return nil, err
+	}
+	if calculation.TotalItems == 0 {
+		return nil, fmt.Errorf("%w: no packing of at most %d items can ship now, the whole order would be backordered", BackorderError, requestedCount)
+	}
+	return calculation, nil
+}
+
+// the explanation step for an order that shipped partly
+func backorderStep(limit models.OvershootLimit, shipped, backordered int) string {
+	if limit.Enabled() {
+		return fmt.Sprintf("The order allows a backorder, so rather than go over the overshoot limit of %s or wait for stock, "+
+			"the %d items that fit ship now and the other %d are backordered.", describeOvershootLimit(limit), shipped, backordered)
+	}
+	return fmt.Sprintf("The order allows a backorder, so rather than wait for stock, the %d items that fit ship now and the other %d are backordered.",
+		shipped, backordered)
+}
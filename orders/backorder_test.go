@@ -0,0 +1,231 @@
+package orders
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/irreal/order-packs/models"
+)
+
+// the partial packing has to match trying every combination within stock that doesn't go over the order
+func TestSolver_SolveUnder_MatchesBruteForce(t *testing.T) {
+	packs := []models.Pack{3, 5, 8}
+	strategies := []Strategy{
+		LeastItemsStrategy{},
+		FewestPacksStrategy{},
+		LowestCostStrategy{Costs: map[models.Pack]int{3: 1, 5: 4, 8: 2}},
+	}
+	stocks := []map[models.Pack]int{
+		nil,
+		{3: 2, 5: 3, 8: 1},
+		{3: 0, 5: 1},
+	}
+
+	for _, strategy := range strategies {
+		solver, err := NewStrategySolver(packs, strategy)
+		if err != nil {
+			t.Fatalf("NewStrategySolver() error = %v", err)
+		}
+
+		for _, stock := range stocks {
+			limit := func(pack models.Pack) int {
+				if available, limited := stock[pack]; limited {
+					return available
+				}
+				return 20
+			}
+
+			for requestedCount := 1; requestedCount <= 60; requestedCount++ {
+				result, err := solver.SolveUnder(requestedCount, stock)
+				if err != nil {
+					t.Fatalf("%s SolveUnder(%d, %v) error = %v", strategy.Objective(), requestedCount, stock, err)
+				}
+
+				// most items first, then as the strategy ranks them
+				bestItems, bestCost, bestPacks := 0, 0, 0
+				for a := 0; a <= limit(3); a++ {
+					for b := 0; b <= limit(5); b++ {
+						for c := 0; c <= limit(8); c++ {
+							items := 3*a + 5*b + 8*c
+							if items > requestedCount || items < bestItems {
+								continue
+							}
+							cost := a*solver.costs[0] + b*solver.costs[1] + c*solver.costs[2]
+							count := a + b + c
+							if items > bestItems || cost < bestCost || (cost == bestCost && count < bestPacks) {
+								bestItems, bestCost, bestPacks = items, cost, count
+							}
+						}
+					}
+				}
+
+				if result.TotalItems != bestItems || result.TotalPacks != bestPacks {
+					t.Errorf("%s SolveUnder(%d, %v) = %d items in %d packs, want %d items in %d packs",
+						strategy.Objective(), requestedCount, stock, result.TotalItems, result.TotalPacks, bestItems, bestPacks)
+				}
+				for pack, count := range result.Packs {
+					if count > limit(pack) {
+						t.Errorf("%s SolveUnder(%d, %v) uses %d packs of %d, only %d in stock",
+							strategy.Objective(), requestedCount, stock, count, pack, limit(pack))
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestSolver_SolveUnder_LargeOrders(t *testing.T) {
+	solver, err := NewSolver([]models.Pack{250, 500, 1000, 2000, 5000})
+	if err != nil {
+		t.Fatalf("NewSolver() error = %v", err)
+	}
+
+	result, err := solver.SolveUnder(1_000_000_001, nil)
+	if err != nil {
+		t.Fatalf("SolveUnder() error = %v", err)
+	}
+	if result.TotalItems != 1_000_000_000 || result.TotalPacks != 200_000 {
+		t.Errorf("SolveUnder() = %d items in %d packs, want 1000000000 items in 200000 packs", result.TotalItems, result.TotalPacks)
+	}
+}
+
+func TestService_CreateOrder_Backorder(t *testing.T) {
+	stocked := func(stock map[models.Pack]int) []models.PackDetails {
+		details := models.Packs{250, 500, 1000}.Details()
+		for i := range details {
+			if available, limited := stock[details[i].Size]; limited {
+				details[i].Stock = &available
+			}
+		}
+		return details
+	}
+
+	tests := []struct {
+		name                string
+		maxOvershoot        *int
+		orderRequest        models.OrderRequest
+		stock               map[models.Pack]int
+		expectedShipped     int
+		expectedBackordered int
+		expectedError       error
+	}{
+		{
+			name:            "backorder overships without a limit",
+			orderRequest:    models.OrderRequest{ItemCount: 1001, Fulfillment: models.FulfillmentBackorder},
+			expectedShipped: 1250,
+		},
+		{
+			name:            "overships without the backorder fulfillment",
+			orderRequest:    models.OrderRequest{ItemCount: 1001},
+			expectedShipped: 1250,
+		},
+		{
+			// without the backorder fulfillment the order isn't split, the overshoot limit rejects it, see TestService_CreateOrder_OvershootLimit
+			name:          "over the overshoot limit without the backorder fulfillment",
+			maxOvershoot:  limitOf(100),
+			orderRequest:  models.OrderRequest{ItemCount: 1001},
+			expectedError: &OvershootLimitError{},
+		},
+		{
+			name:            "within the overshoot limit",
+			maxOvershoot:    limitOf(250),
+			orderRequest:    models.OrderRequest{ItemCount: 1001, Fulfillment: models.FulfillmentBackorder},
+			expectedShipped: 1250,
+		},
+		{
+			name:                "over the overshoot limit",
+			maxOvershoot:        limitOf(100),
+			orderRequest:        models.OrderRequest{ItemCount: 1001, Fulfillment: models.FulfillmentBackorder},
+			expectedShipped:     1000,
+			expectedBackordered: 1,
+		},
+		{
+			name:                "short on stock",
+			orderRequest:        models.OrderRequest{ItemCount: 2000, Fulfillment: models.FulfillmentBackorder},
+			stock:               map[models.Pack]int{250: 1, 500: 1, 1000: 1},
+			expectedShipped:     1750,
+			expectedBackordered: 250,
+		},
+		{
+			name:          "short on stock without the backorder fulfillment",
+			orderRequest:  models.OrderRequest{ItemCount: 2000},
+			stock:         map[models.Pack]int{250: 1, 500: 1, 1000: 1},
+			expectedError: InsufficientStockError,
+		},
+		{
+			name:          "nothing fits in the order",
+			maxOvershoot:  limitOf(100),
+			orderRequest:  models.OrderRequest{ItemCount: 100, Fulfillment: models.FulfillmentBackorder},
+			expectedError: BackorderError,
+		},
+		{
+			name:          "unknown fulfillment",
+			orderRequest:  models.OrderRequest{ItemCount: 100, Fulfillment: "later"},
+			expectedError: InvalidFulfillmentError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := NewMockOrderRepository()
+			service := NewService(1000000, mockRepo)
+			service.Overshoot.MaxItems = tt.maxOvershoot
+
+			order, err := service.CreateOrder(tt.orderRequest, stocked(tt.stock), 0)
+			if tt.expectedError != nil {
+				var overshootErr *OvershootLimitError
+				if _, typed := tt.expectedError.(*OvershootLimitError); typed && !errors.As(err, &overshootErr) {
+					t.Errorf("CreateOrder() error = %v, want an OvershootLimitError", err)
+				} else if !typed && !errors.Is(err, tt.expectedError) {
+					t.Errorf("CreateOrder() error = %v, want %v", err, tt.expectedError)
+				}
+				if len(mockRepo.GetSavedOrders()) != 0 {
+					t.Errorf("CreateOrder() saved %d orders, want none", len(mockRepo.GetSavedOrders()))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateOrder() unexpected error = %v", err)
+			}
+
+			if order.RequestedItemCount != tt.orderRequest.ItemCount || order.ShippedItemCount != tt.expectedShipped {
+				t.Errorf("CreateOrder() requested %d shipped %d, want %d shipped %d",
+					order.RequestedItemCount, order.ShippedItemCount, tt.orderRequest.ItemCount, tt.expectedShipped)
+			}
+
+			if tt.expectedBackordered == 0 {
+				if order.Backorder != nil || len(mockRepo.GetSavedOrders()) != 1 {
+					t.Errorf("Backorder = %+v, want none", order.Backorder)
+				}
+				return
+			}
+
+			backorder := order.Backorder
+			if backorder == nil {
+				t.Fatal("Backorder = nil, want one")
+			}
+			if backorder.RequestedItemCount != tt.expectedBackordered || backorder.ShippedItemCount != 0 || len(backorder.Packs) != 0 {
+				t.Errorf("Backorder = %+v, want %d items requested and nothing shipped", backorder, tt.expectedBackordered)
+			}
+			if backorder.Status != models.OrderStatusBackordered || backorder.BackorderOf != order.PublicID {
+				t.Errorf("Backorder status %s of %q, want %s of %q", backorder.Status, backorder.BackorderOf, models.OrderStatusBackordered, order.PublicID)
+			}
+			if saved := mockRepo.GetSavedOrders(); len(saved) != 2 || saved[1] != backorder {
+				t.Errorf("CreateOrder() saved %d orders, want the order and its backorder", len(saved))
+			}
+		})
+	}
+}
+
+func TestService_Quote_Backorder(t *testing.T) {
+	service := NewService(1000000, NewMockOrderRepository())
+	service.Overshoot.MaxItems = limitOf(100)
+
+	quote, err := service.Quote(models.OrderRequest{ItemCount: 1001, Fulfillment: models.FulfillmentBackorder}, models.Packs{250, 500, 1000}.Details())
+	if err != nil {
+		t.Fatalf("Quote() unexpected error = %v", err)
+	}
+	if quote.TotalItems != 1000 || quote.Backordered != 1 || quote.Overshoot != 0 {
+		t.Errorf("Quote() = %d items, %d backordered, %d overshoot, want 1000, 1 and 0", quote.TotalItems, quote.Backordered, quote.Overshoot)
+	}
+}
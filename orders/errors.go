@@ -4,3 +4,19 @@ import "fmt"
 
 var InvalidOrderItemCountError = fmt.Errorf("requested count is not valid")
 var OrderCalculationError = fmt.Errorf("order calculation failed")
+var InvalidObjectiveError = fmt.Errorf("objective is not valid")
+var InsufficientStockError = fmt.Errorf("not enough packs in stock")
+var InvalidAlternativesError = fmt.Errorf("alternatives count is not valid")
+var OrderNotFoundError = fmt.Errorf("order not found")
+var InvalidStatusError = fmt.Errorf("order status is not valid")
+var OrderStatusChangedError = fmt.Errorf("order status was changed in the meantime")
+var InvalidOrderQueryError = fmt.Errorf("order query is not valid")
+var InvalidPackSetError = fmt.Errorf("pack set is not valid")
+var PackSetTooLargeError = fmt.Errorf("pack set is too large to calculate")
+var InvalidRecommendationError = fmt.Errorf("pack recommendation request is not valid")
+var NotEnoughOrdersError = fmt.Errorf("not enough orders")
+var InvalidOrderLinesError = fmt.Errorf("order lines are not valid")
+var ContainerCapacityError = fmt.Errorf("pack does not fit in a container")
+var InvalidFulfillmentError = fmt.Errorf("fulfillment is not valid")
+var BackorderError = fmt.Errorf("order can't be partly shipped")
+var InvalidOvershootLimitError = fmt.Errorf("overshoot limit is not valid")
@@ -0,0 +1,159 @@
+package orders
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/irreal/order-packs/models"
+)
+
+// rival packings listed in an explanation
+const explainedRivals = 4
+
+var objectiveDescriptions = map[models.Objective]string{
+	models.ObjectiveLeastItems:  "least items shipped, then fewest packs",
+	models.ObjectiveFewestPacks: "fewest packs, then least items shipped",
+	models.ObjectiveLowestCost:  "lowest packaging cost, then least items shipped, then fewest packs",
+}
+
+// Explains why chosen is the packing for the order. chosen is what Solve or SolveWithStock returned,
+// stock is what was passed to SolveWithStock, nil when stock wasn't considered.
+func (s *Solver) Explain(requestedCount int, chosen *PackingCalculation, stock map[models.Pack]int) (*models.Explanation, error) {
+	best, err := s.Solve(requestedCount)
+	if err != nil {
+		return nil, err
+	}
+
+	objective := s.strategy.Objective()
+	sizes := make([]string, len(s.packs))
+	for i, pack := range s.packs {
+		sizes[i] = fmt.Sprint(pack)
+	}
+
+	explanation := &models.Explanation{Objective: objective}
+	explanation.Steps = append(explanation.Steps, fmt.Sprintf("Ordered %d items, available packs are %s. Packs are picked for the %s.",
+		requestedCount, strings.Join(sizes, ", "), objectiveDescriptions[objective]))
+
+	bestCost := s.packingCost(best.Packs)
+	extra := best.TotalItems - requestedCount
+	switch objective {
+	case models.ObjectiveFewestPacks:
+		explanation.Steps = append(explanation.Steps,
+			fmt.Sprintf("No packing covers %d items with fewer than %d packs.", requestedCount, best.TotalPacks),
+			fmt.Sprintf("Of the packings with %d packs, the one shipping the least items ships %d (%d extra): %s.",
+				best.TotalPacks, best.TotalItems, extra, describePacks(best.Packs)))
+	case models.ObjectiveLowestCost:
+		explanation.Steps = append(explanation.Steps,
+			fmt.Sprintf("No packing covers %d items for less than %d cents of packaging.", requestedCount, bestCost),
+			fmt.Sprintf("Of the packings costing %d cents, the least items shipped is %d (%d extra), which fit in no fewer than %d packs: %s.",
+				bestCost, best.TotalItems, extra, best.TotalPacks, describePacks(best.Packs)))
+	default:
+		if extra == 0 {
+			explanation.Steps = append(explanation.Steps, fmt.Sprintf("%d items can be packed exactly, so nothing extra is shipped.", requestedCount))
+		} else {
+			explanation.Steps = append(explanation.Steps, fmt.Sprintf("No combination of whole packs adds up to anything from %d to %d items, "+
+				"so the smallest total that covers the order is %d (%d extra).", requestedCount, best.TotalItems-1, best.TotalItems, extra))
+		}
+		explanation.Steps = append(explanation.Steps, fmt.Sprintf("%d items fit in no fewer than %d packs: %s.",
+			best.TotalItems, best.TotalPacks, describePacks(best.Packs)))
+	}
+
+	if !maps.Equal(chosen.Packs, best.Packs) && !fitsStock(best, stock) {
+		explanation.Steps = append(explanation.Steps, fmt.Sprintf("That needs more packs than are in stock, "+
+			"so the best packing that is in stock was used instead: %s, %d items in %d packs.",
+			describePacks(chosen.Packs), chosen.TotalItems, chosen.TotalPacks))
+	}
+
+	chosenCost := s.packingCost(chosen.Packs)
+	ties := 0
+	for _, rival := range s.rivals(requestedCount, explainedRivals+1) {
+		if maps.Equal(rival.Packs, chosen.Packs) || len(explanation.Rejected) >= explainedRivals {
+			continue
+		}
+
+		rivalCost := s.packingCost(rival.Packs)
+		var reason string
+		switch {
+		case stock != nil && !fitsStock(rival, stock):
+			reason = "needs more packs than are in stock"
+		case rivalCost > chosenCost && objective == models.ObjectiveFewestPacks:
+			reason = fmt.Sprintf("uses %d packs instead of %d", rival.TotalPacks, chosen.TotalPacks)
+		case rivalCost > chosenCost:
+			reason = fmt.Sprintf("costs %d cents more to pack", rivalCost-chosenCost)
+		case rival.TotalItems > chosen.TotalItems:
+			reason = fmt.Sprintf("ships %d items, %d more than the chosen packing", rival.TotalItems, rival.TotalItems-chosen.TotalItems)
+		case rival.TotalPacks > chosen.TotalPacks:
+			reason = fmt.Sprintf("ships the same %d items in %d packs instead of %d", rival.TotalItems, rival.TotalPacks, chosen.TotalPacks)
+		default:
+			ties++
+			reason = "just as good, only one of the tied packings can be shipped"
+		}
+
+		explanation.Rejected = append(explanation.Rejected, models.RejectedPacking{
+			Packs:      rival.Packs,
+			TotalItems: rival.TotalItems,
+			TotalPacks: rival.TotalPacks,
+			Reason:     reason,
+		})
+	}
+
+	if ties > 0 {
+		explanation.Steps = append(explanation.Steps, fmt.Sprintf("%d other packings are tied with it, any of them would have done.", ties))
+	}
+
+	return explanation, nil
+}
+
+// The best packings of the count cheapest totals covering the order, cheapest first, then the least items.
+// They come from the solver's tables, so listing them costs a lookup per total instead of a dp per order
+func (s *Solver) rivals(requestedCount, count int) []*PackingCalculation {
+	type rival struct{ total, cost int }
+	cheapest := make([]rival, 0, count+1)
+	// past a largest pack over the order every packing has a pack it doesn't need
+	for total := requestedCount; total < requestedCount+s.largest; total++ {
+		cost := s.exactCost(total)
+		if cost == math.MaxInt || (len(cheapest) == count && cost >= cheapest[count-1].cost) {
+			continue
+		}
+		i := len(cheapest)
+		for i > 0 && cheapest[i-1].cost > cost {
+			i--
+		}
+		cheapest = slices.Insert(cheapest, i, rival{total, cost})
+		if len(cheapest) > count {
+			cheapest = cheapest[:count]
+		}
+	}
+
+	rivals := make([]*PackingCalculation, len(cheapest))
+	for i, rival := range cheapest {
+		rivals[i] = s.packExactly(rival.total)
+	}
+	return rivals
+}
+
+// total cost of the packs as priced by the solver's strategy
+func (s *Solver) packingCost(packs map[models.Pack]int) int {
+	cost := 0
+	for pack, count := range packs {
+		if i, found := slices.BinarySearch(s.packs, pack); found {
+			cost += s.costs[i] * count
+		}
+	}
+	return cost
+}
+
+// e.g. "1 x 500 + 1 x 250", largest packs first
+func describePacks(packs map[models.Pack]int) string {
+	sizes := slices.Sorted(maps.Keys(packs))
+	slices.Reverse(sizes)
+
+	parts := make([]string, len(sizes))
+	for i, pack := range sizes {
+		parts[i] = fmt.Sprintf("%d x %d", packs[pack], pack)
+	}
+	return strings.Join(parts, " + ")
+}
@@ -0,0 +1,145 @@
+package orders
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/irreal/order-packs/models"
+)
+
+func TestCalculatePackExplained(t *testing.T) {
+	result, err := CalculatePackExplained([]models.Pack{250, 500, 1000, 2000, 5000}, 501, LeastItemsStrategy{})
+	if err != nil {
+		t.Fatalf("CalculatePackExplained() error = %v", err)
+	}
+
+	explanation := result.Explanation
+	if explanation == nil {
+		t.Fatal("Explanation = nil, want one")
+	}
+
+	steps := strings.Join(explanation.Steps, "\n")
+	for _, want := range []string{
+		"anything from 501 to 749 items",
+		"750 items fit in no fewer than 2 packs: 1 x 500 + 1 x 250",
+	} {
+		if !strings.Contains(steps, want) {
+			t.Errorf("Steps = %q, want them to mention %q", steps, want)
+		}
+	}
+
+	// the packing from the dispute, 1 x 1000, has to be among the rejected ones
+	found := false
+	for _, rejected := range explanation.Rejected {
+		if rejected.Packs[1000] == 1 && len(rejected.Packs) == 1 {
+			found = true
+			if !strings.Contains(rejected.Reason, "250 more") {
+				t.Errorf("1 x 1000 rejected because %q, want it to mention the 250 extra items", rejected.Reason)
+			}
+		}
+		if rejected.Packs[250] == 1 && rejected.Packs[500] == 1 && len(rejected.Packs) == 2 {
+			t.Errorf("chosen packing listed as rejected")
+		}
+	}
+	if !found {
+		t.Errorf("Rejected = %+v, want 1 x 1000 among them", explanation.Rejected)
+	}
+}
+
+func TestSolver_Explain(t *testing.T) {
+	tests := []struct {
+		name      string
+		strategy  Strategy
+		stock     map[models.Pack]int
+		count     int
+		wantSteps []string
+		// reason of the first rejected packing
+		wantRejected string
+	}{
+		{
+			name:         "exact match",
+			strategy:     LeastItemsStrategy{},
+			count:        1000,
+			wantSteps:    []string{"1000 items can be packed exactly", "in no fewer than 1 packs"},
+			wantRejected: "ships 1250 items, 250 more than the chosen packing",
+		},
+		{
+			name:         "fewest packs",
+			strategy:     FewestPacksStrategy{},
+			count:        501,
+			wantSteps:    []string{"with fewer than 1 packs", "ships 1000 (499 extra): 1 x 1000"},
+			wantRejected: "uses 2 packs instead of 1",
+		},
+		{
+			name:         "lowest cost",
+			strategy:     LowestCostStrategy{Costs: map[models.Pack]int{250: 40, 500: 80, 1000: 100}},
+			count:        501,
+			wantSteps:    []string{"for less than 100 cents", "fit in no fewer than 1 packs: 1 x 1000"},
+			wantRejected: "costs 20 cents more to pack",
+		},
+		{
+			name:         "out of stock",
+			strategy:     LeastItemsStrategy{},
+			stock:        map[models.Pack]int{500: 0},
+			count:        501,
+			wantSteps:    []string{"more packs than are in stock", "3 x 250"},
+			wantRejected: "needs more packs than are in stock",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			solver, err := NewStrategySolver([]models.Pack{250, 500, 1000}, tt.strategy)
+			if err != nil {
+				t.Fatalf("NewStrategySolver() error = %v", err)
+			}
+			chosen, err := solver.SolveWithStock(tt.count, tt.stock)
+			if err != nil {
+				t.Fatalf("SolveWithStock() error = %v", err)
+			}
+
+			explanation, err := solver.Explain(tt.count, chosen, tt.stock)
+			if err != nil {
+				t.Fatalf("Explain() error = %v", err)
+			}
+
+			if explanation.Objective != tt.strategy.Objective() {
+				t.Errorf("Objective = %q, want %q", explanation.Objective, tt.strategy.Objective())
+			}
+			steps := strings.Join(explanation.Steps, "\n")
+			for _, want := range tt.wantSteps {
+				if !strings.Contains(steps, want) {
+					t.Errorf("Steps = %q, want them to mention %q", steps, want)
+				}
+			}
+			if len(explanation.Rejected) == 0 || explanation.Rejected[0].Reason != tt.wantRejected {
+				t.Errorf("Rejected = %+v, want the first one rejected because %q", explanation.Rejected, tt.wantRejected)
+			}
+		})
+	}
+}
+
+func TestSolver_Explain_LargeOrder(t *testing.T) {
+	// too big for the alternatives dp, the rivals come from the solver's tables
+	solver, err := NewSolver([]models.Pack{997, 1000})
+	if err != nil {
+		t.Fatalf("NewSolver() error = %v", err)
+	}
+	chosen, err := solver.Solve(12345678)
+	if err != nil {
+		t.Fatalf("Solve() error = %v", err)
+	}
+
+	explanation, err := solver.Explain(12345678, chosen, nil)
+	if err != nil {
+		t.Fatalf("Explain() error = %v", err)
+	}
+	if len(explanation.Rejected) != explainedRivals {
+		t.Fatalf("Rejected = %+v, want %d rivals", explanation.Rejected, explainedRivals)
+	}
+	for _, rejected := range explanation.Rejected {
+		if rejected.TotalItems <= chosen.TotalItems {
+			t.Errorf("rejected %d items, want more than the chosen %d", rejected.TotalItems, chosen.TotalItems)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package orders
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/irreal/order-packs/models"
+)
+
+const (
+	DefaultOrderPageSize = 20
+	MaxOrderPageSize     = 100
+)
+
+// One page of order history. cursor is the NextCursor of the previous page, empty for the first one,
+// and only works with the same sort it was made for
+func (s *Service) ListOrders(query models.OrderQuery, cursor string) (*models.OrderPage, error) {
+	if query.Sort == "" {
+		query.Sort = models.OrderSortNewest
+	}
+	if query.Limit == 0 {
+		query.Limit = DefaultOrderPageSize
+	}
+	if err := validateOrderQuery(query); err != nil {
+		return nil, err
+	}
+
+	if cursor != "" {
+		after, err := decodeOrderCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		if after.Sort != query.Sort {
+			return nil, fmt.Errorf("%w: cursor is for sort %q, not %q", InvalidOrderQueryError, after.Sort, query.Sort)
+		}
+		query.After = after
+	}
+
+	// one more than asked for tells whether there is a next page
+	limit := query.Limit
+	query.Limit++
+	orders, err := s.repo.ListOrders(query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.OrderPage{Orders: orders}
+	if len(orders) > limit {
+		page.Orders = orders[:limit]
+		page.NextCursor = encodeOrderCursor(cursorFor(query.Sort, page.Orders[limit-1]))
+	}
+	return page, nil
+}
+
+func validateOrderQuery(query models.OrderQuery) error {
+	for _, status := range query.Statuses {
+		if _, known := statusTransitions[status]; !known {
+			return fmt.Errorf("%w: %q", InvalidStatusError, status)
+		}
+	}
+	switch query.Sort {
+	case models.OrderSortNewest, models.OrderSortOldest,
+		models.OrderSortRequestedDesc, models.OrderSortRequestedAsc,
+		models.OrderSortShippedDesc, models.OrderSortShippedAsc:
+	default:
+		return fmt.Errorf("%w: unknown sort %q", InvalidOrderQueryError, query.Sort)
+	}
+	if query.Limit < 1 || query.Limit > MaxOrderPageSize {
+		return fmt.Errorf("%w: limit must be between 1 and %d", InvalidOrderQueryError, MaxOrderPageSize)
+	}
+	if query.MinRequested < 0 || query.MaxRequested < 0 || query.MinShipped < 0 || query.MaxShipped < 0 || query.PackSize < 0 {
+		return fmt.Errorf("%w: item counts and pack size can't be negative", InvalidOrderQueryError)
+	}
+	if query.MaxRequested != 0 && query.MinRequested > query.MaxRequested {
+		return fmt.Errorf("%w: minimum requested items is above the maximum", InvalidOrderQueryError)
+	}
+	if query.MaxShipped != 0 && query.MinShipped > query.MaxShipped {
+		return fmt.Errorf("%w: minimum shipped items is above the maximum", InvalidOrderQueryError)
+	}
+	if !query.CreatedFrom.IsZero() && !query.CreatedTo.IsZero() && !query.CreatedFrom.Before(query.CreatedTo) {
+		return fmt.Errorf("%w: created from must be before created to", InvalidOrderQueryError)
+	}
+	return nil
+}
+
+// where the order sits in the sort, newest and oldest go by id alone
+func cursorFor(sort models.OrderSort, order *models.Order) *models.OrderCursor {
+	cursor := &models.OrderCursor{Sort: sort, ID: order.ID}
+	switch sort {
+	case models.OrderSortRequestedDesc, models.OrderSortRequestedAsc:
+		cursor.Value = int64(order.RequestedItemCount)
+	case models.OrderSortShippedDesc, models.OrderSortShippedAsc:
+		cursor.Value = int64(order.ShippedItemCount)
+	}
+	return cursor
+}
+
+// cursors are opaque to clients, url safe base64 of the json
+func encodeOrderCursor(cursor *models.OrderCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeOrderCursor(cursor string) (*models.OrderCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cursor is not valid", InvalidOrderQueryError)
+	}
+	var decoded models.OrderCursor
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, fmt.Errorf("%w: cursor is not valid", InvalidOrderQueryError)
+	}
+	return &decoded, nil
+}
@@ -0,0 +1,140 @@
+package orders
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/irreal/order-packs/models"
+)
+
+const MaxOrderLines = 100
+
+// Places an order for several products at once. Every line is packed on its own with its product's enabled packs,
+// like CalculatePack does for the warehouse packs, and the order gets the totals of all lines. Its Packs stay empty,
+// a product's 250 isn't the warehouse's 250, so the packs are only kept per line.
+// products are the ordered ones by ID. Product packs don't track stock and lines don't get alternatives, explanations or cases and pallets.
+// A single line over the overshoot limit rejects the whole order, or holds all of it for approval
+func (s *Service) CreateLineOrder(orderRequest models.OrderRequest, products map[int64]*models.Product) (*models.Order, error) {
+	if err := s.validateLines(orderRequest); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	order := &models.Order{
+		PublicID:  models.NewPublicOrderID(),
+		Packs:     make(map[models.Pack]int),
+		Status:    models.OrderStatusNew,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	var shipped []shipmentPack
+	for _, lineRequest := range orderRequest.Lines {
+		product := products[lineRequest.ProductID]
+		if product == nil {
+			return nil, fmt.Errorf("%w: product %d is missing", InvalidOrderLinesError, lineRequest.ProductID)
+		}
+		line, err := s.packLine(lineRequest, product, orderRequest.Objective)
+		if err != nil {
+			return nil, err
+		}
+		// the overshoot limit holds for every product on its own
+		needsApproval, err := s.checkOvershoot(orderRequest, line.RequestedItemCount, line.ShippedItemCount)
+		if err != nil {
+			return nil, fmt.Errorf("product %d: %w", line.ProductID, err)
+		}
+		if needsApproval {
+			order.Status = models.OrderStatusNeedsApproval
+		}
+
+		order.Lines = append(order.Lines, *line)
+		order.RequestedItemCount += line.RequestedItemCount
+		order.ShippedItemCount += line.ShippedItemCount
+		order.PackagingCost += line.PackagingCost
+		order.ShipmentWeight += line.ShipmentWeight
+		shipped = append(shipped, shipmentPacks(line.Packs, product.Packs, s.Containers.ItemWeight)...)
+	}
+
+	// products share the containers
+	shipment, err := assignContainers(shipped, s.Containers)
+	if err != nil {
+		return nil, err
+	}
+	order.Shipment = shipment
+
+	if err := s.repo.SaveOrder(order); err != nil {
+		return nil, fmt.Errorf("failed to save order: %w", err)
+	}
+	return order, nil
+}
+
+func (s *Service) validateLines(orderRequest models.OrderRequest) error {
+	if len(orderRequest.Lines) == 0 || len(orderRequest.Lines) > MaxOrderLines {
+		return fmt.Errorf("%w: an order has between 1 and %d lines", InvalidOrderLinesError, MaxOrderLines)
+	}
+	if orderRequest.ItemCount != 0 {
+		return fmt.Errorf("%w: give either an item count or lines, not both", InvalidOrderLinesError)
+	}
+	if orderRequest.Alternatives != 0 {
+		return fmt.Errorf("%w: alternatives are not offered for orders with lines", InvalidAlternativesError)
+	}
+	// product packs have no stock to run out of
+	if orderRequest.Fulfillment == models.FulfillmentBackorder {
+		return fmt.Errorf("%w: backorders are not offered for orders with lines", InvalidFulfillmentError)
+	}
+	if err := validateOvershoot(orderRequest); err != nil {
+		return err
+	}
+
+	seen := make(map[int64]bool, len(orderRequest.Lines))
+	for _, line := range orderRequest.Lines {
+		if seen[line.ProductID] {
+			return fmt.Errorf("%w: product %d is on more than one line", InvalidOrderLinesError, line.ProductID)
+		}
+		seen[line.ProductID] = true
+
+		if line.ItemCount <= 0 || line.ItemCount > s.MaxOrderItemCount {
+			return fmt.Errorf("%w: item count of product %d has to be between 1 and %d", InvalidOrderItemCountError, line.ProductID, s.MaxOrderItemCount)
+		}
+	}
+	return nil
+}
+
+// products with the same packs share a cached solver, see lineSolverFor
+func (s *Service) packLine(lineRequest models.OrderLineRequest, product *models.Product, objective models.Objective) (*models.OrderLine, error) {
+	packs := models.EnabledPacks(product.Packs)
+	packsBySize := make(map[models.Pack]models.PackDetails, len(packs))
+	costs := make(map[models.Pack]int, len(packs))
+	for _, pack := range packs {
+		packsBySize[pack.Size] = pack
+		costs[pack.Size] = pack.UnitCost
+	}
+
+	strategy, err := StrategyFor(objective, costs)
+	if err != nil {
+		return nil, err
+	}
+
+	solver, err := s.lineSolverFor(models.PackSizes(packs), strategy)
+	if err != nil {
+		return nil, fmt.Errorf("%w: product %d: %v", OrderCalculationError, product.ID, err)
+	}
+	calculation, err := solver.Solve(lineRequest.ItemCount)
+	if err != nil {
+		return nil, fmt.Errorf("%w: product %d: %v", OrderCalculationError, product.ID, err)
+	}
+
+	line := &models.OrderLine{
+		ProductID:          product.ID,
+		ProductSKU:         product.SKU,
+		ProductName:        product.Name,
+		RequestedItemCount: lineRequest.ItemCount,
+		ShippedItemCount:   calculation.TotalItems,
+		Packs:              calculation.Packs,
+	}
+	for pack, count := range calculation.Packs {
+		line.PackagingCost += packsBySize[pack].UnitCost * count
+		line.ShipmentWeight += packsBySize[pack].TareWeight * count
+	}
+	return line, nil
+}
@@ -0,0 +1,196 @@
+package orders
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/irreal/order-packs/models"
+)
+
+func testProducts() map[int64]*models.Product {
+	return map[int64]*models.Product{
+		1: {ID: 1, SKU: "BLN-RED", Name: "Red balloons", Packs: []models.PackDetails{
+			{Size: 250, UnitCost: 40, TareWeight: 30},
+			{Size: 500, UnitCost: 60, TareWeight: 50},
+		}},
+		2: {ID: 2, SKU: "BLN-BLUE", Name: "Blue balloons", Packs: []models.PackDetails{
+			{Size: 100, UnitCost: 10, TareWeight: 5},
+			{Size: 250, UnitCost: 20, TareWeight: 10, Disabled: true},
+		}},
+	}
+}
+
+func TestService_CreateLineOrder(t *testing.T) {
+	mockRepo := NewMockOrderRepository()
+	service := NewService(1000, mockRepo)
+
+	order, err := service.CreateLineOrder(models.OrderRequest{Lines: []models.OrderLineRequest{
+		{ProductID: 1, ItemCount: 501},
+		{ProductID: 2, ItemCount: 250},
+	}}, testProducts())
+	if err != nil {
+		t.Fatalf("CreateLineOrder() unexpected error = %v", err)
+	}
+
+	expectedLines := []models.OrderLine{
+		{ProductID: 1, ProductSKU: "BLN-RED", ProductName: "Red balloons", RequestedItemCount: 501, ShippedItemCount: 750,
+			Packs: map[models.Pack]int{250: 1, 500: 1}, PackagingCost: 100, ShipmentWeight: 80},
+		// 250 is disabled for this product
+		{ProductID: 2, ProductSKU: "BLN-BLUE", ProductName: "Blue balloons", RequestedItemCount: 250, ShippedItemCount: 300,
+			Packs: map[models.Pack]int{100: 3}, PackagingCost: 30, ShipmentWeight: 15},
+	}
+	if !reflect.DeepEqual(order.Lines, expectedLines) {
+		t.Errorf("Lines = %+v, want %+v", order.Lines, expectedLines)
+	}
+
+	// totals of both lines, packs by size across products
+	if order.RequestedItemCount != 751 || order.ShippedItemCount != 1050 || order.PackagingCost != 130 || order.ShipmentWeight != 95 {
+		t.Errorf("RequestedItemCount, ShippedItemCount, PackagingCost, ShipmentWeight = %d, %d, %d, %d, want 751, 1050, 130, 95",
+			order.RequestedItemCount, order.ShippedItemCount, order.PackagingCost, order.ShipmentWeight)
+	}
+	// the products' packs aren't the warehouse's, they stay on the lines
+	if len(order.Packs) != 0 {
+		t.Errorf("Packs = %v, want none", order.Packs)
+	}
+	if order.Status != models.OrderStatusNew || order.PublicID == "" || order.Explanation != nil {
+		t.Errorf("CreateLineOrder() = %+v, want a new order with a public ID and no explanation", order)
+	}
+	if len(mockRepo.GetSavedOrders()) != 1 {
+		t.Errorf("saved %d orders, want 1", len(mockRepo.GetSavedOrders()))
+	}
+
+	// the packing of each line follows the objective
+	order, err = service.CreateLineOrder(models.OrderRequest{
+		Objective: models.ObjectiveFewestPacks,
+		Lines:     []models.OrderLineRequest{{ProductID: 1, ItemCount: 251}},
+	}, testProducts())
+	if err != nil {
+		t.Fatalf("CreateLineOrder() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(order.Lines[0].Packs, map[models.Pack]int{500: 1}) {
+		t.Errorf("Packs with fewest packs = %v, want map[500:1]", order.Lines[0].Packs)
+	}
+
+	// line orders ask for their products' packs, not the warehouse ones
+	demand, _ := mockRepo.GetOrderDemand()
+	if len(demand) != 0 {
+		t.Errorf("GetOrderDemand() = %v, want no demand from line orders", demand)
+	}
+}
+
+func TestService_CreateLineOrder_InvalidRequest(t *testing.T) {
+	manyLines := make([]models.OrderLineRequest, MaxOrderLines+1)
+	for i := range manyLines {
+		manyLines[i] = models.OrderLineRequest{ProductID: int64(i + 1), ItemCount: 1}
+	}
+
+	tests := []struct {
+		name          string
+		request       models.OrderRequest
+		expectedError error
+	}{
+		{
+			name:          "no lines",
+			request:       models.OrderRequest{},
+			expectedError: InvalidOrderLinesError,
+		},
+		{
+			name:          "too many lines",
+			request:       models.OrderRequest{Lines: manyLines},
+			expectedError: InvalidOrderLinesError,
+		},
+		{
+			name:          "item count and lines",
+			request:       models.OrderRequest{ItemCount: 5, Lines: []models.OrderLineRequest{{ProductID: 1, ItemCount: 1}}},
+			expectedError: InvalidOrderLinesError,
+		},
+		{
+			name:          "same product twice",
+			request:       models.OrderRequest{Lines: []models.OrderLineRequest{{ProductID: 1, ItemCount: 1}, {ProductID: 1, ItemCount: 2}}},
+			expectedError: InvalidOrderLinesError,
+		},
+		{
+			name:          "unknown product",
+			request:       models.OrderRequest{Lines: []models.OrderLineRequest{{ProductID: 9, ItemCount: 1}}},
+			expectedError: InvalidOrderLinesError,
+		},
+		{
+			name:          "zero items",
+			request:       models.OrderRequest{Lines: []models.OrderLineRequest{{ProductID: 1, ItemCount: 0}}},
+			expectedError: InvalidOrderItemCountError,
+		},
+		{
+			name:          "too many items",
+			request:       models.OrderRequest{Lines: []models.OrderLineRequest{{ProductID: 1, ItemCount: 1001}}},
+			expectedError: InvalidOrderItemCountError,
+		},
+		{
+			name:          "alternatives",
+			request:       models.OrderRequest{Alternatives: 2, Lines: []models.OrderLineRequest{{ProductID: 1, ItemCount: 1}}},
+			expectedError: InvalidAlternativesError,
+		},
+		{
+			name:          "unknown objective",
+			request:       models.OrderRequest{Objective: "most-fun", Lines: []models.OrderLineRequest{{ProductID: 1, ItemCount: 1}}},
+			expectedError: InvalidObjectiveError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := NewMockOrderRepository()
+			service := NewService(1000, mockRepo)
+			_, err := service.CreateLineOrder(tt.request, testProducts())
+			if !errors.Is(err, tt.expectedError) {
+				t.Errorf("CreateLineOrder() error = %v, want %v", err, tt.expectedError)
+			}
+			if len(mockRepo.GetSavedOrders()) != 0 {
+				t.Errorf("CreateLineOrder() saved an invalid order")
+			}
+		})
+	}
+}
+
+func TestService_CreateLineOrder_CachesSolvers(t *testing.T) {
+	service := NewService(1000, NewMockOrderRepository())
+	products := testProducts()
+	// the same packs as product 1, they share a solver
+	products[3] = &models.Product{ID: 3, SKU: "BLN-GREEN", Packs: products[1].Packs}
+
+	orderRequest := models.OrderRequest{Lines: []models.OrderLineRequest{{ProductID: 1, ItemCount: 251}, {ProductID: 2, ItemCount: 100}, {ProductID: 3, ItemCount: 1}}}
+	if _, err := service.CreateLineOrder(orderRequest, products); err != nil {
+		t.Fatalf("CreateLineOrder() unexpected error = %v", err)
+	}
+	if len(service.lineSolvers) != 2 {
+		t.Fatalf("CreateLineOrder() cached %d solvers, want one per pack set", len(service.lineSolvers))
+	}
+	cached := make(map[string]*Solver)
+	for key, solver := range service.lineSolvers {
+		cached[key] = solver
+	}
+
+	if _, err := service.CreateLineOrder(orderRequest, products); err != nil {
+		t.Fatalf("CreateLineOrder() unexpected error = %v", err)
+	}
+	for key, solver := range service.lineSolvers {
+		if cached[key] != solver {
+			t.Errorf("CreateLineOrder() built a new solver for %q, want the cached one", key)
+		}
+	}
+
+	// new packs of a product are never answered by the stale solver
+	products[1] = &models.Product{ID: 1, SKU: "BLN-RED", Packs: models.Packs{300}.Details()}
+	order, err := service.CreateLineOrder(models.OrderRequest{Lines: []models.OrderLineRequest{{ProductID: 1, ItemCount: 251}}}, products)
+	if err != nil {
+		t.Fatalf("CreateLineOrder() unexpected error = %v", err)
+	}
+	if order.ShippedItemCount != 300 {
+		t.Errorf("ShippedItemCount = %d, want 300", order.ShippedItemCount)
+	}
+
+	service.InvalidateLineSolvers()
+	if service.lineSolvers != nil {
+		t.Error("InvalidateLineSolvers() expected the solvers to be dropped")
+	}
+}
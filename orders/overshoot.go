@@ -0,0 +1,159 @@
+package orders
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/irreal/order-packs/models"
+)
+
+// Returned when the packs for an order go over its overshoot limit and the order is rejected for it
+type OvershootLimitError struct {
+	RequestedItemCount int
+	ShippedItemCount   int
+	Limit              models.OvershootLimit
+}
+
+func (e *OvershootLimitError) Error() string {
+	return fmt.Sprintf("shipping %d items for %d goes over the overshoot limit of %s",
+		e.ShippedItemCount, e.RequestedItemCount, describeOvershootLimit(e.Limit))
+}
+
+func validateOvershoot(orderRequest models.OrderRequest) error {
+	if limit := orderRequest.OvershootLimit; limit != nil && ((limit.MaxItems != nil && *limit.MaxItems < 0) || (limit.MaxPercent != nil && *limit.MaxPercent < 0)) {
+		return fmt.Errorf("%w: limits can't be negative", InvalidOvershootLimitError)
+	}
+	switch orderRequest.OnOvershoot {
+	case "", models.OvershootReject, models.OvershootApproval:
+		return nil
+	}
+	return fmt.Errorf("%w: %q, use %s or %s", InvalidOvershootLimitError, orderRequest.OnOvershoot, models.OvershootReject, models.OvershootApproval)
+}
+
+// the global limit, tightened by the request's own
+func (s *Service) overshootLimit(orderRequest models.OrderRequest) models.OvershootLimit {
+	limit := s.Overshoot
+	if own := orderRequest.OvershootLimit; own != nil {
+		limit.MaxItems = tighterLimit(limit.MaxItems, own.MaxItems)
+		limit.MaxPercent = tighterLimit(limit.MaxPercent, own.MaxPercent)
+	}
+	return limit
+}
+
+// nil is no limit, so it's the loosest
+func tighterLimit(a, b *int) *int {
+	if a == nil || (b != nil && *b < *a) {
+		return b
+	}
+	return a
+}
+
+func (s *Service) overshootAction(orderRequest models.OrderRequest) models.OvershootAction {
+	return cmp.Or(orderRequest.OnOvershoot, s.OnOvershoot, models.OvershootReject)
+}
+
+// Like SolveWithStock, but only packings of at most maxTotal items count, nil when none of them can be built from stock.
+// Orders whose best packing goes over their overshoot limit use this to find the best one within it
+func (s *Solver) SolveWithin(requestedCount, maxTotal int, stock map[models.Pack]int) (*PackingCalculation, error) {
+	if requestedCount <= 0 {
+		return nil, fmt.Errorf("requested count must be greater than 0")
+	}
+	if s.PerOrder() && requestedCount <= s.bound {
+		calculation, err := s.solveBounded(requestedCount, maxTotal, stock)
+		if errors.Is(err, InsufficientStockError) {
+			return nil, nil
+		}
+		return calculation, err
+	}
+	// dropping a pack from a bigger total only makes it better, so no best packing ships a largest pack or more over the order
+	maxTotal = min(maxTotal, requestedCount+s.largest-1)
+
+	// cheapest total, the least items on a tie
+	optimalCount, bestCost := 0, math.MaxInt
+	for total := requestedCount; total <= maxTotal; total++ {
+		if cost := s.exactCost(total); cost < bestCost {
+			optimalCount, bestCost = total, cost
+		}
+	}
+	if optimalCount == 0 {
+		return nil, nil
+	}
+
+	calculation := s.packExactly(optimalCount)
+	if fitsStock(calculation, stock) {
+		return calculation, nil
+	}
+	calculation, err := s.solveBounded(requestedCount, maxTotal, stock)
+	if errors.Is(err, InsufficientStockError) {
+		return nil, nil
+	}
+	return calculation, err
+}
+
+// cost of the best packs for exactly total items times the base size, like the residue costs.
+// math.MaxInt when whole packs don't add up to total. Without the small order table the residue tables only know
+// totals of at least the sum of their residue's other packs, smaller ones are math.MaxInt too
+func (s *Solver) exactCost(total int) int {
+	if total < len(s.minCost) {
+		if s.minCost[total] == math.MaxInt {
+			return math.MaxInt
+		}
+		return s.minCost[total] * s.base
+	}
+	if s.residueCost[total%s.base] == math.MaxInt || s.residueSum[total%s.base] > total {
+		return math.MaxInt
+	}
+	return s.residueCost[total%s.base] + s.baseCost*total
+}
+
+// Checks a packing against the request's overshoot limit. Over it, the order is either rejected with an OvershootLimitError,
+// or true comes back and the order waits for approval
+func (s *Service) checkOvershoot(orderRequest models.OrderRequest, requestedCount, shippedCount int) (bool, error) {
+	limit := s.overshootLimit(orderRequest)
+	if !limit.Exceeded(requestedCount, shippedCount) {
+		return false, nil
+	}
+	if s.overshootAction(orderRequest) == models.OvershootReject {
+		return false, &OvershootLimitError{RequestedItemCount: requestedCount, ShippedItemCount: shippedCount, Limit: limit}
+	}
+	return true, nil
+}
+
+// e.g. 100 items and 10%
+func describeOvershootLimit(limit models.OvershootLimit) string {
+	var parts []string
+	if limit.MaxItems != nil {
+		parts = append(parts, fmt.Sprintf("%d items", *limit.MaxItems))
+	}
+	if limit.MaxPercent != nil {
+		parts = append(parts, fmt.Sprintf("%d%%", *limit.MaxPercent))
+	}
+	return cmp.Or(strings.Join(parts, " and "), "nothing")
+}
+
+// Moves an order waiting for approval to new, from there it goes through packing like any other order
+func (s *Service) ApproveOrder(id string) (*models.Order, error) {
+	return s.reviewOrder(id, models.OrderStatusNew)
+}
+
+// Turns down an order waiting for approval, its packs go back in stock
+func (s *Service) RejectOrder(id string) (*models.Order, error) {
+	return s.reviewOrder(id, models.OrderStatusRejected)
+}
+
+func (s *Service) reviewOrder(id string, status models.OrderStatus) (*models.Order, error) {
+	order, err := s.FindOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.OrderStatusNeedsApproval {
+		return nil, &StatusTransitionError{From: order.Status, To: status}
+	}
+	if err := s.repo.UpdateOrderStatus(order, status); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
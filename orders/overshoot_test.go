@@ -0,0 +1,336 @@
+package orders
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/irreal/order-packs/models"
+)
+
+func TestOvershootLimit_Exceeded(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    models.OvershootLimit
+		shipped  int
+		expected bool
+	}{
+		{name: "no limit", limit: models.OvershootLimit{}, shipped: 5000, expected: false},
+		{name: "within items", limit: models.OvershootLimit{MaxItems: limitOf(250)}, shipped: 1250, expected: false},
+		{name: "over items", limit: models.OvershootLimit{MaxItems: limitOf(249)}, shipped: 1250, expected: true},
+		{name: "within percent", limit: models.OvershootLimit{MaxPercent: limitOf(25)}, shipped: 1250, expected: false},
+		{name: "over percent", limit: models.OvershootLimit{MaxPercent: limitOf(24)}, shipped: 1250, expected: true},
+		{name: "over one of both", limit: models.OvershootLimit{MaxItems: limitOf(1000), MaxPercent: limitOf(10)}, shipped: 1250, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.limit.Exceeded(1000, tt.shipped); got != tt.expected {
+				t.Errorf("Exceeded(1000, %d) = %v, want %v", tt.shipped, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestService_CreateOrder_OvershootLimit(t *testing.T) {
+	tests := []struct {
+		name           string
+		globalLimit    models.OvershootLimit
+		globalAction   models.OvershootAction
+		orderRequest   models.OrderRequest
+		expectedStatus models.OrderStatus
+		expectedError  error
+	}{
+		{
+			name:           "within the global limit",
+			globalLimit:    models.OvershootLimit{MaxItems: limitOf(249)},
+			orderRequest:   models.OrderRequest{ItemCount: 251},
+			expectedStatus: models.OrderStatusNew,
+		},
+		{
+			name:          "over the global limit",
+			globalLimit:   models.OvershootLimit{MaxItems: limitOf(200)},
+			orderRequest:  models.OrderRequest{ItemCount: 251},
+			expectedError: &OvershootLimitError{},
+		},
+		{
+			name:          "over the request's own limit",
+			globalLimit:   models.OvershootLimit{MaxItems: limitOf(1000)},
+			orderRequest:  models.OrderRequest{ItemCount: 251, OvershootLimit: &models.OvershootLimit{MaxPercent: limitOf(50)}},
+			expectedError: &OvershootLimitError{},
+		},
+		{
+			name:           "a request can't loosen the global limit",
+			globalLimit:    models.OvershootLimit{MaxItems: limitOf(200)},
+			globalAction:   models.OvershootApproval,
+			orderRequest:   models.OrderRequest{ItemCount: 251, OvershootLimit: &models.OvershootLimit{MaxItems: limitOf(1000)}},
+			expectedStatus: models.OrderStatusNeedsApproval,
+		},
+		{
+			name:           "request asks for approval",
+			orderRequest:   models.OrderRequest{ItemCount: 251, OvershootLimit: &models.OvershootLimit{MaxItems: limitOf(10)}, OnOvershoot: models.OvershootApproval},
+			expectedStatus: models.OrderStatusNeedsApproval,
+		},
+		{
+			name:          "request asks for rejection",
+			globalAction:  models.OvershootApproval,
+			orderRequest:  models.OrderRequest{ItemCount: 251, OvershootLimit: &models.OvershootLimit{MaxItems: limitOf(10)}, OnOvershoot: models.OvershootReject},
+			expectedError: &OvershootLimitError{},
+		},
+		{
+			name:          "negative limit",
+			orderRequest:  models.OrderRequest{ItemCount: 251, OvershootLimit: &models.OvershootLimit{MaxItems: limitOf(-1)}},
+			expectedError: InvalidOvershootLimitError,
+		},
+		{
+			name:          "unknown action",
+			orderRequest:  models.OrderRequest{ItemCount: 251, OnOvershoot: "ignore"},
+			expectedError: InvalidOvershootLimitError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := NewMockOrderRepository()
+			service := NewService(1000000, mockRepo)
+			service.Overshoot = tt.globalLimit
+			service.OnOvershoot = tt.globalAction
+
+			order, err := service.CreateOrder(tt.orderRequest, models.Packs{250, 500}.Details(), 0)
+			if tt.expectedError != nil {
+				var overshootErr *OvershootLimitError
+				if _, typed := tt.expectedError.(*OvershootLimitError); typed && !errors.As(err, &overshootErr) {
+					t.Errorf("CreateOrder() error = %v, want an OvershootLimitError", err)
+				} else if !typed && !errors.Is(err, tt.expectedError) {
+					t.Errorf("CreateOrder() error = %v, want %v", err, tt.expectedError)
+				}
+				if len(mockRepo.GetSavedOrders()) != 0 {
+					t.Errorf("CreateOrder() saved a rejected order")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateOrder() unexpected error = %v", err)
+			}
+			if order.Status != tt.expectedStatus {
+				t.Errorf("Status = %s, want %s", order.Status, tt.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestService_ReviewOrder(t *testing.T) {
+	mockRepo := NewMockOrderRepository()
+	service := NewService(1000000, mockRepo)
+	service.Overshoot = models.OvershootLimit{MaxItems: limitOf(10)}
+	service.OnOvershoot = models.OvershootApproval
+
+	place := func() *models.Order {
+		order, err := service.CreateOrder(models.OrderRequest{ItemCount: 251}, models.Packs{250, 500}.Details(), 0)
+		if err != nil {
+			t.Fatalf("CreateOrder() unexpected error = %v", err)
+		}
+		return order
+	}
+
+	approved, err := service.ApproveOrder(place().PublicID)
+	if err != nil || approved.Status != models.OrderStatusNew {
+		t.Errorf("ApproveOrder() = %v, %v, want a new order", approved, err)
+	}
+	rejected, err := service.RejectOrder(place().PublicID)
+	if err != nil || rejected.Status != models.OrderStatusRejected {
+		t.Errorf("RejectOrder() = %v, %v, want a rejected order", rejected, err)
+	}
+
+	// only orders waiting for approval get reviewed
+	var transitionErr *StatusTransitionError
+	if _, err := service.RejectOrder(approved.PublicID); !errors.As(err, &transitionErr) {
+		t.Errorf("RejectOrder() of a new order error = %v, want a StatusTransitionError", err)
+	}
+	if _, err := service.UpdateOrderStatus(place().PublicID, models.OrderStatusNew); !errors.As(err, &transitionErr) {
+		t.Errorf("UpdateOrderStatus() to new error = %v, want a StatusTransitionError", err)
+	}
+}
+
+func TestService_CreateLineOrder_OvershootLimit(t *testing.T) {
+	service := NewService(1000000, NewMockOrderRepository())
+	service.Overshoot = models.OvershootLimit{MaxPercent: limitOf(50)}
+
+	// 251 of product 1 ships 500
+	orderRequest := models.OrderRequest{Lines: []models.OrderLineRequest{{ProductID: 1, ItemCount: 251}, {ProductID: 2, ItemCount: 100}}}
+	var overshootErr *OvershootLimitError
+	if _, err := service.CreateLineOrder(orderRequest, testProducts()); !errors.As(err, &overshootErr) {
+		t.Fatalf("CreateLineOrder() error = %v, want an OvershootLimitError", err)
+	}
+
+	orderRequest.OnOvershoot = models.OvershootApproval
+	order, err := service.CreateLineOrder(orderRequest, testProducts())
+	if err != nil {
+		t.Fatalf("CreateLineOrder() unexpected error = %v", err)
+	}
+	if order.Status != models.OrderStatusNeedsApproval {
+		t.Errorf("Status = %s, want %s", order.Status, models.OrderStatusNeedsApproval)
+	}
+}
+
+// the packing within a limit has to match trying every combination of at most maxTotal items within stock
+func TestSolver_SolveWithin_MatchesBruteForce(t *testing.T) {
+	packs := []models.Pack{3, 5, 8}
+	strategies := []Strategy{
+		LeastItemsStrategy{},
+		FewestPacksStrategy{},
+		LowestCostStrategy{Costs: map[models.Pack]int{3: 1, 5: 4, 8: 2}},
+	}
+	stocks := []map[models.Pack]int{nil, {3: 2, 5: 3, 8: 1}, {8: 0}}
+
+	for _, strategy := range strategies {
+		solver, err := NewStrategySolver(packs, strategy)
+		if err != nil {
+			t.Fatalf("NewStrategySolver() error = %v", err)
+		}
+
+		for _, stock := range stocks {
+			limit := func(pack models.Pack) int {
+				if available, limited := stock[pack]; limited {
+					return available
+				}
+				return 10
+			}
+			for requestedCount := 1; requestedCount <= 30; requestedCount++ {
+				for maxTotal := requestedCount - 1; maxTotal <= requestedCount+8; maxTotal++ {
+					result, err := solver.SolveWithin(requestedCount, maxTotal, stock)
+					if err != nil {
+						t.Fatalf("%s SolveWithin(%d, %d, %v) error = %v", strategy.Objective(), requestedCount, maxTotal, stock, err)
+					}
+
+					bestCost, bestItems, bestPacks := math.MaxInt, 0, 0
+					for a := 0; a <= limit(3); a++ {
+						for b := 0; b <= limit(5); b++ {
+							for c := 0; c <= limit(8); c++ {
+								items := 3*a + 5*b + 8*c
+								if items < requestedCount || items > maxTotal {
+									continue
+								}
+								cost := a*solver.costs[0] + b*solver.costs[1] + c*solver.costs[2]
+								count := a + b + c
+								if cost < bestCost || (cost == bestCost && (items < bestItems || (items == bestItems && count < bestPacks))) {
+									bestCost, bestItems, bestPacks = cost, items, count
+								}
+							}
+						}
+					}
+
+					if bestCost == math.MaxInt {
+						if result != nil {
+							t.Errorf("%s SolveWithin(%d, %d, %v) = %d items, want none", strategy.Objective(), requestedCount, maxTotal, stock, result.TotalItems)
+						}
+						continue
+					}
+					if result == nil {
+						t.Errorf("%s SolveWithin(%d, %d, %v) = none, want %d items in %d packs", strategy.Objective(), requestedCount, maxTotal, stock, bestItems, bestPacks)
+						continue
+					}
+					if result.TotalItems != bestItems || result.TotalPacks != bestPacks || !fitsStock(result, stock) {
+						t.Errorf("%s SolveWithin(%d, %d, %v) = %v, want %d items in %d packs",
+							strategy.Objective(), requestedCount, maxTotal, stock, result.Packs, bestItems, bestPacks)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestService_CreateOrder_WithinOvershootLimit(t *testing.T) {
+	packs := []models.PackDetails{
+		{Size: 250, UnitCost: 90},
+		{Size: 500, UnitCost: 90},
+		{Size: 1000, UnitCost: 100},
+	}
+
+	tests := []struct {
+		name            string
+		objective       models.Objective
+		limit           models.OvershootLimit
+		expectedShipped int
+		expectedError   bool
+	}{
+		// 501 is best sent as a single 1000 pack for both, 750 is the best within 300 items over
+		{name: "fewest packs", objective: models.ObjectiveFewestPacks, limit: models.OvershootLimit{MaxItems: limitOf(300)}, expectedShipped: 750},
+		{name: "lowest cost", objective: models.ObjectiveLowestCost, limit: models.OvershootLimit{MaxPercent: limitOf(60)}, expectedShipped: 750},
+		{name: "nothing within the limit", objective: models.ObjectiveFewestPacks, limit: models.OvershootLimit{MaxItems: limitOf(0)}, expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := NewMockOrderRepository()
+			service := NewService(1000000, mockRepo)
+			service.Overshoot = tt.limit
+
+			order, err := service.CreateOrder(models.OrderRequest{ItemCount: 501, Objective: tt.objective}, packs, 0)
+			if tt.expectedError {
+				var overshootErr *OvershootLimitError
+				if !errors.As(err, &overshootErr) {
+					t.Errorf("CreateOrder() error = %v, want an OvershootLimitError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateOrder() unexpected error = %v", err)
+			}
+			if order.ShippedItemCount != tt.expectedShipped || order.Status != models.OrderStatusNew {
+				t.Errorf("CreateOrder() shipped %d as %s, want %d as %s", order.ShippedItemCount, order.Status, tt.expectedShipped, models.OrderStatusNew)
+			}
+			if !strings.Contains(strings.Join(order.Explanation.Steps, " "), "best packing within it") {
+				t.Errorf("Explanation = %v, want a step about the packing within the limit", order.Explanation.Steps)
+			}
+		})
+	}
+}
+
+// a packing within the limit that is too big to work out against stock leaves the order over the limit, it isn't an error
+func TestService_CreateOrder_WithinOvershootLimitFails(t *testing.T) {
+	// 2000 packs of 5000 and one of 2000 are in stock for 10000001 items. within 1000 items over, 1999 of 5000 and 3 of 2000
+	// would do, there aren't enough of 2000 and the stock table for the 10 million items is too big
+	packs := []models.PackDetails{
+		{Size: 2000, UnitCost: 1, Stock: limitOf(1)},
+		{Size: 5000, UnitCost: 1, Stock: limitOf(2000)},
+	}
+	service := NewService(100000000, NewMockOrderRepository())
+	service.Overshoot = models.OvershootLimit{MaxItems: limitOf(1000)}
+	service.OnOvershoot = models.OvershootApproval
+
+	order, err := service.CreateOrder(models.OrderRequest{ItemCount: 10000001, Objective: models.ObjectiveLowestCost}, packs, 0)
+	if err != nil {
+		t.Fatalf("CreateOrder() unexpected error = %v", err)
+	}
+	if order.ShippedItemCount != 10002000 || order.Status != models.OrderStatusNeedsApproval {
+		t.Errorf("CreateOrder() shipped %d as %s, want 10002000 as %s", order.ShippedItemCount, order.Status, models.OrderStatusNeedsApproval)
+	}
+}
+
+// a limit of 0 items allows no overshoot at all, it isn't the same as no limit
+func TestOvershootLimit_ZeroIsALimit(t *testing.T) {
+	var orderRequest models.OrderRequest
+	if err := json.Unmarshal([]byte(`{"itemCount": 251, "overshootLimit": {"maxItems": 0}}`), &orderRequest); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if limit := orderRequest.OvershootLimit; limit == nil || !limit.Enabled() || limit.MaxItems == nil || *limit.MaxItems != 0 {
+		t.Fatalf("OvershootLimit = %+v, want a limit of 0 items", orderRequest.OvershootLimit)
+	}
+
+	service := NewService(1000000, NewMockOrderRepository())
+	var overshootErr *OvershootLimitError
+	if _, err := service.CreateOrder(orderRequest, models.Packs{250, 500}.Details(), 0); !errors.As(err, &overshootErr) {
+		t.Errorf("CreateOrder(251) error = %v, want an OvershootLimitError", err)
+	}
+	orderRequest.ItemCount = 750
+	if order, err := service.CreateOrder(orderRequest, models.Packs{250, 500}.Details(), 0); err != nil || order.ShippedItemCount != 750 {
+		t.Errorf("CreateOrder(750) = %v, %v, want 750 items shipped", order, err)
+	}
+}
+
+func limitOf(value int) *int {
+	return &value
+}
@@ -2,8 +2,6 @@ package orders
 
 import (
 	"fmt"
-	"math"
-	"sort"
 
 	"github.com/irreal/order-packs/models"
 )
@@ -12,6 +10,8 @@ type PackingCalculation struct {
 	Packs      map[models.Pack]int
 	TotalItems int
 	TotalPacks int
+	// why these packs, only set by CalculatePackExplained
+	Explanation *models.Explanation
 }
 
 // Calculates the packs to be used given these rules:
@@ -21,9 +21,16 @@ type PackingCalculation struct {
 // 3. Within the constraints of Rules 1 & 2 above, send out as few packs as possible to fulfil each order.
 // note: rule 2 takes precedence over rule 3
 //
+// Memory and time depend only on the pack sizes, not on requestedCount, see Solver.
+//
 // Package Calculator only does basic validation to be able to do the calculation.
 // Things such as max allowed requestedCount are handled at business level by the service
 func CalculatePack(availablePacks []models.Pack, requestedCount int) (*PackingCalculation, error) {
+	return CalculatePackWithStrategy(availablePacks, requestedCount, LeastItemsStrategy{})
+}
+
+// Same as CalculatePack, but the best combination is picked by the given strategy instead of rules 2 and 3
+func CalculatePackWithStrategy(availablePacks []models.Pack, requestedCount int, strategy Strategy) (*PackingCalculation, error) {
 	if requestedCount <= 0 {
 		return nil, fmt.Errorf("requested count must be greater than 0")
 	}
@@ -31,87 +38,43 @@ func CalculatePack(availablePacks []models.Pack, requestedCount int) (*PackingCa
 		return nil, fmt.Errorf("no packs available to fulfill the order")
 	}
 
-	sort.Slice(availablePacks, func(i, j int) bool {
-		return availablePacks[i] < availablePacks[j]
-	})
-
-	maxPackSize := int(availablePacks[len(availablePacks)-1])
-
-	//Let's solve it dynamically for each count up from 0 to requested count
-	//the overshoot can be at most the size of the max pack
-	maxSize := requestedCount + maxPackSize
-	minItems := make([]int, maxSize+1)
-	minPacks := make([]int, maxSize+1)
-	lastPackUsed := make(map[int]models.Pack)
-
-	for i := 1; i <= maxSize; i++ {
-		minItems[i] = math.MaxInt32
-		minPacks[i] = math.MaxInt32
+	solver, err := NewStrategySolver(availablePacks, strategy)
+	if err != nil {
+		return nil, err
 	}
 
-	// base case, for 0 items, we need 0 packs
-	minItems[0] = 0
-	minPacks[0] = 0
-
-	// find the solution for all order counts up to target (+ max pack)
-	for i := 1; i <= maxSize; i++ {
-
-		// consider all packs
-		for _, packSize := range availablePacks {
-
-			// the current pack is a candidate to solve the target. it reaches or overshoots the target, and we already solved the previous sub-target
-			if i >= int(packSize) && minItems[i-int(packSize)] != math.MaxInt32 {
-
-				// this candidate pack gives these results
-				newTotalItems := minItems[i-int(packSize)] + int(packSize)
-				newTotalPacks := minPacks[i-int(packSize)] + 1
+	return solver.Solve(requestedCount)
+}
 
-				// rule 2, minimize the total number of items
-				if newTotalItems < minItems[i] {
-					minItems[i] = newTotalItems
-					minPacks[i] = newTotalPacks
-					lastPackUsed[i] = packSize
-				} else if newTotalItems == minItems[i] {
-					// we found a solution as good as an existing for rule 2, check if it is better in terms of rule 3, the count of packs
-					if newTotalPacks < minPacks[i] {
-						minPacks[i] = newTotalPacks
-						lastPackUsed[i] = packSize
-					}
-				}
-			}
-		}
+// Same as CalculatePackWithStrategy, with an explanation of why the packs were picked, see Solver.Explain
+func CalculatePackExplained(availablePacks []models.Pack, requestedCount int, strategy Strategy) (*PackingCalculation, error) {
+	if requestedCount <= 0 {
+		return nil, fmt.Errorf("requested count must be greater than 0")
 	}
 
-	// first solution that equals or overshoots the requestedCount
-	optimalCount := -1
-	for i := requestedCount; i <= maxSize; i++ {
-		if minItems[i] != math.MaxInt32 {
-			optimalCount = i
-			break
-		}
+	solver, err := NewStrategySolver(availablePacks, strategy)
+	if err != nil {
+		return nil, err
 	}
 
-	// as long as we have some packs, in theory, we always have a solution. this shouldn't happen with the validation checks we have at the top
-	if optimalCount == -1 {
-		return nil, fmt.Errorf("no valid combination found")
+	calculation, err := solver.Solve(requestedCount)
+	if err != nil {
+		return nil, err
 	}
 
-	// we now know the optimal solution. reconstruct the exact packs and counts
-	finalPacks := make(map[models.Pack]int)
-
-	// start with target count and reconstruct the packs, relying on the memory of packs used for computed sub-amounts
-	remainingCount := optimalCount
-	for remainingCount > 0 {
-		pack := lastPackUsed[remainingCount]
-		finalPacks[pack] = finalPacks[pack] + 1
+	calculation.Explanation, err = solver.Explain(requestedCount, calculation, nil)
+	if err != nil {
+		return nil, err
+	}
+	return calculation, nil
+}
 
-		remainingCount -= int(pack)
+// The count best packings for an order according to the strategy, best first, see Solver.Alternatives
+func CalculatePackAlternatives(availablePacks []models.Pack, requestedCount int, count int, strategy Strategy) ([]Alternative, error) {
+	solver, err := NewStrategySolver(availablePacks, strategy)
+	if err != nil {
+		return nil, err
 	}
 
-	// yay, create the response struct
-	return &PackingCalculation{
-		Packs:      finalPacks,
-		TotalItems: minItems[optimalCount],
-		TotalPacks: minPacks[optimalCount],
-	}, nil
+	return solver.Alternatives(requestedCount, count)
 }
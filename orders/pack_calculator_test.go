@@ -156,3 +156,66 @@ func TestCalculatePack_ErrorCases(t *testing.T) {
 		})
 	}
 }
+
+func TestCalculatePack_LargeOrders(t *testing.T) {
+	tests := []struct {
+		name              string
+		availablePacks    []models.Pack
+		requestedCount    int
+		expectedPacks     map[models.Pack]int
+		expectedItemCount int
+		expectedPackCount int
+	}{
+		{
+			name:              "500 million items, exact match with largest pack",
+			availablePacks:    []models.Pack{250, 500, 1000, 2000, 5000},
+			requestedCount:    500000000,
+			expectedPacks:     map[models.Pack]int{5000: 100000},
+			expectedItemCount: 500000000,
+			expectedPackCount: 100000,
+		},
+		{
+			name:              "hundreds of millions with overshoot",
+			availablePacks:    []models.Pack{250, 500, 1000, 2000, 5000},
+			requestedCount:    300000001,
+			expectedPacks:     map[models.Pack]int{5000: 60000, 250: 1},
+			expectedItemCount: 300000250,
+			expectedPackCount: 60001,
+		},
+		{
+			name:              "edge case packs with an exact match",
+			availablePacks:    []models.Pack{23, 31, 53},
+			requestedCount:    500000,
+			expectedPacks:     map[models.Pack]int{23: 2, 31: 7, 53: 9429},
+			expectedItemCount: 500000,
+			expectedPackCount: 9438,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := CalculatePack(tt.availablePacks, tt.requestedCount)
+			if err != nil {
+				t.Fatalf("CalculatePack() error = %v", err)
+			}
+
+			if result.TotalItems != tt.expectedItemCount {
+				t.Errorf("TotalItems = %d, want %d", result.TotalItems, tt.expectedItemCount)
+			}
+
+			if result.TotalPacks != tt.expectedPackCount {
+				t.Errorf("TotalPacks = %d, want %d", result.TotalPacks, tt.expectedPackCount)
+			}
+
+			if len(result.Packs) != len(tt.expectedPacks) {
+				t.Errorf("count of pack types used = %d, want %d", len(result.Packs), len(tt.expectedPacks))
+			}
+
+			for pack, count := range tt.expectedPacks {
+				if result.Packs[pack] != count {
+					t.Errorf("pack %d count = %d, want %d", pack, result.Packs[pack], count)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package orders
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/irreal/order-packs/models"
+)
+
+// where new orders get the case and pallet types from, see PlanPackaging
+type PackagingSource interface {
+	GetPackaging() (*models.PackagingHierarchy, error)
+}
+
+// Plans the levels above packs for the packs of an order. Every level follows the calculator's rules with the level below
+// as its items: units only ship whole, so cases and pallets only ship full, and then the fewest units to handle.
+// A level is calculated with CalculatePack, where a loose unit is the pack of size 1 that always fits.
+// Packs go in cases first, then cases on pallets. nil when there are no case types
+func PlanPackaging(packs map[models.Pack]int, hierarchy *models.PackagingHierarchy) (*models.PackagingPlan, error) {
+	if hierarchy == nil || len(hierarchy.Cases) == 0 {
+		return nil, nil
+	}
+
+	plan := &models.PackagingPlan{
+		Pallets:    []models.PlannedPallet{},
+		Cases:      []models.PlannedCase{},
+		LoosePacks: make(map[models.Pack]int),
+	}
+
+	cases := make(map[string]int)
+	for _, size := range slices.Sorted(maps.Keys(packs)) {
+		// the first case type of every capacity, a second one of the same capacity wouldn't change the plan
+		caseTypes := make(map[int]models.CaseType)
+		for _, caseType := range hierarchy.Cases {
+			if _, found := caseTypes[caseType.Packs]; caseType.PackSize == size && !found {
+				caseTypes[caseType.Packs] = caseType
+			}
+		}
+
+		units, loose, err := planLevel(packs[size], caseTypes)
+		if err != nil {
+			return nil, err
+		}
+		for capacity, count := range units {
+			cases[caseTypes[capacity].SKU] += count
+		}
+		if loose > 0 {
+			plan.LoosePacks[size] = loose
+			plan.TotalUnits += loose
+		}
+	}
+
+	for _, caseType := range hierarchy.Cases {
+		if cases[caseType.SKU] == 0 {
+			continue
+		}
+		palletTypes := make(map[int]models.PalletType)
+		for _, pallet := range hierarchy.Pallets {
+			if _, found := palletTypes[pallet.Cases]; pallet.CaseSKU == caseType.SKU && !found {
+				palletTypes[pallet.Cases] = pallet
+			}
+		}
+
+		units, loose, err := planLevel(cases[caseType.SKU], palletTypes)
+		if err != nil {
+			return nil, err
+		}
+		// largest pallets first
+		for _, capacity := range slices.Backward(slices.Sorted(maps.Keys(units))) {
+			plan.Pallets = append(plan.Pallets, models.PlannedPallet{PalletType: palletTypes[capacity], Count: units[capacity]})
+			plan.TotalUnits += units[capacity]
+		}
+		if loose > 0 {
+			plan.Cases = append(plan.Cases, models.PlannedCase{CaseType: caseType, Count: loose})
+			plan.TotalUnits += loose
+		}
+	}
+
+	return plan, nil
+}
+
+// how many units of every capacity count items go in, by capacity, and how many items are left loose
+func planLevel[T any](count int, unitTypes map[int]T) (map[int]int, int, error) {
+	if len(unitTypes) == 0 {
+		return nil, count, nil
+	}
+
+	sizes := models.Packs{1}
+	for capacity := range unitTypes {
+		sizes = append(sizes, models.Pack(capacity))
+	}
+	calculation, err := CalculatePack(sizes, count)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", OrderCalculationError, err)
+	}
+
+	units := make(map[int]int)
+	for size, n := range calculation.Packs {
+		if size != 1 {
+			units[int(size)] = n
+		}
+	}
+	return units, calculation.Packs[1], nil
+}
@@ -0,0 +1,139 @@
+package orders
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/irreal/order-packs/models"
+)
+
+type staticPackaging struct {
+	hierarchy *models.PackagingHierarchy
+}
+
+func (s staticPackaging) GetPackaging() (*models.PackagingHierarchy, error) {
+	return s.hierarchy, nil
+}
+
+func testPackaging() *models.PackagingHierarchy {
+	return &models.PackagingHierarchy{
+		Cases: []models.CaseType{
+			{SKU: "CASE-250-10", PackSize: 250, Packs: 10},
+			{SKU: "CASE-250-4", PackSize: 250, Packs: 4},
+			{SKU: "CASE-500-6", PackSize: 500, Packs: 6},
+			{SKU: "CASE-500-4", PackSize: 500, Packs: 4},
+		},
+		Pallets: []models.PalletType{
+			{SKU: "PAL-250", CaseSKU: "CASE-250-10", Cases: 2},
+		},
+	}
+}
+
+func TestPlanPackaging(t *testing.T) {
+	hierarchy := testPackaging()
+	caseType := func(sku string) models.CaseType {
+		for _, c := range hierarchy.Cases {
+			if c.SKU == sku {
+				return c
+			}
+		}
+		t.Fatalf("no case %s", sku)
+		return models.CaseType{}
+	}
+
+	tests := []struct {
+		name     string
+		packs    map[models.Pack]int
+		expected *models.PackagingPlan
+	}{
+		{
+			name:  "packs in cases, cases on pallets",
+			packs: map[models.Pack]int{250: 24, 500: 7, 1000: 2},
+			expected: &models.PackagingPlan{
+				Pallets: []models.PlannedPallet{{PalletType: hierarchy.Pallets[0], Count: 1}},
+				Cases: []models.PlannedCase{
+					{CaseType: caseType("CASE-250-4"), Count: 1},
+					{CaseType: caseType("CASE-500-6"), Count: 1},
+				},
+				// no case holds 1000s
+				LoosePacks: map[models.Pack]int{500: 1, 1000: 2},
+				TotalUnits: 6,
+			},
+		},
+		{
+			// the largest case first would leave two loose packs
+			name:  "fewest units, not the largest case first",
+			packs: map[models.Pack]int{500: 8},
+			expected: &models.PackagingPlan{
+				Pallets:    []models.PlannedPallet{},
+				Cases:      []models.PlannedCase{{CaseType: caseType("CASE-500-4"), Count: 2}},
+				LoosePacks: map[models.Pack]int{},
+				TotalUnits: 2,
+			},
+		},
+		{
+			name:  "too few packs for a case",
+			packs: map[models.Pack]int{250: 3},
+			expected: &models.PackagingPlan{
+				Pallets:    []models.PlannedPallet{},
+				Cases:      []models.PlannedCase{},
+				LoosePacks: map[models.Pack]int{250: 3},
+				TotalUnits: 3,
+			},
+		},
+		{
+			name:  "large orders",
+			packs: map[models.Pack]int{250: 1_000_003},
+			expected: &models.PackagingPlan{
+				Pallets:    []models.PlannedPallet{{PalletType: hierarchy.Pallets[0], Count: 50_000}},
+				Cases:      []models.PlannedCase{},
+				LoosePacks: map[models.Pack]int{250: 3},
+				TotalUnits: 50_003,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := PlanPackaging(tt.packs, hierarchy)
+			if err != nil {
+				t.Fatalf("PlanPackaging() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(plan, tt.expected) {
+				t.Errorf("PlanPackaging() = %+v, want %+v", plan, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPlanPackaging_NoCases(t *testing.T) {
+	for _, hierarchy := range []*models.PackagingHierarchy{nil, {}} {
+		plan, err := PlanPackaging(map[models.Pack]int{250: 3}, hierarchy)
+		if err != nil || plan != nil {
+			t.Errorf("PlanPackaging(%v) = %+v, %v, want no plan", hierarchy, plan, err)
+		}
+	}
+}
+
+func TestService_CreateOrder_Packaging(t *testing.T) {
+	mockRepo := NewMockOrderRepository()
+	service := NewService(1000000, mockRepo)
+
+	order, err := service.CreateOrder(models.OrderRequest{ItemCount: 6000}, models.Packs{250}.Details(), 0)
+	if err != nil {
+		t.Fatalf("CreateOrder() unexpected error = %v", err)
+	}
+	if order.Packaging != nil {
+		t.Errorf("Packaging = %+v, want none without a packaging source", order.Packaging)
+	}
+
+	service.Packaging = staticPackaging{testPackaging()}
+	order, err = service.CreateOrder(models.OrderRequest{ItemCount: 6000}, models.Packs{250}.Details(), 0)
+	if err != nil {
+		t.Fatalf("CreateOrder() unexpected error = %v", err)
+	}
+	// 24 packs, two cases of 10 on a pallet and a case of 4
+	if order.Packaging == nil || order.Packaging.TotalUnits != 2 || len(order.Packaging.Pallets) != 1 || len(order.Packaging.Cases) != 1 {
+		t.Errorf("Packaging = %+v, want a pallet and a case", order.Packaging)
+	}
+}
@@ -0,0 +1,241 @@
+package orders
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/irreal/order-packs/models"
+)
+
+const (
+	MaxRecommendedSizes     = 8
+	DefaultRecommendedSizes = 5
+	// larger packs take too long to try many sets with. sets of smaller sizes can still need a solver table
+	// over MaxSolverTableSize, e.g. two big sizes without a common factor, those sets are skipped
+	MaxRecommendedPackSize = 100_000
+	// requested item counts tried as pack sizes, spread over the demand
+	recommendationCandidates = 40
+	// rounds of swapping one picked size for another after the greedy picks
+	recommendationSwapRounds = 5
+)
+
+// Recommends a pack set for the item counts past orders asked for. Sizes are picked greedily, the one that helps most first,
+// then swapped for others while that helps. Every set is scored by packing all past orders with the calculator orders use
+// (see CalculatePack), so the result only depends on the order history and the request.
+// current are the live packs, scored the same way to compare
+func (s *Service) RecommendPacks(request models.PackRecommendationRequest, current []models.PackDetails) (*models.PackRecommendation, error) {
+	demand, err := s.repo.GetOrderDemand()
+	if err != nil {
+		return nil, err
+	}
+	if len(demand) == 0 {
+		return nil, fmt.Errorf("%w: packs are recommended from past orders, there are none yet", NotEnoughOrdersError)
+	}
+
+	request, err = recommendationDefaults(request, demand)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluator := &packSetEvaluator{demand: demand, request: request, scores: make(map[string]models.PackSetScore), tooLarge: make(map[string]bool)}
+	candidates := recommendationCandidatesFor(demand, request.MinSize, request.MaxSize)
+
+	// greedy, stop once another size doesn't make it better
+	var chosen []models.Pack
+	var best models.PackSetScore
+	for len(chosen) < request.MaxSizes {
+		var pick models.Pack
+		var pickScore models.PackSetScore
+		for _, candidate := range candidates {
+			if slices.Contains(chosen, candidate) {
+				continue
+			}
+			score, err := evaluator.score(append(slices.Clone(chosen), candidate))
+			if errors.Is(err, PackSetTooLargeError) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if pick == 0 || evaluator.better(score, pickScore) {
+				pick, pickScore = candidate, score
+			}
+		}
+		if pick == 0 || (len(chosen) > 0 && !evaluator.better(pickScore, best)) {
+			break
+		}
+		chosen = append(chosen, pick)
+		best = pickScore
+	}
+
+	// the first picks were made without knowing the later ones, see if swapping any of them helps
+	for range recommendationSwapRounds {
+		improved := false
+		for i := range chosen {
+			for _, candidate := range candidates {
+				if slices.Contains(chosen, candidate) {
+					continue
+				}
+				trial := slices.Clone(chosen)
+				trial[i] = candidate
+				score, err := evaluator.score(trial)
+				if errors.Is(err, PackSetTooLargeError) {
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+				if evaluator.better(score, best) {
+					chosen, best, improved = trial, score, true
+				}
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+
+	slices.Sort(chosen)
+	recommendation := &models.PackRecommendation{Packs: chosen, Score: best}
+	for _, d := range demand {
+		recommendation.Orders += d.Orders
+	}
+	if live := models.PackSizes(models.EnabledPacks(current)); len(live) > 0 {
+		score, err := evaluator.score(live)
+		if err != nil && !errors.Is(err, PackSetTooLargeError) {
+			return nil, err
+		}
+		if err == nil {
+			recommendation.Current = &score
+		}
+	}
+	return recommendation, nil
+}
+
+func recommendationDefaults(request models.PackRecommendationRequest, demand []models.OrderDemand) (models.PackRecommendationRequest, error) {
+	if request.MaxSizes == 0 {
+		request.MaxSizes = DefaultRecommendedSizes
+	}
+	if request.MinSize == 0 {
+		request.MinSize = 1
+	}
+	if request.MaxSize == 0 {
+		request.MaxSize = max(request.MinSize, min(demand[len(demand)-1].ItemCount, MaxRecommendedPackSize))
+	}
+	if request.Goal == "" {
+		request.Goal = models.RecommendationGoalOvershoot
+	}
+
+	if request.MaxSizes < 1 || request.MaxSizes > MaxRecommendedSizes {
+		return request, fmt.Errorf("%w: max sizes must be between 1 and %d", InvalidRecommendationError, MaxRecommendedSizes)
+	}
+	if request.MinSize < 1 || request.MaxSize < request.MinSize || request.MaxSize > MaxRecommendedPackSize {
+		return request, fmt.Errorf("%w: sizes must be between 1 and %d, with the minimum below the maximum", InvalidRecommendationError, MaxRecommendedPackSize)
+	}
+	switch request.Goal {
+	case models.RecommendationGoalOvershoot:
+	case models.RecommendationGoalCost:
+		if request.ItemCost < 0 || request.PackCost < 0 || request.ItemCost+request.PackCost == 0 {
+			return request, fmt.Errorf("%w: the cost goal needs an item cost or a pack cost, and neither can be negative", InvalidRecommendationError)
+		}
+	default:
+		return request, fmt.Errorf("%w: unknown goal %q", InvalidRecommendationError, request.Goal)
+	}
+	return request, nil
+}
+
+// Requested item counts within the bounds, a pack of exactly that size ships those orders without overshoot.
+// When there are too many, they are picked evenly by orders, so common counts are more likely to be tried.
+// The bounds themselves are always tried
+func recommendationCandidatesFor(demand []models.OrderDemand, minSize, maxSize int) []models.Pack {
+	var inBounds []models.OrderDemand
+	total := 0
+	for _, d := range demand {
+		if d.ItemCount >= minSize && d.ItemCount <= maxSize {
+			inBounds = append(inBounds, d)
+			total += d.Orders
+		}
+	}
+
+	candidates := []models.Pack{models.Pack(minSize), models.Pack(maxSize)}
+	if len(inBounds) <= recommendationCandidates {
+		for _, d := range inBounds {
+			candidates = append(candidates, models.Pack(d.ItemCount))
+		}
+	} else {
+		// the count at the middle of every equal share of orders
+		next, seen := 0, 0
+		for i := range recommendationCandidates {
+			target := (2*i + 1) * total / (2 * recommendationCandidates)
+			for seen+inBounds[next].Orders <= target {
+				seen += inBounds[next].Orders
+				next++
+			}
+			candidates = append(candidates, models.Pack(inBounds[next].ItemCount))
+		}
+	}
+
+	slices.Sort(candidates)
+	return slices.Compact(candidates)
+}
+
+// scores pack sets on the order history, every set is only packed once
+type packSetEvaluator struct {
+	demand  []models.OrderDemand
+	request models.PackRecommendationRequest
+	scores  map[string]models.PackSetScore
+	// sets the solver refused or would solve order by order, see MaxSolverTableSize
+	tooLarge map[string]bool
+}
+
+// fails with PackSetTooLargeError when the set needs too big a solver, those can't be recommended
+func (e *packSetEvaluator) score(packs []models.Pack) (models.PackSetScore, error) {
+	sorted := slices.Clone(packs)
+	slices.Sort(sorted)
+	key := fmt.Sprint(sorted)
+	if score, found := e.scores[key]; found {
+		return score, nil
+	}
+	if e.tooLarge[key] {
+		return models.PackSetScore{}, PackSetTooLargeError
+	}
+
+	solver, err := NewSolver(sorted)
+	if err == nil && solver.PerOrder() {
+		// every demanded count would need a dp of its own
+		err = fmt.Errorf("%w: packs %v need a dp per order", PackSetTooLargeError, sorted)
+	}
+	if errors.Is(err, PackSetTooLargeError) {
+		e.tooLarge[key] = true
+		return models.PackSetScore{}, err
+	}
+	if err != nil {
+		return models.PackSetScore{}, fmt.Errorf("%w: %v", OrderCalculationError, err)
+	}
+	var score models.PackSetScore
+	for _, d := range e.demand {
+		calculation, err := solver.Solve(d.ItemCount)
+		if err != nil {
+			return models.PackSetScore{}, fmt.Errorf("%w: %v", OrderCalculationError, err)
+		}
+		score.Overshoot += (calculation.TotalItems - d.ItemCount) * d.Orders
+		score.Packs += calculation.TotalPacks * d.Orders
+	}
+	if e.request.Goal == models.RecommendationGoalCost {
+		score.Cost = score.Overshoot*e.request.ItemCost + score.Packs*e.request.PackCost
+	}
+
+	e.scores[key] = score
+	return score, nil
+}
+
+// overshoot then packs, the cost goal compares costs first
+func (e *packSetEvaluator) better(a, b models.PackSetScore) bool {
+	return cmp.Or(
+		cmp.Compare(a.Cost, b.Cost),
+		cmp.Compare(a.Overshoot, b.Overshoot),
+		cmp.Compare(a.Packs, b.Packs),
+	) < 0
+}
@@ -0,0 +1,169 @@
+package orders
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/irreal/order-packs/models"
+)
+
+func TestService_RecommendPacks(t *testing.T) {
+	mockRepo := NewMockOrderRepository()
+	service := NewService(1000000, mockRepo)
+	for _, count := range []int{250, 250, 250, 500, 500, 1000} {
+		if _, err := service.CreateOrder(models.OrderRequest{ItemCount: count}, models.Packs{250, 500, 1000}.Details(), 0); err != nil {
+			t.Fatalf("CreateOrder(%d) unexpected error = %v", count, err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		request       models.PackRecommendationRequest
+		expectedPacks models.Packs
+		expectedScore models.PackSetScore
+	}{
+		{
+			name:          "least overshoot",
+			request:       models.PackRecommendationRequest{MaxSizes: 2, MinSize: 100},
+			expectedPacks: models.Packs{250, 500},
+			expectedScore: models.PackSetScore{Overshoot: 0, Packs: 7},
+		},
+		{
+			name:          "a single size",
+			request:       models.PackRecommendationRequest{MaxSizes: 1, MinSize: 100},
+			expectedPacks: models.Packs{250},
+			expectedScore: models.PackSetScore{Overshoot: 0, Packs: 11},
+		},
+		{
+			name:          "sizes stop when they don't help",
+			request:       models.PackRecommendationRequest{MaxSizes: 8, MinSize: 100},
+			expectedPacks: models.Packs{250, 500, 1000},
+			expectedScore: models.PackSetScore{Overshoot: 0, Packs: 6},
+		},
+		{
+			name:          "expensive packs are worth some overshoot",
+			request:       models.PackRecommendationRequest{MaxSizes: 1, MinSize: 100, Goal: models.RecommendationGoalCost, ItemCost: 1, PackCost: 1000},
+			expectedPacks: models.Packs{500},
+			expectedScore: models.PackSetScore{Overshoot: 750, Packs: 7, Cost: 7750},
+		},
+		{
+			// only the bounds and 500 can be tried, 300 wastes less than 500
+			name:          "within bounds",
+			request:       models.PackRecommendationRequest{MaxSizes: 1, MinSize: 300, MaxSize: 600},
+			expectedPacks: models.Packs{300},
+			expectedScore: models.PackSetScore{Overshoot: 550, Packs: 11},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recommendation, err := service.RecommendPacks(tt.request, models.Packs{250, 500, 1000}.Details())
+			if err != nil {
+				t.Fatalf("RecommendPacks() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(recommendation.Packs, tt.expectedPacks) {
+				t.Errorf("Packs = %v, want %v", recommendation.Packs, tt.expectedPacks)
+			}
+			if recommendation.Score != tt.expectedScore {
+				t.Errorf("Score = %+v, want %+v", recommendation.Score, tt.expectedScore)
+			}
+			if recommendation.Orders != 6 {
+				t.Errorf("Orders = %d, want 6", recommendation.Orders)
+			}
+			if recommendation.Current == nil || recommendation.Current.Overshoot != 0 || recommendation.Current.Packs != 6 {
+				t.Errorf("Current = %+v, want no overshoot in 6 packs", recommendation.Current)
+			}
+
+			// the same history gives the same packs
+			again, _ := service.RecommendPacks(tt.request, nil)
+			if !reflect.DeepEqual(again.Packs, recommendation.Packs) || again.Current != nil {
+				t.Errorf("RecommendPacks() again = %v, want %v without current packs", again.Packs, recommendation.Packs)
+			}
+		})
+	}
+}
+
+// both sizes together would need a solver table close to their product, so that set is skipped rather than tried
+func TestService_RecommendPacks_SkipsTooLargeSets(t *testing.T) {
+	mockRepo := NewMockOrderRepository()
+	service := NewService(1000000, mockRepo)
+	for _, count := range []int{19997, 20000} {
+		if _, err := service.CreateOrder(models.OrderRequest{ItemCount: count}, models.Packs{1}.Details(), 0); err != nil {
+			t.Fatalf("CreateOrder(%d) unexpected error = %v", count, err)
+		}
+	}
+
+	recommendation, err := service.RecommendPacks(models.PackRecommendationRequest{MaxSizes: 2, MinSize: 19997, MaxSize: 20000}, nil)
+	if err != nil {
+		t.Fatalf("RecommendPacks() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(recommendation.Packs, models.Packs{20000}) {
+		t.Errorf("Packs = %v, want [20000]", recommendation.Packs)
+	}
+	if recommendation.Score != (models.PackSetScore{Overshoot: 3, Packs: 2}) {
+		t.Errorf("Score = %+v, want 3 items overshoot in 2 packs", recommendation.Score)
+	}
+}
+
+func TestService_RecommendPacks_InvalidRequest(t *testing.T) {
+	service := NewService(1000, NewMockOrderRepository())
+	if _, err := service.RecommendPacks(models.PackRecommendationRequest{}, nil); !errors.Is(err, NotEnoughOrdersError) {
+		t.Errorf("RecommendPacks() without orders error = %v, want %v", err, NotEnoughOrdersError)
+	}
+
+	if _, err := service.CreateOrder(models.OrderRequest{ItemCount: 300}, models.Packs{250}.Details(), 0); err != nil {
+		t.Fatalf("CreateOrder() unexpected error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		request models.PackRecommendationRequest
+	}{
+		{name: "too many sizes", request: models.PackRecommendationRequest{MaxSizes: MaxRecommendedSizes + 1}},
+		{name: "negative sizes", request: models.PackRecommendationRequest{MaxSizes: -1}},
+		{name: "minimum above maximum", request: models.PackRecommendationRequest{MinSize: 500, MaxSize: 100}},
+		{name: "maximum too large", request: models.PackRecommendationRequest{MaxSize: MaxRecommendedPackSize + 1}},
+		{name: "unknown goal", request: models.PackRecommendationRequest{Goal: "fun"}},
+		{name: "cost goal without costs", request: models.PackRecommendationRequest{Goal: models.RecommendationGoalCost}},
+		{name: "negative cost", request: models.PackRecommendationRequest{Goal: models.RecommendationGoalCost, ItemCost: 5, PackCost: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.RecommendPacks(tt.request, nil); !errors.Is(err, InvalidRecommendationError) {
+				t.Errorf("RecommendPacks() error = %v, want %v", err, InvalidRecommendationError)
+			}
+		})
+	}
+}
+
+func TestRecommendationCandidates(t *testing.T) {
+	// few counts are all tried, with the bounds
+	demand := []models.OrderDemand{{ItemCount: 50, Orders: 1}, {ItemCount: 120, Orders: 4}, {ItemCount: 800, Orders: 2}}
+	candidates := recommendationCandidatesFor(demand, 100, 1000)
+	if !reflect.DeepEqual(candidates, []models.Pack{100, 120, 800, 1000}) {
+		t.Errorf("recommendationCandidatesFor() = %v, want [100 120 800 1000]", candidates)
+	}
+
+	// many counts are picked by orders, the popular one shows up even though it's a single count
+	demand = nil
+	for count := 1; count <= 1000; count++ {
+		orders := 1
+		if count == 777 {
+			orders = 1000
+		}
+		demand = append(demand, models.OrderDemand{ItemCount: count, Orders: orders})
+	}
+	candidates = recommendationCandidatesFor(demand, 1, 1000)
+	if len(candidates) > recommendationCandidates+2 {
+		t.Errorf("recommendationCandidatesFor() gave %d candidates, want at most %d", len(candidates), recommendationCandidates+2)
+	}
+	found := false
+	for _, candidate := range candidates {
+		found = found || candidate == 777
+	}
+	if !found {
+		t.Errorf("recommendationCandidatesFor() = %v, want 777 in it", candidates)
+	}
+}
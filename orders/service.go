@@ -1,7 +1,11 @@
 package orders
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/irreal/order-packs/models"
@@ -9,12 +13,43 @@ import (
 
 type Service struct {
 	MaxOrderItemCount int
-	repo              OrderRepository
+	// what fits in a carton or pallet, new orders and quotes get their packs split into containers when it has limits
+	Containers models.ContainerConfig
+	// case and pallet types new orders are planned with, nil to only plan packs
+	Packaging PackagingSource
+	// how far over its request every order may ship, requests can only tighten it.
+	// orders over it are handled by OnOvershoot, or ship less when they allow a backorder
+	Overshoot models.OvershootLimit
+	// for requests that don't pick an action, rejecting them when empty
+	OnOvershoot models.OvershootAction
+	repo        OrderRepository
+
+	// solvers for the pack set currently in use, one per objective, rebuilt lazily after InvalidateSolver
+	solverMu sync.RWMutex
+	solvers  map[models.Objective]*Solver
+	// solvers for the products' pack sets, by objective and pack set so products with the same packs share one.
+	// dropped by InvalidateLineSolvers
+	lineSolvers map[string]*Solver
 }
 
 type OrderRepository interface {
+	// persists the order, and its backorder if it has one, and takes its packs out of stock in one go,
+	// fails with InsufficientStockError if a tracked pack size ran out in the meantime.
+	// orders with lines are packed in product packs, which don't track stock
 	SaveOrder(order *models.Order) error
 	GetLast10Orders() ([]*models.Order, error)
+	// both fail with OrderNotFoundError if there is no such order
+	GetOrder(id int64) (*models.Order, error)
+	GetOrderByPublicID(publicID string) (*models.Order, error)
+	// moves the order from order.Status to status and updates order on success.
+	// fails with OrderStatusChangedError if the saved order isn't in order.Status anymore.
+	// cancelled and rejected orders put their packs back in stock
+	UpdateOrderStatus(order *models.Order, status models.OrderStatus) error
+	// orders matching the query in its sort order, at most query.Limit of them, starting after query.After
+	ListOrders(query models.OrderQuery) ([]*models.Order, error)
+	// how many orders asked for each item count, by item count. orders with lines aren't counted, their products have their own packs,
+	// and neither are backorders, the order they came from asked for all of it
+	GetOrderDemand() ([]models.OrderDemand, error)
 }
 
 func NewService(maxOrderItemCount int, repo OrderRepository) *Service {
@@ -24,25 +59,68 @@ func NewService(maxOrderItemCount int, repo OrderRepository) *Service {
 	}
 }
 
-func (s *Service) CreateOrder(orderRequest models.OrderRequest, availablePacks []models.Pack) (*models.Order, error) {
-	if orderRequest.ItemCount <= 0 {
-		return nil, fmt.Errorf("%w: Item count has to be greater than 0", InvalidOrderItemCountError)
-	}
-	if orderRequest.ItemCount > s.MaxOrderItemCount {
-		return nil, fmt.Errorf("%w: Item count has to be less than or equal to %d", InvalidOrderItemCountError, s.MaxOrderItemCount)
+// packSetVersion is the version availablePacks came from, recorded with the order, 0 when they aren't versioned
+func (s *Service) CreateOrder(orderRequest models.OrderRequest, availablePacks []models.PackDetails, packSetVersion int64) (*models.Order, error) {
+	quote, solver, err := s.quote(orderRequest, availablePacks)
+	if err != nil {
+		return nil, err
 	}
 
-	packsCalculation, err := CalculatePack(availablePacks, orderRequest.ItemCount)
+	// kept with the order so support can show why it shipped like it did
+	explanation, err := solver.Explain(orderRequest.ItemCount, &PackingCalculation{
+		Packs:      quote.Packs,
+		TotalItems: quote.TotalItems,
+		TotalPacks: quote.TotalPacks,
+	}, models.StockLevels(availablePacks))
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", OrderCalculationError, err)
 	}
+	if quote.WithinLimit {
+		explanation.Steps = append(explanation.Steps, fmt.Sprintf("That goes over the overshoot limit of %s, so the best packing within it was used instead: %s, %d items in %d packs.",
+			describeOvershootLimit(s.overshootLimit(orderRequest)), describePacks(quote.Packs), quote.TotalItems, quote.TotalPacks))
+	}
+	if quote.Backordered > 0 {
+		explanation.Steps = append(explanation.Steps, backorderStep(s.overshootLimit(orderRequest), quote.TotalItems, quote.Backordered))
+	}
+	status := models.OrderStatusNew
+	if quote.NeedsApproval {
+		status = models.OrderStatusNeedsApproval
+		explanation.Steps = append(explanation.Steps, fmt.Sprintf("That goes over the overshoot limit of %s, so the order waits for an admin to approve it.",
+			describeOvershootLimit(s.overshootLimit(orderRequest))))
+	}
 
+	packaging, err := s.planPackaging(quote.Packs)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
 	order := &models.Order{
+		PublicID:           models.NewPublicOrderID(),
 		RequestedItemCount: orderRequest.ItemCount,
-		ShippedItemCount:   packsCalculation.TotalItems,
-		Packs:              packsCalculation.Packs,
-		Status:             models.OrderStatusNew,
-		CreatedAt:          time.Now(),
+		ShippedItemCount:   quote.TotalItems,
+		Packs:              quote.Packs,
+		PackagingCost:      quote.PackagingCost,
+		ShipmentWeight:     quote.ShipmentWeight,
+		PackSetVersion:     packSetVersion,
+		Status:             status,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+		Explanation:        explanation,
+		Alternatives:       quote.Alternatives,
+		Shipment:           quote.Shipment,
+		Packaging:          packaging,
+	}
+	if quote.Backordered > 0 {
+		order.Backorder = &models.Order{
+			PublicID:           models.NewPublicOrderID(),
+			RequestedItemCount: quote.Backordered,
+			Packs:              map[models.Pack]int{},
+			Status:             models.OrderStatusBackordered,
+			CreatedAt:          now,
+			UpdatedAt:          now,
+			BackorderOf:        order.PublicID,
+		}
 	}
 
 	// persist order to repo
@@ -53,6 +131,269 @@ func (s *Service) CreateOrder(orderRequest models.OrderRequest, availablePacks [
 	return order, nil
 }
 
+// Calculates the packs an order would get, with the same validation as CreateOrder, but doesn't save anything
+func (s *Service) Quote(orderRequest models.OrderRequest, availablePacks []models.PackDetails) (*models.Quote, error) {
+	quote, _, err := s.quote(orderRequest, availablePacks)
+	return quote, err
+}
+
+// the quote and the solver that calculated it
+func (s *Service) quote(orderRequest models.OrderRequest, availablePacks []models.PackDetails) (*models.Quote, *Solver, error) {
+	if orderRequest.ItemCount <= 0 {
+		return nil, nil, fmt.Errorf("%w: Item count has to be greater than 0", InvalidOrderItemCountError)
+	}
+	if orderRequest.ItemCount > s.MaxOrderItemCount {
+		return nil, nil, fmt.Errorf("%w: Item count has to be less than or equal to %d", InvalidOrderItemCountError, s.MaxOrderItemCount)
+	}
+	if orderRequest.Alternatives < 0 || orderRequest.Alternatives > MaxAlternatives {
+		return nil, nil, fmt.Errorf("%w: Alternatives have to be between 0 and %d", InvalidAlternativesError, MaxAlternatives)
+	}
+	if orderRequest.Fulfillment != "" && orderRequest.Fulfillment != models.FulfillmentOvership && orderRequest.Fulfillment != models.FulfillmentBackorder {
+		return nil, nil, fmt.Errorf("%w: %q, use %s or %s", InvalidFulfillmentError, orderRequest.Fulfillment, models.FulfillmentOvership, models.FulfillmentBackorder)
+	}
+	if err := validateOvershoot(orderRequest); err != nil {
+		return nil, nil, err
+	}
+
+	// disabled packs are still configured, orders just can't use them
+	availablePacks = models.EnabledPacks(availablePacks)
+
+	packsBySize := make(map[models.Pack]models.PackDetails, len(availablePacks))
+	costs := make(map[models.Pack]int, len(availablePacks))
+	for _, pack := range availablePacks {
+		packsBySize[pack.Size] = pack
+		costs[pack.Size] = pack.UnitCost
+	}
+
+	strategy, err := StrategyFor(orderRequest.Objective, costs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	solver, err := s.solverFor(models.PackSizes(availablePacks), strategy)
+	if errors.Is(err, InvalidObjectiveError) {
+		return nil, nil, err
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", OrderCalculationError, err)
+	}
+
+	stock := models.StockLevels(availablePacks)
+	limit := s.overshootLimit(orderRequest)
+	packsCalculation, err := solver.SolveWithStock(orderRequest.ItemCount, stock)
+	withinLimit := false
+	if err == nil && limit.Exceeded(orderRequest.ItemCount, packsCalculation.TotalItems) {
+		// the objective doesn't get to break the limit, the best packing within it goes first. least items has none,
+		// it already ships as little as it can
+		// when that can't be worked out the order keeps its packing and goes over the limit like before
+		maxShipped, _ := limit.MaxShipped(orderRequest.ItemCount)
+		if within, withinErr := solver.SolveWithin(orderRequest.ItemCount, maxShipped, stock); withinErr == nil && within != nil {
+			packsCalculation, withinLimit = within, true
+		}
+	}
+	if orderRequest.Fulfillment == models.FulfillmentBackorder &&
+		(errors.Is(err, InsufficientStockError) || (err == nil && limit.Exceeded(orderRequest.ItemCount, packsCalculation.TotalItems))) {
+		packsCalculation, err = partialPacking(solver, orderRequest.ItemCount, stock)
+	}
+	if errors.Is(err, InsufficientStockError) || errors.Is(err, InvalidOrderItemCountError) || errors.Is(err, BackorderError) {
+		return nil, nil, err
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", OrderCalculationError, err)
+	}
+
+	quote := &models.Quote{
+		RequestedItemCount: orderRequest.ItemCount,
+		Packs:              packsCalculation.Packs,
+		TotalItems:         packsCalculation.TotalItems,
+		TotalPacks:         packsCalculation.TotalPacks,
+		Overshoot:          max(packsCalculation.TotalItems-orderRequest.ItemCount, 0),
+		Backordered:        max(orderRequest.ItemCount-packsCalculation.TotalItems, 0),
+		WithinLimit:        withinLimit,
+	}
+	if quote.NeedsApproval, err = s.checkOvershoot(orderRequest, orderRequest.ItemCount, packsCalculation.TotalItems); err != nil {
+		return nil, nil, err
+	}
+
+	for pack, count := range packsCalculation.Packs {
+		quote.PackagingCost += packsBySize[pack].UnitCost * count
+		quote.ShipmentWeight += packsBySize[pack].TareWeight * count
+	}
+
+	quote.Shipment, err = AssignContainers(packsCalculation.Packs, availablePacks, s.Containers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if orderRequest.Alternatives == 0 {
+		return quote, solver, nil
+	}
+
+	// alternatives are about swapping packs on the floor, they ignore stock
+	alternatives, err := solver.Alternatives(orderRequest.ItemCount, orderRequest.Alternatives)
+	if errors.Is(err, InvalidOrderItemCountError) {
+		return nil, nil, err
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", OrderCalculationError, err)
+	}
+
+	for _, alternative := range alternatives {
+		packingAlternative := models.PackingAlternative{
+			Packs:      alternative.Packs,
+			TotalItems: alternative.TotalItems,
+			TotalPacks: alternative.TotalPacks,
+			Overshoot:  alternative.TotalItems - orderRequest.ItemCount,
+			Optimal:    alternative.Optimal,
+		}
+		for pack, count := range alternative.Packs {
+			packingAlternative.PackagingCost += packsBySize[pack].UnitCost * count
+			packingAlternative.ShipmentWeight += packsBySize[pack].TareWeight * count
+		}
+		quote.Alternatives = append(quote.Alternatives, packingAlternative)
+	}
+
+	return quote, solver, nil
+}
+
+// cases and pallets for the packs of a new order, nil without a packaging source
+func (s *Service) planPackaging(packs map[models.Pack]int) (*models.PackagingPlan, error) {
+	if s.Packaging == nil {
+		return nil, nil
+	}
+	hierarchy, err := s.Packaging.GetPackaging()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get packaging: %w", err)
+	}
+	return PlanPackaging(packs, hierarchy)
+}
+
 func (s *Service) GetLast10Orders() ([]*models.Order, error) {
 	return s.repo.GetLast10Orders()
 }
+
+func (s *Service) GetOrder(id int64) (*models.Order, error) {
+	return s.repo.GetOrder(id)
+}
+
+// finds an order by its public ID only, sequential IDs are guessable so customers can't look orders up by them.
+// fails with OrderNotFoundError for anything else
+func (s *Service) FindPublicOrder(id string) (*models.Order, error) {
+	if !strings.HasPrefix(id, models.PublicOrderIDPrefix) {
+		return nil, fmt.Errorf("%w: %s", OrderNotFoundError, id)
+	}
+	return s.repo.GetOrderByPublicID(id)
+}
+
+// finds an order by either its public ID or its sequential ID, for admins. see FindPublicOrder
+func (s *Service) FindOrder(id string) (*models.Order, error) {
+	if strings.HasPrefix(id, models.PublicOrderIDPrefix) {
+		return s.repo.GetOrderByPublicID(id)
+	}
+
+	sequentialID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", OrderNotFoundError, id)
+	}
+	return s.repo.GetOrder(sequentialID)
+}
+
+// drops the cached solvers, the next order builds new ones. called when the pack set changes
+func (s *Service) InvalidateSolver() {
+	s.solverMu.Lock()
+	defer s.solverMu.Unlock()
+	s.solvers = nil
+}
+
+// returns the cached solver if it was built for these packs, otherwise builds and caches a new one
+func (s *Service) solverFor(availablePacks []models.Pack, strategy Strategy) (*Solver, error) {
+	s.solverMu.RLock()
+	solver := s.solvers[strategy.Objective()]
+	s.solverMu.RUnlock()
+
+	if solver != nil && solver.Matches(availablePacks, strategy) {
+		return solver, nil
+	}
+
+	solver, err := NewStrategySolver(availablePacks, strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	s.solverMu.Lock()
+	if s.solvers == nil {
+		s.solvers = make(map[models.Objective]*Solver)
+	}
+	s.solvers[strategy.Objective()] = solver
+	s.solverMu.Unlock()
+
+	return solver, nil
+}
+
+// drops the cached solvers of product pack sets, the next line orders build new ones. called when a product changes
+func (s *Service) InvalidateLineSolvers() {
+	s.solverMu.Lock()
+	defer s.solverMu.Unlock()
+	s.lineSolvers = nil
+}
+
+// like solverFor, for the pack set of a product
+func (s *Service) lineSolverFor(availablePacks []models.Pack, strategy Strategy) (*Solver, error) {
+	key, err := lineSolverKey(availablePacks, strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	s.solverMu.RLock()
+	solver := s.lineSolvers[key]
+	s.solverMu.RUnlock()
+
+	if solver != nil && solver.Matches(availablePacks, strategy) {
+		return solver, nil
+	}
+
+	solver, err = NewStrategySolver(availablePacks, strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	s.solverMu.Lock()
+	if s.lineSolvers == nil {
+		s.lineSolvers = make(map[string]*Solver)
+	}
+	s.lineSolvers[key] = solver
+	s.solverMu.Unlock()
+
+	return solver, nil
+}
+
+// e.g. lowest-cost 250:40 500:60, the sizes with what the strategy counts for each
+func lineSolverKey(availablePacks []models.Pack, strategy Strategy) (string, error) {
+	var key strings.Builder
+	key.WriteString(string(strategy.Objective()))
+	for _, pack := range availablePacks {
+		cost, err := strategy.PackCost(pack)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&key, " %d:%d", pack, cost)
+	}
+	return key.String(), nil
+}
+
+// moves an order, found like FindOrder, to a new status if the order lifecycle allows it
+func (s *Service) UpdateOrderStatus(id string, status models.OrderStatus) (*models.Order, error) {
+	order, err := s.FindOrder(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateStatusTransition(order.Status, status); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateOrderStatus(order, status); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
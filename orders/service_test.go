@@ -2,6 +2,9 @@ package orders
 
 import (
 	"errors"
+	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"testing"
 
@@ -24,10 +27,48 @@ func (m *MockOrderRepository) SaveOrder(order *models.Order) error {
 	if m.saveOrderError != nil {
 		return m.saveOrderError
 	}
+	order.ID = int64(len(m.savedOrders) + 1)
 	m.savedOrders = append(m.savedOrders, order)
+	if order.Backorder != nil {
+		order.Backorder.ID = order.ID + 1
+		m.savedOrders = append(m.savedOrders, order.Backorder)
+	}
 	return nil
 }
 
+func (m *MockOrderRepository) GetOrder(id int64) (*models.Order, error) {
+	for _, order := range m.savedOrders {
+		if order.ID == id {
+			return order, nil
+		}
+	}
+	return nil, OrderNotFoundError
+}
+
+func (m *MockOrderRepository) UpdateOrderStatus(order *models.Order, status models.OrderStatus) error {
+	for _, saved := range m.savedOrders {
+		if saved.ID != order.ID {
+			continue
+		}
+		if saved.Status != order.Status {
+			return OrderStatusChangedError
+		}
+		saved.Status = status
+		order.Status = status
+		return nil
+	}
+	return OrderNotFoundError
+}
+
+func (m *MockOrderRepository) GetOrderByPublicID(publicID string) (*models.Order, error) {
+	for _, order := range m.savedOrders {
+		if order.PublicID == publicID {
+			return order, nil
+		}
+	}
+	return nil, OrderNotFoundError
+}
+
 func (m *MockOrderRepository) GetLast10Orders() ([]*models.Order, error) {
 	if m.getLast10Error != nil {
 		return nil, m.getLast10Error
@@ -43,6 +84,64 @@ func (m *MockOrderRepository) GetLast10Orders() ([]*models.Order, error) {
 	return result, nil
 }
 
+// in memory version of the history query, only the filters the tests use
+func (m *MockOrderRepository) ListOrders(query models.OrderQuery) ([]*models.Order, error) {
+	descending := query.Sort == models.OrderSortNewest || query.Sort == models.OrderSortRequestedDesc || query.Sort == models.OrderSortShippedDesc
+	compare := func(a, b *models.OrderCursor) int {
+		if a.Value != b.Value {
+			return int(a.Value - b.Value)
+		}
+		return int(a.ID - b.ID)
+	}
+
+	var result []*models.Order
+	for _, order := range m.savedOrders {
+		if len(query.Statuses) > 0 && !slices.Contains(query.Statuses, order.Status) {
+			continue
+		}
+		if order.RequestedItemCount < query.MinRequested || (query.MaxRequested != 0 && order.RequestedItemCount > query.MaxRequested) {
+			continue
+		}
+		if query.PackSize != 0 && order.Packs[query.PackSize] == 0 {
+			continue
+		}
+		if query.SingleCountOnly && (len(order.Lines) > 0 || order.BackorderOf != "") {
+			continue
+		}
+		if query.After != nil {
+			c := compare(cursorFor(query.Sort, order), query.After)
+			if (descending && c >= 0) || (!descending && c <= 0) {
+				continue
+			}
+		}
+		result = append(result, order)
+	}
+
+	slices.SortFunc(result, func(a, b *models.Order) int {
+		c := compare(cursorFor(query.Sort, a), cursorFor(query.Sort, b))
+		if descending {
+			return -c
+		}
+		return c
+	})
+	return result[:min(len(result), query.Limit)], nil
+}
+
+func (m *MockOrderRepository) GetOrderDemand() ([]models.OrderDemand, error) {
+	orders := make(map[int]int)
+	for _, order := range m.savedOrders {
+		if len(order.Lines) > 0 || order.BackorderOf != "" {
+			continue
+		}
+		orders[order.RequestedItemCount]++
+	}
+	demand := []models.OrderDemand{}
+	for _, itemCount := range slices.Sorted(maps.Keys(orders)) {
+		demand = append(demand, models.OrderDemand{ItemCount: itemCount, Orders: orders[itemCount]})
+	}
+	return demand, nil
+}
+
 func (m *MockOrderRepository) GetSavedOrders() []*models.Order {
 	return m.savedOrders
 }
@@ -104,7 +203,7 @@ func TestOrderService_CreateOrder_HappyPath(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := NewMockOrderRepository()
 			service := NewService(tt.maxCount, mockRepo)
-			order, err := service.CreateOrder(tt.orderRequest, tt.packs)
+			order, err := service.CreateOrder(tt.orderRequest, models.Packs(tt.packs).Details(), 0)
 
 			// no errors
 			if err != nil {
@@ -211,13 +310,20 @@ func TestService_CreateOrder_ErrorCases(t *testing.T) {
 			packs:        nil,
 			expectedErr:  OrderCalculationError,
 		},
+		{
+			name:         "unknown objective",
+			maxCount:     1000000000,
+			orderRequest: models.OrderRequest{ItemCount: 1, Objective: "cheapest-please"},
+			packs:        []models.Pack{250, 500, 1000, 2000, 5000},
+			expectedErr:  InvalidObjectiveError,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := NewMockOrderRepository()
 			service := NewService(tt.maxCount, mockRepo)
-			order, err := service.CreateOrder(tt.orderRequest, tt.packs)
+			order, err := service.CreateOrder(tt.orderRequest, models.Packs(tt.packs).Details(), 0)
 
 			// has to error
 			if err == nil {
@@ -251,7 +357,7 @@ func TestService_CreateOrder_RepositoryError(t *testing.T) {
 	orderRequest := models.OrderRequest{ItemCount: 1}
 	packs := []models.Pack{250, 500, 1000}
 
-	order, err := service.CreateOrder(orderRequest, packs)
+	order, err := service.CreateOrder(orderRequest, models.Packs(packs).Details(), 0)
 
 	// return error when repository fails
 	if err == nil {
@@ -334,3 +440,479 @@ func TestService_GetLast10Orders_RepositoryError(t *testing.T) {
 		t.Errorf("GetLast10Orders() error = %v, want %v", err, mockRepo.getLast10Error)
 	}
 }
+
+func TestService_CreateOrder_CachesSolver(t *testing.T) {
+	mockRepo := NewMockOrderRepository()
+	service := NewService(1000000000, mockRepo)
+	packs := models.Packs{250, 500, 1000, 2000, 5000}
+
+	if _, err := service.CreateOrder(models.OrderRequest{ItemCount: 251}, packs.Details(), 0); err != nil {
+		t.Fatalf("CreateOrder() unexpected error = %v", err)
+	}
+	cached := service.solvers[models.ObjectiveLeastItems]
+	if cached == nil {
+		t.Fatal("CreateOrder() expected solver to be cached")
+	}
+
+	// same pack set reuses the solver
+	if _, err := service.CreateOrder(models.OrderRequest{ItemCount: 12001}, packs.Details(), 0); err != nil {
+		t.Fatalf("CreateOrder() unexpected error = %v", err)
+	}
+	if service.solvers[models.ObjectiveLeastItems] != cached {
+		t.Error("CreateOrder() expected cached solver to be reused for the same packs")
+	}
+
+	// different pack set is never answered by the stale solver
+	order, err := service.CreateOrder(models.OrderRequest{ItemCount: 251}, models.Packs{300}.Details(), 0)
+	if err != nil {
+		t.Fatalf("CreateOrder() unexpected error = %v", err)
+	}
+	if order.ShippedItemCount != 300 {
+		t.Errorf("ShippedItemCount = %d, want 300", order.ShippedItemCount)
+	}
+	if service.solvers[models.ObjectiveLeastItems] == cached {
+		t.Error("CreateOrder() expected a new solver for different packs")
+	}
+
+	// invalidation drops the solver
+	service.InvalidateSolver()
+	if service.solvers != nil {
+		t.Error("InvalidateSolver() expected solvers to be dropped")
+	}
+}
+
+func TestService_CreateOrder_Objectives(t *testing.T) {
+	packs := []models.PackDetails{
+		{Size: 250, UnitCost: 90},
+		{Size: 500, UnitCost: 90},
+		{Size: 1000, UnitCost: 100},
+		{Size: 2000, UnitCost: 180},
+		{Size: 5000, UnitCost: 400},
+	}
+
+	tests := []struct {
+		name             string
+		objective        models.Objective
+		expectedShipped  int
+		expectedPackSize models.Pack
+	}{
+		{name: "default objective", objective: "", expectedShipped: 750},
+		{name: "fewest packs", objective: models.ObjectiveFewestPacks, expectedShipped: 1000, expectedPackSize: 1000},
+		{name: "lowest cost", objective: models.ObjectiveLowestCost, expectedShipped: 1000, expectedPackSize: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := NewMockOrderRepository()
+			service := NewService(1000000000, mockRepo)
+
+			order, err := service.CreateOrder(models.OrderRequest{ItemCount: 501, Objective: tt.objective}, packs, 0)
+			if err != nil {
+				t.Fatalf("CreateOrder() unexpected error = %v", err)
+			}
+
+			if order.ShippedItemCount != tt.expectedShipped {
+				t.Errorf("ShippedItemCount = %d, want %d", order.ShippedItemCount, tt.expectedShipped)
+			}
+			if tt.expectedPackSize != 0 && order.Packs[tt.expectedPackSize] != 1 {
+				t.Errorf("Packs = %v, want a single %d pack", order.Packs, tt.expectedPackSize)
+			}
+		})
+	}
+}
+
+func TestService_CreateOrder_PackagingCostAndWeight(t *testing.T) {
+	mockRepo := NewMockOrderRepository()
+	service := NewService(1000000000, mockRepo)
+	packs := []models.PackDetails{
+		{Size: 250, UnitCost: 40, TareWeight: 30},
+		{Size: 500, UnitCost: 60, TareWeight: 50},
+		{Size: 1000, UnitCost: 100, TareWeight: 90},
+		{Size: 2000, UnitCost: 180, TareWeight: 160},
+		{Size: 5000, UnitCost: 400, TareWeight: 350},
+	}
+
+	// 2x5000 + 1x2000 + 1x250
+	order, err := service.CreateOrder(models.OrderRequest{ItemCount: 12001}, packs, 0)
+	if err != nil {
+		t.Fatalf("CreateOrder() unexpected error = %v", err)
+	}
+
+	if order.PackagingCost != 2*400+180+40 {
+		t.Errorf("PackagingCost = %d, want %d", order.PackagingCost, 2*400+180+40)
+	}
+	if order.ShipmentWeight != 2*350+160+30 {
+		t.Errorf("ShipmentWeight = %d, want %d", order.ShipmentWeight, 2*350+160+30)
+	}
+}
+
+func TestService_CreateOrder_RecordsPackSetVersion(t *testing.T) {
+	mockRepo := NewMockOrderRepository()
+	service := NewService(1000000000, mockRepo)
+
+	order, err := service.CreateOrder(models.OrderRequest{ItemCount: 251}, models.Packs{250, 500}.Details(), 7)
+	if err != nil {
+		t.Fatalf("CreateOrder() unexpected error = %v", err)
+	}
+	if order.PackSetVersion != 7 || mockRepo.GetSavedOrders()[0].PackSetVersion != 7 {
+		t.Errorf("PackSetVersion = %d, want the version the packs came from", order.PackSetVersion)
+	}
+}
+
+func TestService_CreateOrder_LimitedStock(t *testing.T) {
+	mockRepo := NewMockOrderRepository()
+	service := NewService(1000000000, mockRepo)
+	noStock, fewPacks := 0, 1
+	packs := []models.PackDetails{
+		{Size: 250},
+		{Size: 500},
+		{Size: 1000},
+		{Size: 2000, Stock: &fewPacks},
+		{Size: 5000, Stock: &noStock},
+	}
+
+	// without stock limits this would be 2x5000 + 1x2000 + 1x250
+	order, err := service.CreateOrder(models.OrderRequest{ItemCount: 12001}, packs, 0)
+	if err != nil {
+		t.Fatalf("CreateOrder() unexpected error = %v", err)
+	}
+
+	if order.ShippedItemCount != 12250 {
+		t.Errorf("ShippedItemCount = %d, want %d", order.ShippedItemCount, 12250)
+	}
+	if order.Packs[5000] != 0 || order.Packs[2000] > 1 {
+		t.Errorf("Packs = %v, exceed the available stock", order.Packs)
+	}
+
+	// nothing unlimited left, and not enough stock for the order
+	limited := []models.PackDetails{{Size: 2000, Stock: &fewPacks}, {Size: 5000, Stock: &noStock}}
+	_, err = service.CreateOrder(models.OrderRequest{ItemCount: 12001}, limited, 0)
+	if !errors.Is(err, InsufficientStockError) {
+		t.Errorf("CreateOrder() error = %v, want %v", err, InsufficientStockError)
+	}
+}
+
+func TestService_Quote(t *testing.T) {
+	mockRepo := NewMockOrderRepository()
+	service := NewService(1000, mockRepo)
+	packs := []models.PackDetails{
+		{Size: 250, UnitCost: 40, TareWeight: 30},
+		{Size: 500, UnitCost: 60, TareWeight: 50},
+		{Size: 1000, UnitCost: 100, TareWeight: 90},
+	}
+
+	quote, err := service.Quote(models.OrderRequest{ItemCount: 501}, packs)
+	if err != nil {
+		t.Fatalf("Quote() unexpected error = %v", err)
+	}
+
+	if quote.RequestedItemCount != 501 {
+		t.Errorf("RequestedItemCount = %d, want %d", quote.RequestedItemCount, 501)
+	}
+	if quote.TotalItems != 750 || quote.TotalPacks != 2 || quote.Overshoot != 249 {
+		t.Errorf("TotalItems, TotalPacks, Overshoot = %d, %d, %d, want 750, 2, 249", quote.TotalItems, quote.TotalPacks, quote.Overshoot)
+	}
+	if quote.Packs[250] != 1 || quote.Packs[500] != 1 {
+		t.Errorf("Packs = %v, want 1x250 + 1x500", quote.Packs)
+	}
+	if quote.PackagingCost != 100 || quote.ShipmentWeight != 80 {
+		t.Errorf("PackagingCost, ShipmentWeight = %d, %d, want 100, 80", quote.PackagingCost, quote.ShipmentWeight)
+	}
+
+	// same limit as orders
+	_, err = service.Quote(models.OrderRequest{ItemCount: 1001}, packs)
+	if !errors.Is(err, InvalidOrderItemCountError) {
+		t.Errorf("Quote() error = %v, want %v", err, InvalidOrderItemCountError)
+	}
+
+	if len(mockRepo.GetSavedOrders()) != 0 {
+		t.Errorf("Quote() saved %d orders, want none", len(mockRepo.GetSavedOrders()))
+	}
+}
+
+func TestService_Quote_IgnoresDisabledPacks(t *testing.T) {
+	service := NewService(1000, NewMockOrderRepository())
+	packs := []models.PackDetails{{Size: 250}, {Size: 500, Disabled: true}, {Size: 1000}}
+
+	// 1x500 would be the best fit, but it's disabled
+	quote, err := service.Quote(models.OrderRequest{ItemCount: 501}, packs)
+	if err != nil {
+		t.Fatalf("Quote() unexpected error = %v", err)
+	}
+	if len(quote.Packs) != 1 || quote.Packs[250] != 3 {
+		t.Errorf("Packs = %v, want 3x250", quote.Packs)
+	}
+}
+
+func TestService_Quote_Alternatives(t *testing.T) {
+	mockRepo := NewMockOrderRepository()
+	service := NewService(1000000000, mockRepo)
+	packs := []models.PackDetails{
+		{Size: 250, UnitCost: 40},
+		{Size: 500, UnitCost: 60},
+		{Size: 1000, UnitCost: 100},
+	}
+
+	quote, err := service.Quote(models.OrderRequest{ItemCount: 501, Alternatives: 3}, packs)
+	if err != nil {
+		t.Fatalf("Quote() unexpected error = %v", err)
+	}
+
+	if len(quote.Alternatives) != 3 {
+		t.Fatalf("got %d alternatives, want 3: %+v", len(quote.Alternatives), quote.Alternatives)
+	}
+	best := quote.Alternatives[0]
+	if !best.Optimal || best.TotalItems != quote.TotalItems || best.TotalPacks != quote.TotalPacks {
+		t.Errorf("best alternative = %+v, want the quoted packing", best)
+	}
+	if best.Overshoot != 249 || best.PackagingCost != 100 {
+		t.Errorf("best alternative overshoot and cost = %d, %d, want 249, 100", best.Overshoot, best.PackagingCost)
+	}
+
+	order, err := service.CreateOrder(models.OrderRequest{ItemCount: 501, Alternatives: 2}, packs, 0)
+	if err != nil {
+		t.Fatalf("CreateOrder() unexpected error = %v", err)
+	}
+	if len(order.Alternatives) != 2 {
+		t.Errorf("got %d order alternatives, want 2", len(order.Alternatives))
+	}
+
+	for _, alternatives := range []int{-1, MaxAlternatives + 1} {
+		_, err := service.Quote(models.OrderRequest{ItemCount: 501, Alternatives: alternatives}, packs)
+		if !errors.Is(err, InvalidAlternativesError) {
+			t.Errorf("Quote() with %d alternatives error = %v, want %v", alternatives, err, InvalidAlternativesError)
+		}
+	}
+}
+
+func TestService_CreateOrder_KeepsExplanation(t *testing.T) {
+	mockRepo := NewMockOrderRepository()
+	service := NewService(1000000000, mockRepo)
+
+	order, err := service.CreateOrder(models.OrderRequest{ItemCount: 501}, models.Packs{250, 500, 1000}.Details(), 0)
+	if err != nil {
+		t.Fatalf("CreateOrder() unexpected error = %v", err)
+	}
+	if order.Explanation == nil || len(order.Explanation.Steps) == 0 {
+		t.Fatalf("Explanation = %+v, want steps", order.Explanation)
+	}
+
+	saved, err := service.GetOrder(order.ID)
+	if err != nil {
+		t.Fatalf("GetOrder() unexpected error = %v", err)
+	}
+	if saved.Explanation != order.Explanation {
+		t.Errorf("saved order has a different explanation")
+	}
+
+	if _, err := service.GetOrder(order.ID + 1); !errors.Is(err, OrderNotFoundError) {
+		t.Errorf("GetOrder() error = %v, want %v", err, OrderNotFoundError)
+	}
+}
+
+func TestService_FindOrder(t *testing.T) {
+	mockRepo := NewMockOrderRepository()
+	service := NewService(1000000000, mockRepo)
+
+	order, err := service.CreateOrder(models.OrderRequest{ItemCount: 501}, models.Packs{250, 500}.Details(), 0)
+	if err != nil {
+		t.Fatalf("CreateOrder() unexpected error = %v", err)
+	}
+	if !strings.HasPrefix(order.PublicID, models.PublicOrderIDPrefix) || len(order.PublicID) != len(models.PublicOrderIDPrefix)+26 {
+		t.Errorf("PublicID = %q, want %s followed by 26 characters", order.PublicID, models.PublicOrderIDPrefix)
+	}
+
+	other, err := service.CreateOrder(models.OrderRequest{ItemCount: 501}, models.Packs{250, 500}.Details(), 0)
+	if err != nil {
+		t.Fatalf("CreateOrder() unexpected error = %v", err)
+	}
+	if other.PublicID == order.PublicID {
+		t.Errorf("two orders got the same public id %q", order.PublicID)
+	}
+
+	tests := []struct {
+		name      string
+		id        string
+		wantOrder *models.Order
+	}{
+		{"public id", order.PublicID, order},
+		{"sequential id", fmt.Sprint(other.ID), other},
+		{"unknown public id", models.PublicOrderIDPrefix + "nope", nil},
+		{"unknown sequential id", "999", nil},
+		{"not an id", "abc", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := service.FindOrder(tt.id)
+			if tt.wantOrder == nil {
+				if !errors.Is(err, OrderNotFoundError) {
+					t.Errorf("FindOrder() error = %v, want %v", err, OrderNotFoundError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindOrder() unexpected error = %v", err)
+			}
+			if found != tt.wantOrder {
+				t.Errorf("FindOrder() = %+v, want %+v", found, tt.wantOrder)
+			}
+		})
+	}
+}
+
+func TestService_FindPublicOrder(t *testing.T) {
+	mockRepo := NewMockOrderRepository()
+	service := NewService(1000000000, mockRepo)
+
+	order, err := service.CreateOrder(models.OrderRequest{ItemCount: 501}, models.Packs{250, 500}.Details(), 0)
+	if err != nil {
+		t.Fatalf("CreateOrder() unexpected error = %v", err)
+	}
+
+	found, err := service.FindPublicOrder(order.PublicID)
+	if err != nil || found != order {
+		t.Errorf("FindPublicOrder() = %+v, %v, want %+v", found, err, order)
+	}
+	// sequential ids can be guessed, they only work for admins
+	for _, id := range []string{fmt.Sprint(order.ID), models.PublicOrderIDPrefix + "nope", "abc"} {
+		if _, err := service.FindPublicOrder(id); !errors.Is(err, OrderNotFoundError) {
+			t.Errorf("FindPublicOrder(%q) error = %v, want %v", id, err, OrderNotFoundError)
+		}
+	}
+}
+
+func TestService_UpdateOrderStatus(t *testing.T) {
+	mockRepo := NewMockOrderRepository()
+	service := NewService(1000000000, mockRepo)
+
+	order, err := service.CreateOrder(models.OrderRequest{ItemCount: 501}, models.Packs{250, 500}.Details(), 0)
+	if err != nil {
+		t.Fatalf("CreateOrder() unexpected error = %v", err)
+	}
+
+	for _, status := range []models.OrderStatus{models.OrderStatusPending, models.OrderStatusPacked} {
+		updated, err := service.UpdateOrderStatus(order.PublicID, status)
+		if err != nil {
+			t.Fatalf("UpdateOrderStatus(%s) unexpected error = %v", status, err)
+		}
+		if updated.Status != status {
+			t.Errorf("Status = %s, want %s", updated.Status, status)
+		}
+	}
+
+	var transitionErr *StatusTransitionError
+	if _, err := service.UpdateOrderStatus(order.PublicID, models.OrderStatusNew); !errors.As(err, &transitionErr) {
+		t.Errorf("UpdateOrderStatus() error = %v, want a StatusTransitionError", err)
+	}
+	if _, err := service.UpdateOrderStatus("999", models.OrderStatusShipped); !errors.Is(err, OrderNotFoundError) {
+		t.Errorf("UpdateOrderStatus() error = %v, want %v", err, OrderNotFoundError)
+	}
+}
+
+func TestService_ListOrders(t *testing.T) {
+	mockRepo := NewMockOrderRepository()
+	service := NewService(1000000000, mockRepo)
+
+	for _, count := range []int{1, 501, 251, 750, 251} {
+		if _, err := service.CreateOrder(models.OrderRequest{ItemCount: count}, models.Packs{250, 500}.Details(), 0); err != nil {
+			t.Fatalf("CreateOrder(%d) unexpected error = %v", count, err)
+		}
+	}
+	if _, err := service.UpdateOrderStatus("2", models.OrderStatusCancelled); err != nil {
+		t.Fatalf("UpdateOrderStatus() unexpected error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		query   models.OrderQuery
+		wantIDs [][]int64
+	}{
+		{
+			name:    "newest first by default",
+			query:   models.OrderQuery{},
+			wantIDs: [][]int64{{5, 4, 3, 2, 1}},
+		},
+		{
+			name:    "pages of two",
+			query:   models.OrderQuery{Sort: models.OrderSortOldest, Limit: 2},
+			wantIDs: [][]int64{{1, 2}, {3, 4}, {5}},
+		},
+		{
+			name:    "requested items ties broken by id",
+			query:   models.OrderQuery{Sort: models.OrderSortRequestedDesc, Limit: 2},
+			wantIDs: [][]int64{{4, 2}, {5, 3}, {1}},
+		},
+		{
+			name:    "filtered by status",
+			query:   models.OrderQuery{Statuses: []models.OrderStatus{models.OrderStatusCancelled}},
+			wantIDs: [][]int64{{2}},
+		},
+		{
+			name:    "filtered by requested items and pack size",
+			query:   models.OrderQuery{MinRequested: 2, PackSize: 250, Sort: models.OrderSortOldest},
+			wantIDs: [][]int64{{2, 4}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor := ""
+			for i, wantIDs := range tt.wantIDs {
+				page, err := service.ListOrders(tt.query, cursor)
+				if err != nil {
+					t.Fatalf("ListOrders() page %d unexpected error = %v", i+1, err)
+				}
+				var ids []int64
+				for _, order := range page.Orders {
+					ids = append(ids, order.ID)
+				}
+				if !slices.Equal(ids, wantIDs) {
+					t.Errorf("ListOrders() page %d ids = %v, want %v", i+1, ids, wantIDs)
+				}
+				lastPage := i == len(tt.wantIDs)-1
+				if (page.NextCursor == "") != lastPage {
+					t.Fatalf("ListOrders() page %d NextCursor = %q, last page = %v", i+1, page.NextCursor, lastPage)
+				}
+				cursor = page.NextCursor
+			}
+		})
+	}
+}
+
+func TestService_ListOrders_InvalidQuery(t *testing.T) {
+	service := NewService(1000000000, NewMockOrderRepository())
+
+	page, err := service.ListOrders(models.OrderQuery{Sort: models.OrderSortOldest, Limit: 1}, "")
+	if err != nil {
+		t.Fatalf("ListOrders() unexpected error = %v", err)
+	}
+	if page.NextCursor != "" {
+		t.Errorf("NextCursor = %q on an empty history", page.NextCursor)
+	}
+	otherSortCursor := encodeOrderCursor(&models.OrderCursor{Sort: models.OrderSortOldest, ID: 1})
+
+	tests := []struct {
+		name    string
+		query   models.OrderQuery
+		cursor  string
+		wantErr error
+	}{
+		{"unknown status", models.OrderQuery{Statuses: []models.OrderStatus{"lost"}}, "", InvalidStatusError},
+		{"unknown sort", models.OrderQuery{Sort: "random"}, "", InvalidOrderQueryError},
+		{"limit too big", models.OrderQuery{Limit: MaxOrderPageSize + 1}, "", InvalidOrderQueryError},
+		{"negative limit", models.OrderQuery{Limit: -1}, "", InvalidOrderQueryError},
+		{"requested range reversed", models.OrderQuery{MinRequested: 10, MaxRequested: 5}, "", InvalidOrderQueryError},
+		{"negative pack size", models.OrderQuery{PackSize: -250}, "", InvalidOrderQueryError},
+		{"garbage cursor", models.OrderQuery{}, "not a cursor!", InvalidOrderQueryError},
+		{"cursor for another sort", models.OrderQuery{Sort: models.OrderSortNewest}, otherSortCursor, InvalidOrderQueryError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.ListOrders(tt.query, tt.cursor); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ListOrders() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
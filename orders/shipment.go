@@ -0,0 +1,140 @@
+package orders
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/irreal/order-packs/models"
+)
+
+// packs of one size and weight going out, packs of the same size weigh differently when they hold different products
+type shipmentPack struct {
+	size   models.Pack
+	weight int
+	count  int
+}
+
+// Splits the packs of an order into containers within the limits of config, see assignContainers.
+// details give the tare weight of every size. nil when containers have no limits
+func AssignContainers(packs map[models.Pack]int, details []models.PackDetails, config models.ContainerConfig) (*models.Shipment, error) {
+	return assignContainers(shipmentPacks(packs, details, config.ItemWeight), config)
+}
+
+func shipmentPacks(packs map[models.Pack]int, details []models.PackDetails, itemWeight int) []shipmentPack {
+	tareWeights := make(map[models.Pack]int, len(details))
+	for _, pack := range details {
+		tareWeights[pack.Size] = pack.TareWeight
+	}
+
+	shipped := make([]shipmentPack, 0, len(packs))
+	for pack, count := range packs {
+		shipped = append(shipped, shipmentPack{size: pack, weight: tareWeights[pack] + int(pack)*itemWeight, count: count})
+	}
+	return shipped
+}
+
+// First fit decreasing: the heaviest packs go first, each into the first container with room left,
+// and a new container is started when none has room. Containers that end up with the same packs are kept as one
+// entry with a count, so orders of thousands of packs still get a short breakdown.
+// Fails with ContainerCapacityError when a single pack is heavier than a container can hold
+func assignContainers(packs []shipmentPack, config models.ContainerConfig) (*models.Shipment, error) {
+	if !config.Enabled() {
+		return nil, nil
+	}
+
+	packs = slices.Clone(packs)
+	slices.SortFunc(packs, func(a, b shipmentPack) int {
+		return cmp.Or(cmp.Compare(b.weight, a.weight), cmp.Compare(b.size, a.size))
+	})
+
+	var containers []*models.Container
+	for _, pack := range packs {
+		if config.MaxWeight > 0 && pack.weight > config.MaxWeight {
+			return nil, fmt.Errorf("%w: a pack of %d weighs %d g, containers hold up to %d g",
+				ContainerCapacityError, pack.size, pack.weight, config.MaxWeight)
+		}
+
+		left := pack.count
+		for i := 0; i < len(containers) && left > 0; i++ {
+			group := containers[i]
+			room := min(containerRoom(group, pack.weight, config), left)
+			if room == 0 {
+				continue
+			}
+			if left >= room*group.Count {
+				addToContainer(group, pack, room)
+				left -= room * group.Count
+				continue
+			}
+
+			// not enough for every container of the group, it splits into the filled ones, one with the rest and the untouched ones
+			filled, rest := left/room, left%room
+			var split []*models.Container
+			if filled > 0 {
+				split = append(split, copyContainer(group, filled))
+				addToContainer(split[len(split)-1], pack, room)
+			}
+			if rest > 0 {
+				split = append(split, copyContainer(group, 1))
+				addToContainer(split[len(split)-1], pack, rest)
+			}
+			if untouched := group.Count - filled - min(rest, 1); untouched > 0 {
+				split = append(split, copyContainer(group, untouched))
+			}
+			containers = slices.Replace(containers, i, i+1, split...)
+			left = 0
+		}
+
+		if left == 0 {
+			continue
+		}
+		// what is left goes into new containers, all full but the last one
+		perContainer := min(containerRoom(&models.Container{}, pack.weight, config), left)
+		if full := left / perContainer; full > 0 {
+			container := &models.Container{Count: full, Packs: make(map[models.Pack]int)}
+			addToContainer(container, pack, perContainer)
+			containers = append(containers, container)
+		}
+		if rest := left % perContainer; rest > 0 {
+			container := &models.Container{Count: 1, Packs: make(map[models.Pack]int)}
+			addToContainer(container, pack, rest)
+			containers = append(containers, container)
+		}
+	}
+
+	shipment := &models.Shipment{Containers: make([]models.Container, len(containers)), Limits: config}
+	for i, container := range containers {
+		shipment.Containers[i] = *container
+		shipment.TotalContainers += container.Count
+	}
+	return shipment, nil
+}
+
+// how many more packs of the given weight fit in one container of the group, math.MaxInt when nothing limits them
+func containerRoom(container *models.Container, weight int, config models.ContainerConfig) int {
+	room := math.MaxInt
+	if config.MaxPacks > 0 {
+		room = config.MaxPacks - container.TotalPacks
+	}
+	if config.MaxWeight > 0 && weight > 0 {
+		room = min(room, (config.MaxWeight-container.Weight)/weight)
+	}
+	return max(room, 0)
+}
+
+// adds count packs to every container of the group
+func addToContainer(container *models.Container, pack shipmentPack, count int) {
+	container.Packs[pack.size] += count
+	container.TotalPacks += count
+	container.Weight += count * pack.weight
+}
+
+func copyContainer(container *models.Container, count int) *models.Container {
+	copied := *container
+	copied.Count = count
+	copied.Packs = maps.Clone(container.Packs)
+	return &copied
+}
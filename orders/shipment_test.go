@@ -0,0 +1,163 @@
+package orders
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/irreal/order-packs/models"
+)
+
+func TestAssignContainers(t *testing.T) {
+	details := []models.PackDetails{{Size: 250, TareWeight: 30}, {Size: 500, TareWeight: 50}}
+
+	tests := []struct {
+		name               string
+		packs              map[models.Pack]int
+		details            []models.PackDetails
+		config             models.ContainerConfig
+		expectedContainers []models.Container
+		expectedTotal      int
+	}{
+		{
+			name:    "heaviest packs first, lighter ones fill the gaps",
+			packs:   map[models.Pack]int{250: 5, 500: 3},
+			details: details,
+			config:  models.ContainerConfig{MaxPacks: 4},
+			expectedContainers: []models.Container{
+				{Count: 1, Packs: map[models.Pack]int{500: 3, 250: 1}, TotalPacks: 4, Weight: 180},
+				{Count: 1, Packs: map[models.Pack]int{250: 4}, TotalPacks: 4, Weight: 120},
+			},
+			expectedTotal: 2,
+		},
+		{
+			name:    "full containers are counted once",
+			packs:   map[models.Pack]int{250: 10},
+			details: details,
+			config:  models.ContainerConfig{MaxWeight: 100},
+			expectedContainers: []models.Container{
+				{Count: 3, Packs: map[models.Pack]int{250: 3}, TotalPacks: 3, Weight: 90},
+				{Count: 1, Packs: map[models.Pack]int{250: 1}, TotalPacks: 1, Weight: 30},
+			},
+			expectedTotal: 4,
+		},
+		{
+			name:    "identical containers split when only some get more packs",
+			packs:   map[models.Pack]int{500: 10, 250: 3},
+			details: []models.PackDetails{{Size: 250, TareWeight: 10}, {Size: 500, TareWeight: 40}},
+			config:  models.ContainerConfig{MaxWeight: 100},
+			expectedContainers: []models.Container{
+				{Count: 1, Packs: map[models.Pack]int{500: 2, 250: 2}, TotalPacks: 4, Weight: 100},
+				{Count: 1, Packs: map[models.Pack]int{500: 2, 250: 1}, TotalPacks: 3, Weight: 90},
+				{Count: 3, Packs: map[models.Pack]int{500: 2}, TotalPacks: 2, Weight: 80},
+			},
+			expectedTotal: 5,
+		},
+		{
+			name:    "items add to the weight",
+			packs:   map[models.Pack]int{250: 3},
+			details: details,
+			config:  models.ContainerConfig{MaxWeight: 1100, ItemWeight: 2},
+			expectedContainers: []models.Container{
+				{Count: 1, Packs: map[models.Pack]int{250: 2}, TotalPacks: 2, Weight: 1060},
+				{Count: 1, Packs: map[models.Pack]int{250: 1}, TotalPacks: 1, Weight: 530},
+			},
+			expectedTotal: 2,
+		},
+		{
+			name:   "weightless packs only count against max packs",
+			packs:  map[models.Pack]int{250: 7},
+			config: models.ContainerConfig{MaxPacks: 5, MaxWeight: 10},
+			expectedContainers: []models.Container{
+				{Count: 1, Packs: map[models.Pack]int{250: 5}, TotalPacks: 5},
+				{Count: 1, Packs: map[models.Pack]int{250: 2}, TotalPacks: 2},
+			},
+			expectedTotal: 2,
+		},
+		{
+			name:    "many packs keep a short breakdown",
+			packs:   map[models.Pack]int{250: 4_000_000},
+			details: details,
+			config:  models.ContainerConfig{MaxPacks: 10},
+			expectedContainers: []models.Container{
+				{Count: 400_000, Packs: map[models.Pack]int{250: 10}, TotalPacks: 10, Weight: 300},
+			},
+			expectedTotal: 400_000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shipment, err := AssignContainers(tt.packs, tt.details, tt.config)
+			if err != nil {
+				t.Fatalf("AssignContainers() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(shipment.Containers, tt.expectedContainers) {
+				t.Errorf("Containers = %+v, want %+v", shipment.Containers, tt.expectedContainers)
+			}
+			if shipment.TotalContainers != tt.expectedTotal {
+				t.Errorf("TotalContainers = %d, want %d", shipment.TotalContainers, tt.expectedTotal)
+			}
+			if shipment.Limits != tt.config {
+				t.Errorf("Limits = %+v, want %+v", shipment.Limits, tt.config)
+			}
+		})
+	}
+}
+
+func TestAssignContainers_NoLimits(t *testing.T) {
+	shipment, err := AssignContainers(map[models.Pack]int{250: 3}, nil, models.ContainerConfig{ItemWeight: 5})
+	if err != nil || shipment != nil {
+		t.Errorf("AssignContainers() = %+v, %v, want no shipment", shipment, err)
+	}
+}
+
+func TestAssignContainers_PackTooHeavy(t *testing.T) {
+	_, err := AssignContainers(map[models.Pack]int{250: 1}, []models.PackDetails{{Size: 250, TareWeight: 30}}, models.ContainerConfig{MaxWeight: 20})
+	if !errors.Is(err, ContainerCapacityError) {
+		t.Errorf("AssignContainers() error = %v, want %v", err, ContainerCapacityError)
+	}
+}
+
+func TestService_Shipment(t *testing.T) {
+	mockRepo := NewMockOrderRepository()
+	service := NewService(1000, mockRepo)
+	service.Containers = models.ContainerConfig{MaxPacks: 2}
+
+	// 3 packs of 250
+	order, err := service.CreateOrder(models.OrderRequest{ItemCount: 750}, models.Packs{250}.Details(), 0)
+	if err != nil {
+		t.Fatalf("CreateOrder() unexpected error = %v", err)
+	}
+	if order.Shipment == nil || order.Shipment.TotalContainers != 2 {
+		t.Errorf("Shipment = %+v, want 2 containers", order.Shipment)
+	}
+
+	quote, err := service.Quote(models.OrderRequest{ItemCount: 750}, models.Packs{250}.Details())
+	if err != nil {
+		t.Fatalf("Quote() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(quote.Shipment, order.Shipment) {
+		t.Errorf("Quote() Shipment = %+v, want %+v", quote.Shipment, order.Shipment)
+	}
+
+	// lines share containers, 2 packs of the first product and 3 of the second
+	order, err = service.CreateLineOrder(models.OrderRequest{Lines: []models.OrderLineRequest{
+		{ProductID: 1, ItemCount: 750},
+		{ProductID: 2, ItemCount: 300},
+	}}, testProducts())
+	if err != nil {
+		t.Fatalf("CreateLineOrder() unexpected error = %v", err)
+	}
+	if order.Shipment == nil || order.Shipment.TotalContainers != 3 {
+		t.Errorf("Shipment = %+v, want 3 containers", order.Shipment)
+	}
+
+	service.Containers = models.ContainerConfig{MaxWeight: 10}
+	if _, err := service.CreateOrder(models.OrderRequest{ItemCount: 750}, []models.PackDetails{{Size: 250, TareWeight: 30}}, 0); !errors.Is(err, ContainerCapacityError) {
+		t.Errorf("CreateOrder() error = %v, want %v", err, ContainerCapacityError)
+	}
+	if len(mockRepo.GetSavedOrders()) != 2 {
+		t.Errorf("saved %d orders, want 2", len(mockRepo.GetSavedOrders()))
+	}
+}
@@ -0,0 +1,173 @@
+package orders
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/irreal/order-packs/models"
+)
+
+const (
+	// most orders a single simulation replays, the oldest matching ones
+	MaxSimulatedOrders = 100_000
+	// how many of the most changed orders come with a simulation
+	SimulationExamples = 20
+)
+
+// Replays the requested item counts of past orders matching the query against a candidate pack set,
+// and compares what they would have shipped to what they did. Stock is ignored and nothing is saved.
+// query only filters, orders are always replayed oldest first. Orders with lines and backorders are left out,
+// neither was packed with the warehouse packs for its requested item count
+func (s *Service) Simulate(request models.SimulationRequest, query models.OrderQuery) (*models.Simulation, error) {
+	candidate := models.EnabledPacks(request.Packs)
+	if len(candidate) == 0 {
+		return nil, fmt.Errorf("%w: at least one enabled pack is needed", InvalidPackSetError)
+	}
+	packsBySize := make(map[models.Pack]models.PackDetails, len(candidate))
+	costs := make(map[models.Pack]int, len(candidate))
+	for _, pack := range candidate {
+		if err := pack.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %w", InvalidPackSetError, err)
+		}
+		packsBySize[pack.Size] = pack
+		costs[pack.Size] = pack.UnitCost
+	}
+
+	query.Sort = models.OrderSortOldest
+	query.Limit = MaxOrderPageSize
+	query.After = nil
+	query.SingleCountOnly = true
+	if err := validateOrderQuery(query); err != nil {
+		return nil, err
+	}
+
+	strategy, err := StrategyFor(request.Objective, costs)
+	if err != nil {
+		return nil, err
+	}
+	// not the cached solver, that one is for the live packs
+	solver, err := NewStrategySolver(models.PackSizes(candidate), strategy)
+	if err == nil && solver.PerOrder() {
+		// too slow to replay every order with
+		err = fmt.Errorf("%w: packs %v need a dp per order", PackSetTooLargeError, candidate)
+	}
+	if errors.Is(err, PackSetTooLargeError) {
+		return nil, fmt.Errorf("%w: %w", InvalidPackSetError, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", OrderCalculationError, err)
+	}
+
+	simulation := &models.Simulation{LargestChanges: []models.SimulatedOrder{}}
+	var changed []models.SimulatedOrder
+	for {
+		// one more than a page tells whether there is a next one
+		query.Limit = MaxOrderPageSize + 1
+		page, err := s.repo.ListOrders(query)
+		if err != nil {
+			return nil, err
+		}
+		more := len(page) > MaxOrderPageSize
+		page = page[:min(len(page), MaxOrderPageSize)]
+
+		for _, order := range page {
+			if simulation.Orders == MaxSimulatedOrders {
+				simulation.Truncated = true
+				break
+			}
+			replayed, err := replayOrder(solver, order, packsBySize)
+			if err != nil {
+				return nil, err
+			}
+			simulation.Orders++
+			addTotals(&simulation.Actual, replayed.Actual)
+			addTotals(&simulation.Simulated, replayed.Simulated)
+
+			switch compareTotals(replayed.Simulated, replayed.Actual) {
+			case -1:
+				simulation.Better++
+				changed = append(changed, *replayed)
+			case 1:
+				simulation.Worse++
+				changed = append(changed, *replayed)
+			default:
+				simulation.Unchanged++
+			}
+		}
+
+		if !more || simulation.Truncated {
+			break
+		}
+		query.After = cursorFor(query.Sort, page[len(page)-1])
+	}
+
+	// biggest change in overshoot first, then in packs, oldest first on a tie
+	slices.SortStableFunc(changed, func(a, b models.SimulatedOrder) int {
+		return cmp.Or(
+			cmp.Compare(overshootChange(b), overshootChange(a)),
+			cmp.Compare(packsChange(b), packsChange(a)),
+		)
+	})
+	simulation.LargestChanges = append(simulation.LargestChanges, changed[:min(len(changed), SimulationExamples)]...)
+	return simulation, nil
+}
+
+func replayOrder(solver *Solver, order *models.Order, packsBySize map[models.Pack]models.PackDetails) (*models.SimulatedOrder, error) {
+	calculation, err := solver.Solve(order.RequestedItemCount)
+	if err != nil {
+		return nil, fmt.Errorf("%w: order %d: %v", OrderCalculationError, order.ID, err)
+	}
+
+	replayed := &models.SimulatedOrder{
+		OrderID:            order.ID,
+		RequestedItemCount: order.RequestedItemCount,
+		ActualPacks:        order.Packs,
+		SimulatedPacks:     calculation.Packs,
+		Actual: models.SimulationTotals{
+			ShippedItems:  order.ShippedItemCount,
+			Overshoot:     order.ShippedItemCount - order.RequestedItemCount,
+			PackagingCost: order.PackagingCost,
+		},
+		Simulated: models.SimulationTotals{
+			ShippedItems: calculation.TotalItems,
+			Overshoot:    calculation.TotalItems - order.RequestedItemCount,
+			Packs:        calculation.TotalPacks,
+		},
+	}
+	for _, count := range order.Packs {
+		replayed.Actual.Packs += count
+	}
+	for pack, count := range calculation.Packs {
+		replayed.Simulated.PackagingCost += packsBySize[pack].UnitCost * count
+	}
+	return replayed, nil
+}
+
+func addTotals(sum *models.SimulationTotals, totals models.SimulationTotals) {
+	sum.ShippedItems += totals.ShippedItems
+	sum.Overshoot += totals.Overshoot
+	sum.Packs += totals.Packs
+	sum.PackagingCost += totals.PackagingCost
+}
+
+// fewer items first, then fewer packs, like the calculator's own rules
+func compareTotals(a, b models.SimulationTotals) int {
+	return cmp.Or(cmp.Compare(a.ShippedItems, b.ShippedItems), cmp.Compare(a.Packs, b.Packs))
+}
+
+func overshootChange(order models.SimulatedOrder) int {
+	return abs(order.Simulated.Overshoot - order.Actual.Overshoot)
+}
+
+func packsChange(order models.SimulatedOrder) int {
+	return abs(order.Simulated.Packs - order.Actual.Packs)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,175 @@
+package orders
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/irreal/order-packs/models"
+)
+
+func TestService_Simulate(t *testing.T) {
+	mockRepo := NewMockOrderRepository()
+	service := NewService(1000000000, mockRepo)
+
+	// shipped 250, 750, 500, 750 and 500 items
+	for _, count := range []int{1, 501, 251, 750, 251} {
+		if _, err := service.CreateOrder(models.OrderRequest{ItemCount: count}, models.Packs{250, 500}.Details(), 0); err != nil {
+			t.Fatalf("CreateOrder(%d) unexpected error = %v", count, err)
+		}
+	}
+	if _, err := service.UpdateOrderStatus("2", models.OrderStatusCancelled); err != nil {
+		t.Fatalf("UpdateOrderStatus() unexpected error = %v", err)
+	}
+
+	candidate := models.SimulationRequest{Packs: []models.PackDetails{{Size: 100, UnitCost: 10}, {Size: 500, UnitCost: 30}}}
+	simulation, err := service.Simulate(candidate, models.OrderQuery{})
+	if err != nil {
+		t.Fatalf("Simulate() unexpected error = %v", err)
+	}
+
+	if simulation.Orders != 5 || simulation.Better != 4 || simulation.Worse != 1 || simulation.Unchanged != 0 {
+		t.Errorf("Orders, Better, Worse, Unchanged = %d, %d, %d, %d, want 5, 4, 1, 0",
+			simulation.Orders, simulation.Better, simulation.Worse, simulation.Unchanged)
+	}
+	// 100, 500 + 100, 3x100, 500 + 3x100 and 3x100
+	wantSimulated := models.SimulationTotals{ShippedItems: 2100, Overshoot: 346, Packs: 13, PackagingCost: 170}
+	if simulation.Simulated != wantSimulated {
+		t.Errorf("Simulated = %+v, want %+v", simulation.Simulated, wantSimulated)
+	}
+	wantActual := models.SimulationTotals{ShippedItems: 2750, Overshoot: 996, Packs: 7}
+	if simulation.Actual != wantActual {
+		t.Errorf("Actual = %+v, want %+v", simulation.Actual, wantActual)
+	}
+
+	var ids []int64
+	for _, change := range simulation.LargestChanges {
+		ids = append(ids, change.OrderID)
+	}
+	if !slices.Equal(ids, []int64{3, 5, 1, 2, 4}) {
+		t.Errorf("LargestChanges = %v, want orders 3, 5, 1, 2, 4", ids)
+	}
+
+	// orders are filtered like order history
+	cancelled, err := service.Simulate(candidate, models.OrderQuery{Statuses: []models.OrderStatus{models.OrderStatusCancelled}})
+	if err != nil {
+		t.Fatalf("Simulate() unexpected error = %v", err)
+	}
+	if cancelled.Orders != 1 || cancelled.LargestChanges[0].OrderID != 2 {
+		t.Errorf("Simulate() of cancelled orders = %+v, want only order 2", cancelled)
+	}
+
+	// nothing was saved
+	if len(mockRepo.GetSavedOrders()) != 5 {
+		t.Errorf("Simulate() saved orders, there are %d now", len(mockRepo.GetSavedOrders()))
+	}
+}
+
+func TestService_Simulate_SkipsLinesAndBackorders(t *testing.T) {
+	mockRepo := NewMockOrderRepository()
+	service := NewService(1000000000, mockRepo)
+	service.Overshoot = models.OvershootLimit{MaxItems: limitOf(0)}
+
+	if _, err := service.CreateOrder(models.OrderRequest{ItemCount: 250}, models.Packs{250, 500}.Details(), 0); err != nil {
+		t.Fatalf("CreateOrder() unexpected error = %v", err)
+	}
+	// ships 500 now and backorders 1
+	backordered, err := service.CreateOrder(models.OrderRequest{ItemCount: 501, Fulfillment: models.FulfillmentBackorder}, models.Packs{250, 500}.Details(), 0)
+	if err != nil || backordered.Backorder == nil {
+		t.Fatalf("CreateOrder() = %+v, %v, want an order with a backorder", backordered, err)
+	}
+	lines := models.OrderRequest{Lines: []models.OrderLineRequest{{ProductID: 1, ItemCount: 500}, {ProductID: 2, ItemCount: 100}}}
+	if _, err := service.CreateLineOrder(lines, testProducts()); err != nil {
+		t.Fatalf("CreateLineOrder() unexpected error = %v", err)
+	}
+
+	simulation, err := service.Simulate(models.SimulationRequest{Packs: models.Packs{250, 500}.Details()}, models.OrderQuery{})
+	if err != nil {
+		t.Fatalf("Simulate() unexpected error = %v", err)
+	}
+	// the order that shipped part of its items is replayed for all of them, like it was asked for
+	if simulation.Orders != 2 || simulation.Better != 0 || simulation.Worse != 1 || simulation.Unchanged != 1 {
+		t.Errorf("Orders, Better, Worse, Unchanged = %d, %d, %d, %d, want 2, 0, 1, 1",
+			simulation.Orders, simulation.Better, simulation.Worse, simulation.Unchanged)
+	}
+	wantActual := models.SimulationTotals{ShippedItems: 750, Overshoot: -1, Packs: 2}
+	if simulation.Actual != wantActual {
+		t.Errorf("Actual = %+v, want %+v", simulation.Actual, wantActual)
+	}
+	for _, change := range simulation.LargestChanges {
+		if change.OrderID != backordered.ID {
+			t.Errorf("LargestChanges has order %d, want only order %d", change.OrderID, backordered.ID)
+		}
+	}
+}
+
+func TestService_Simulate_ManyPages(t *testing.T) {
+	mockRepo := NewMockOrderRepository()
+	service := NewService(1000, mockRepo)
+
+	orderCount := 2*MaxOrderPageSize + 30
+	for range orderCount {
+		if _, err := service.CreateOrder(models.OrderRequest{ItemCount: 1}, models.Packs{250}.Details(), 0); err != nil {
+			t.Fatalf("CreateOrder() unexpected error = %v", err)
+		}
+	}
+
+	simulation, err := service.Simulate(models.SimulationRequest{Packs: models.Packs{250}.Details()}, models.OrderQuery{})
+	if err != nil {
+		t.Fatalf("Simulate() unexpected error = %v", err)
+	}
+	if simulation.Orders != orderCount || simulation.Unchanged != orderCount || simulation.Truncated {
+		t.Errorf("Orders, Unchanged, Truncated = %d, %d, %v, want %d, %d, false",
+			simulation.Orders, simulation.Unchanged, simulation.Truncated, orderCount, orderCount)
+	}
+	if len(simulation.LargestChanges) != 0 {
+		t.Errorf("LargestChanges = %+v, want none", simulation.LargestChanges)
+	}
+}
+
+func TestService_Simulate_InvalidRequest(t *testing.T) {
+	service := NewService(1000, NewMockOrderRepository())
+
+	tests := []struct {
+		name          string
+		request       models.SimulationRequest
+		query         models.OrderQuery
+		expectedError error
+	}{
+		{
+			name:          "no packs",
+			request:       models.SimulationRequest{},
+			expectedError: InvalidPackSetError,
+		},
+		{
+			name:          "only disabled packs",
+			request:       models.SimulationRequest{Packs: []models.PackDetails{{Size: 250, Disabled: true}}},
+			expectedError: InvalidPackSetError,
+		},
+		{
+			name:          "invalid pack",
+			request:       models.SimulationRequest{Packs: []models.PackDetails{{Size: 250, UnitCost: -1}}},
+			expectedError: InvalidPackSetError,
+		},
+		{
+			name:          "unknown objective",
+			request:       models.SimulationRequest{Packs: models.Packs{250}.Details(), Objective: "most-fun"},
+			expectedError: InvalidObjectiveError,
+		},
+		{
+			name:          "invalid query",
+			request:       models.SimulationRequest{Packs: models.Packs{250}.Details()},
+			query:         models.OrderQuery{MinRequested: 10, MaxRequested: 5},
+			expectedError: InvalidOrderQueryError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Simulate(tt.request, tt.query)
+			if !errors.Is(err, tt.expectedError) {
+				t.Errorf("Simulate() error = %v, want %v", err, tt.expectedError)
+			}
+		})
+	}
+}
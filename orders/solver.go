@@ -0,0 +1,439 @@
+package orders
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/irreal/order-packs/models"
+)
+
+// Solver holds everything about a pack set that does not depend on the requested count,
+// so it can be built once per pack set and then answer any number of orders with lookups.
+// A Solver is read only after construction and safe for concurrent use.
+//
+// Whatever the strategy, the solver minimizes the total cost of the packs (as priced by the strategy) first,
+// then the items sent, then the number of packs. The default strategy prices every pack at 0, which gives rules 2 and 3.
+//
+// The idea: let B be the base pack, the one with the lowest cost per item (the largest one on a tie).
+// Any combination is some "other" packs plus a number of B packs. Swapping other packs for B packs never hurts
+// once the order is big enough, so for every residue r (mod B) we only need to know the best set of other packs reaching that residue.
+// For other packs with sum s, count c and cost k that is the lowest (k*B - s*cost(B), c*B - s) - the extra cost and packs
+// they bring compared to filling the same items with B packs. That is a shortest path problem over B residues.
+//
+// Past the largest sum of those best combinations (bound) every order is answered straight from the residue tables,
+// below it we keep a plain dynamic programming table, which is then also bounded by the pack sizes.
+// The bound can get close to the product of two pack sizes, e.g. 4999 and 5000, so when that table would hold
+// more than MaxSolverTableSize totals it isn't built, and orders below the bound get a dp of their own instead,
+// bounded by the order plus a largest pack, see PerOrder.
+type Solver struct {
+	strategy Strategy
+
+	// sorted ascending, without duplicates, with their cost according to the strategy
+	packs   []models.Pack
+	costs   []int
+	largest int
+
+	// the pack with the lowest cost per item, residues are taken modulo its size
+	base      int
+	baseCost  int
+	baseIndex int
+
+	// per residue of the base pack, the best combination of other packs reaching it
+	residueCost   []int
+	residueWeight []int
+	residueSum    []int
+	residuePack   []models.Pack
+
+	// only when every pack is free. distance from a residue to the closest reachable residue at or after it
+	residueOffset []int
+
+	// orders above this are solved using the residue tables only
+	bound int
+
+	// dp table for every total up to bound + largest, nil when it would be too big
+	minCost   []int
+	minPacks  []int
+	lastPack  []models.Pack
+	bestTotal []int
+
+	// orders above this plus a base pack set aside base packs before looking for alternatives, built on first use
+	alternativesOnce   sync.Once
+	alternativesWindow int
+}
+
+// most totals the tables of a solver may hold, the small order table takes 32 bytes per total
+const MaxSolverTableSize = 2_000_000
+
+// Solver for the default strategy, see CalculatePack
+func NewSolver(availablePacks []models.Pack) (*Solver, error) {
+	return NewStrategySolver(availablePacks, LeastItemsStrategy{})
+}
+
+// fails with PackSetTooLargeError when a pack is larger than MaxSolverTableSize, see Solver
+func NewStrategySolver(availablePacks []models.Pack, strategy Strategy) (*Solver, error) {
+	s, err := newResidueSolver(availablePacks, strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.bound+s.largest <= MaxSolverTableSize {
+		s.buildSmallOrderTable()
+	}
+	return s, nil
+}
+
+// Fails with PackSetTooLargeError when the enabled packs can't be solved with for any of the objectives,
+// costs are taken from the packs. Nothing is checked without enabled packs, there is nothing to solve
+func ValidatePackSetSize(packs []models.PackDetails) error {
+	enabled := models.EnabledPacks(packs)
+	if len(enabled) == 0 {
+		return nil
+	}
+	costs := make(map[models.Pack]int, len(enabled))
+	for _, pack := range enabled {
+		costs[pack.Size] = pack.UnitCost
+	}
+
+	for _, objective := range []models.Objective{models.ObjectiveLeastItems, models.ObjectiveFewestPacks, models.ObjectiveLowestCost} {
+		strategy, err := StrategyFor(objective, costs)
+		if err != nil {
+			return err
+		}
+		// the residue tables tell how big the rest would get, without building it
+		if _, err := newResidueSolver(models.PackSizes(enabled), strategy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// a solver with everything but the small order table
+func newResidueSolver(availablePacks []models.Pack, strategy Strategy) (*Solver, error) {
+	if len(availablePacks) == 0 {
+		return nil, fmt.Errorf("no packs available to fulfill the order")
+	}
+
+	packs := make([]models.Pack, 0, len(availablePacks))
+	for _, pack := range availablePacks {
+		if pack <= 0 {
+			return nil, fmt.Errorf("pack size must be greater than 0")
+		}
+		packs = append(packs, pack)
+	}
+	slices.Sort(packs)
+	packs = slices.Compact(packs)
+
+	s := &Solver{
+		strategy: strategy,
+		packs:    packs,
+		costs:    make([]int, len(packs)),
+		largest:  int(packs[len(packs)-1]),
+	}
+	if s.largest > MaxSolverTableSize {
+		return nil, fmt.Errorf("%w: pack %d is larger than %d", PackSetTooLargeError, s.largest, MaxSolverTableSize)
+	}
+
+	// pick the base pack, lowest cost per item, the largest one on a tie
+	baseIndex := -1
+	for i, pack := range packs {
+		cost, err := strategy.PackCost(pack)
+		if err != nil {
+			return nil, err
+		}
+		if cost < 0 {
+			return nil, fmt.Errorf("cost of pack %d must not be negative", pack)
+		}
+		s.costs[i] = cost
+
+		// cost/pack <= baseCost/base, compared without division
+		if baseIndex == -1 || cost*s.base <= s.baseCost*int(pack) {
+			baseIndex = i
+			s.baseIndex = i
+			s.base = int(pack)
+			s.baseCost = cost
+		}
+	}
+
+	s.buildResidueTables()
+	return s, nil
+}
+
+// the strategy the solver was built for
+func (s *Solver) Strategy() Strategy {
+	return s.strategy
+}
+
+// pack sizes the solver was built for, sorted ascending
+func (s *Solver) Packs() models.Packs {
+	return slices.Clone(s.packs)
+}
+
+// true if the solver was built for the same pack sizes, regardless of order and duplicates,
+// and the strategy still prices them the same
+func (s *Solver) Matches(availablePacks []models.Pack, strategy Strategy) bool {
+	if strategy.Objective() != s.strategy.Objective() {
+		return false
+	}
+
+	packs := slices.Clone(availablePacks)
+	slices.Sort(packs)
+	if !slices.Equal(slices.Compact(packs), s.packs) {
+		return false
+	}
+
+	for i, pack := range s.packs {
+		cost, err := strategy.PackCost(pack)
+		if err != nil || cost != s.costs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// true when the pack set was too big for the small order table, orders below the bound then run a dp of their own.
+// Those fail with InvalidOrderItemCountError when even that gets too big, and are too slow to solve many orders with
+func (s *Solver) PerOrder() bool {
+	return s.minCost == nil
+}
+
+// Solves an order according to the solver's strategy. Time depends on the pack sizes only, not on requestedCount,
+// unless the solver is PerOrder and the order is below the bound.
+func (s *Solver) Solve(requestedCount int) (*PackingCalculation, error) {
+	if requestedCount <= 0 {
+		return nil, fmt.Errorf("requested count must be greater than 0")
+	}
+
+	if requestedCount > s.bound {
+		return s.solveLarge(requestedCount), nil
+	}
+	if s.PerOrder() {
+		// no stock limits, so this is a plain dp up to a largest pack over the order
+		return s.solveBounded(requestedCount, math.MaxInt, nil)
+	}
+	return s.solveSmall(requestedCount), nil
+}
+
+// small orders, straight from the dp table
+func (s *Solver) solveSmall(requestedCount int) *PackingCalculation {
+	return s.packSmall(s.bestTotal[requestedCount])
+}
+
+// the best packs for exactly total items, total has to be reachable and inside the dp table
+func (s *Solver) packSmall(optimalCount int) *PackingCalculation {
+	// reconstruct the packs relying on the memory of packs used for computed sub-amounts
+	finalPacks := make(map[models.Pack]int)
+	for remainingCount := optimalCount; remainingCount > 0; remainingCount -= int(s.lastPack[remainingCount]) {
+		finalPacks[s.lastPack[remainingCount]]++
+	}
+
+	return &PackingCalculation{
+		Packs:      finalPacks,
+		TotalItems: optimalCount,
+		TotalPacks: s.minPacks[optimalCount],
+	}
+}
+
+// large orders, the best other packs for a residue topped up with the base pack
+func (s *Solver) solveLarge(requestedCount int) *PackingCalculation {
+	var optimalCount int
+	if s.residueOffset != nil {
+		// everything is free, so the first reachable total wins
+		optimalCount = requestedCount + s.residueOffset[requestedCount%s.base]
+	} else {
+		// every residue that is reachable at all is reachable past the bound, pick the cheapest total.
+		// costs are compared multiplied by the base size, which keeps them whole numbers
+		bestCost := math.MaxInt
+		for offset := range s.base {
+			residue := (requestedCount + offset) % s.base
+			if s.residueCost[residue] == math.MaxInt {
+				continue
+			}
+			cost := s.residueCost[residue] + s.baseCost*(requestedCount+offset)
+			if cost < bestCost {
+				bestCost = cost
+				optimalCount = requestedCount + offset
+			}
+		}
+	}
+
+	return s.packLarge(optimalCount)
+}
+
+// the best packs for exactly total items, total has to be reachable from the residue tables, see exactCost
+func (s *Solver) packLarge(optimalCount int) *PackingCalculation {
+	finalPacks := make(map[models.Pack]int)
+	totalPacks := 0
+
+	residue := optimalCount % s.base
+	for residue != 0 {
+		pack := s.residuePack[residue]
+		finalPacks[pack]++
+		totalPacks++
+		residue = ((residue-int(pack))%s.base + s.base) % s.base
+	}
+
+	baseCount := (optimalCount - s.residueSum[optimalCount%s.base]) / s.base
+	if baseCount > 0 {
+		finalPacks[models.Pack(s.base)] += baseCount
+		totalPacks += baseCount
+	}
+
+	return &PackingCalculation{
+		Packs:      finalPacks,
+		TotalItems: optimalCount,
+		TotalPacks: totalPacks,
+	}
+}
+
+// dijkstra over residues of the base pack, starting from an empty combination
+func (s *Solver) buildResidueTables() {
+	s.residueCost = make([]int, s.base)
+	s.residueWeight = make([]int, s.base)
+	s.residueSum = make([]int, s.base)
+	s.residuePack = make([]models.Pack, s.base)
+	for r := range s.base {
+		s.residueCost[r] = math.MaxInt
+		s.residueWeight[r] = math.MaxInt
+		s.residueSum[r] = math.MaxInt
+	}
+
+	s.residueCost[0] = 0
+	s.residueWeight[0] = 0
+	s.residueSum[0] = 0
+	queue := &residueQueue{{}}
+	for queue.Len() > 0 {
+		current := heap.Pop(queue).(residueState)
+
+		// stale entry, residue was already settled with a better combination
+		if current != s.residueState(current.residue) {
+			continue
+		}
+
+		for i, pack := range s.packs {
+			if int(pack) == s.base {
+				continue
+			}
+
+			// a pack with the same cost per item as the base is always smaller than it, so every step is a strict improvement
+			next := residueState{
+				residue: (current.residue + int(pack)) % s.base,
+				cost:    current.cost + s.costs[i]*s.base - s.baseCost*int(pack),
+				weight:  current.weight + s.base - int(pack),
+				sum:     current.sum + int(pack),
+			}
+			if next.better(s.residueState(next.residue)) {
+				s.residueCost[next.residue] = next.cost
+				s.residueWeight[next.residue] = next.weight
+				s.residueSum[next.residue] = next.sum
+				s.residuePack[next.residue] = pack
+				heap.Push(queue, next)
+			}
+		}
+	}
+
+	for r := range s.base {
+		if s.residueSum[r] != math.MaxInt && s.residueSum[r] > s.bound {
+			s.bound = s.residueSum[r]
+		}
+	}
+
+	if slices.ContainsFunc(s.costs, func(cost int) bool { return cost != 0 }) {
+		return
+	}
+
+	// walk the residues backwards twice to wrap around, residue 0 is always reachable
+	s.residueOffset = make([]int, s.base)
+	offset := 0
+	for i := 2*s.base - 1; i >= 0; i-- {
+		r := i % s.base
+		if s.residueCost[r] != math.MaxInt {
+			offset = 0
+		} else {
+			offset++
+		}
+		s.residueOffset[r] = offset
+	}
+}
+
+// plain dp for totals up to bound + largest. dropping any pack from a bigger total only makes it better,
+// so the optimal total for an order is always less than a largest pack above it
+func (s *Solver) buildSmallOrderTable() {
+	maxSize := s.bound + s.largest
+	s.minCost = make([]int, maxSize+1)
+	s.minPacks = make([]int, maxSize+1)
+	s.lastPack = make([]models.Pack, maxSize+1)
+	s.bestTotal = make([]int, maxSize+1)
+
+	for i := 1; i <= maxSize; i++ {
+		s.minCost[i] = math.MaxInt
+		s.minPacks[i] = math.MaxInt
+		for j, pack := range s.packs {
+			if i < int(pack) || s.minCost[i-int(pack)] == math.MaxInt {
+				continue
+			}
+			cost := s.minCost[i-int(pack)] + s.costs[j]
+			packs := s.minPacks[i-int(pack)] + 1
+			if cost < s.minCost[i] || (cost == s.minCost[i] && packs < s.minPacks[i]) {
+				s.minCost[i] = cost
+				s.minPacks[i] = packs
+				s.lastPack[i] = pack
+			}
+		}
+	}
+
+	// best total at or after each count, cheapest first, then fewest items.
+	// the largest pack itself is always reachable, so the last entry is too
+	best := maxSize
+	for i := maxSize; i >= 0; i-- {
+		if s.minCost[i] != math.MaxInt && s.minCost[i] <= s.minCost[best] {
+			best = i
+		}
+		s.bestTotal[i] = best
+	}
+}
+
+func (s *Solver) residueState(residue int) residueState {
+	return residueState{
+		residue: residue,
+		cost:    s.residueCost[residue],
+		weight:  s.residueWeight[residue],
+		sum:     s.residueSum[residue],
+	}
+}
+
+// a combination of other packs reaching a residue of the base pack
+type residueState struct {
+	residue int
+	cost    int
+	weight  int
+	sum     int
+}
+
+// lower cost wins, then lower weight (fewer packs), then the smaller sum keeps the bound low
+func (r residueState) better(other residueState) bool {
+	if r.cost != other.cost {
+		return r.cost < other.cost
+	}
+	if r.weight != other.weight {
+		return r.weight < other.weight
+	}
+	return r.sum < other.sum
+}
+
+// min heap for the residue dijkstra
+type residueQueue []residueState
+
+func (q residueQueue) Len() int           { return len(q) }
+func (q residueQueue) Less(i, j int) bool { return q[i].better(q[j]) }
+func (q residueQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *residueQueue) Push(x any)        { *q = append(*q, x.(residueState)) }
+func (q *residueQueue) Pop() any {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[:n-1]
+	return item
+}
@@ -0,0 +1,251 @@
+package orders
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/irreal/order-packs/models"
+)
+
+// the solver has to agree with the plain dynamic programming solution on rules 2 and 3
+func TestSolver_MatchesDynamicProgramming(t *testing.T) {
+	packSets := [][]models.Pack{
+		{250, 500, 1000, 2000, 5000},
+		{23, 31, 53},
+		{6, 9, 20},
+		{4, 10},
+		{7},
+		{3, 5, 5, 8},
+	}
+
+	for _, packs := range packSets {
+		solver, err := NewSolver(packs)
+		if err != nil {
+			t.Fatalf("NewSolver(%v) error = %v", packs, err)
+		}
+
+		// cover both sides of the bound where the solver switches strategy
+		for requestedCount := 1; requestedCount <= solver.bound+3*solver.largest; requestedCount += 3 {
+			result, err := solver.Solve(requestedCount)
+			if err != nil {
+				t.Fatalf("Solve(%v, %d) error = %v", packs, requestedCount, err)
+			}
+
+			expected := referenceCalculatePack(solver.packs, requestedCount)
+			if result.TotalItems != expected.TotalItems || result.TotalPacks != expected.TotalPacks {
+				t.Fatalf("Solve(%v, %d) = %d items in %d packs, want %d items in %d packs",
+					packs, requestedCount, result.TotalItems, result.TotalPacks, expected.TotalItems, expected.TotalPacks)
+			}
+
+			// packs add up to the totals
+			totalItems, totalPacks := 0, 0
+			for pack, count := range result.Packs {
+				totalItems += int(pack) * count
+				totalPacks += count
+			}
+			if totalItems != result.TotalItems || totalPacks != result.TotalPacks {
+				t.Fatalf("Solve(%v, %d) packs %v do not add up to %d items in %d packs",
+					packs, requestedCount, result.Packs, result.TotalItems, result.TotalPacks)
+			}
+		}
+	}
+}
+
+func TestSolver_Matches(t *testing.T) {
+	solver, err := NewSolver([]models.Pack{500, 250, 1000})
+	if err != nil {
+		t.Fatalf("NewSolver() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		packs    []models.Pack
+		expected bool
+	}{
+		{name: "same order", packs: []models.Pack{250, 500, 1000}, expected: true},
+		{name: "different order", packs: []models.Pack{1000, 500, 250}, expected: true},
+		{name: "duplicates", packs: []models.Pack{250, 250, 500, 1000}, expected: true},
+		{name: "missing pack", packs: []models.Pack{250, 500}, expected: false},
+		{name: "extra pack", packs: []models.Pack{250, 500, 1000, 2000}, expected: false},
+		{name: "no packs", packs: nil, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := solver.Matches(tt.packs, LeastItemsStrategy{}); got != tt.expected {
+				t.Errorf("Matches(%v) = %v, want %v", tt.packs, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSolver_ErrorCases(t *testing.T) {
+	if _, err := NewSolver(nil); err == nil {
+		t.Error("NewSolver(nil) expected error")
+	}
+	if _, err := NewSolver([]models.Pack{250, 0}); err == nil {
+		t.Error("NewSolver() with zero pack size expected error")
+	}
+
+	solver, err := NewSolver([]models.Pack{250})
+	if err != nil {
+		t.Fatalf("NewSolver() error = %v", err)
+	}
+	if _, err := solver.Solve(0); err == nil {
+		t.Error("Solve(0) expected error")
+	}
+}
+
+// packs larger than the table are refused before building anything
+func TestSolver_PackSetTooLarge(t *testing.T) {
+	packs := []models.Pack{MaxSolverTableSize + 1}
+	if _, err := NewSolver(packs); !errors.Is(err, PackSetTooLargeError) {
+		t.Errorf("NewSolver(%v) error = %v, want %v", packs, err, PackSetTooLargeError)
+	}
+	if err := ValidatePackSetSize(models.Packs(packs).Details()); !errors.Is(err, PackSetTooLargeError) {
+		t.Errorf("ValidatePackSetSize(%v) error = %v, want %v", packs, err, PackSetTooLargeError)
+	}
+
+	// only enabled packs are solved with
+	disabled := []models.PackDetails{{Size: MaxSolverTableSize + 1, Disabled: true}, {Size: 5000}}
+	if err := ValidatePackSetSize(disabled); err != nil {
+		t.Errorf("ValidatePackSetSize() with the large size disabled error = %v", err)
+	}
+}
+
+// two big sizes without a common factor need a table close to their product,
+// orders below the bound are solved with a dp of their own instead
+func TestSolver_PerOrder(t *testing.T) {
+	packs := []models.Pack{1999, 2000}
+	if err := ValidatePackSetSize(models.Packs(packs).Details()); err != nil {
+		t.Fatalf("ValidatePackSetSize(%v) error = %v", packs, err)
+	}
+	solver, err := NewSolver(packs)
+	if err != nil {
+		t.Fatalf("NewSolver(%v) error = %v", packs, err)
+	}
+	if !solver.PerOrder() {
+		t.Fatalf("PerOrder() = false, want the small order table to be left out for %v", packs)
+	}
+
+	for _, requestedCount := range []int{1, 1999, 2001, 3998, 1_234_567, solver.bound, solver.bound + 1, solver.bound + 4321} {
+		result, err := solver.Solve(requestedCount)
+		if err != nil {
+			t.Fatalf("Solve(%d) error = %v", requestedCount, err)
+		}
+		expected := referenceCalculatePack(packs, requestedCount)
+		if result.TotalItems != expected.TotalItems || result.TotalPacks != expected.TotalPacks {
+			t.Errorf("Solve(%d) = %d items in %d packs, want %d items in %d packs",
+				requestedCount, result.TotalItems, result.TotalPacks, expected.TotalItems, expected.TotalPacks)
+		}
+	}
+
+	// the other ways of solving an order don't need the table either
+	under, err := solver.SolveUnder(5000, nil)
+	if err != nil || under.TotalItems != 4000 {
+		t.Errorf("SolveUnder(5000) = %+v, %v, want 4000 items", under, err)
+	}
+	within, err := solver.SolveWithin(2001, 3999, map[models.Pack]int{1999: 1})
+	if err != nil || within == nil || within.TotalItems != 3999 {
+		t.Errorf("SolveWithin(2001) = %+v, %v, want 3999 items", within, err)
+	}
+	if _, err := solver.Explain(2001, &PackingCalculation{Packs: map[models.Pack]int{2000: 2}, TotalItems: 4000, TotalPacks: 2}, nil); err != nil {
+		t.Errorf("Explain(2001) error = %v", err)
+	}
+}
+
+func TestSolver_ConcurrentUse(t *testing.T) {
+	solver, err := NewSolver([]models.Pack{250, 500, 1000, 2000, 5000})
+	if err != nil {
+		t.Fatalf("NewSolver() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := solver.Solve(12001 + i)
+			if err != nil {
+				t.Errorf("Solve() error = %v", err)
+				return
+			}
+			if result.TotalItems != 12250 || result.TotalPacks != 4 {
+				t.Errorf("Solve(%d) = %d items in %d packs, want 12250 items in 4 packs", 12001+i, result.TotalItems, result.TotalPacks)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// builds the solver on every call, like a request without a cache would
+func BenchmarkCalculatePack_Concurrent(b *testing.B) {
+	packs := []models.Pack{250, 500, 1000, 2000, 5000}
+	b.RunParallel(func(pb *testing.PB) {
+		requestedCount := 1
+		for pb.Next() {
+			if _, err := CalculatePack(packs, requestedCount); err != nil {
+				b.Fatal(err)
+			}
+			requestedCount = requestedCount%1000000 + 7919
+		}
+	})
+}
+
+// a solver built once for the pack set, answering by lookup
+func BenchmarkSolver_Concurrent(b *testing.B) {
+	solver, err := NewSolver([]models.Pack{250, 500, 1000, 2000, 5000})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		requestedCount := 1
+		for pb.Next() {
+			if _, err := solver.Solve(requestedCount); err != nil {
+				b.Fatal(err)
+			}
+			requestedCount = requestedCount%1000000 + 7919
+		}
+	})
+}
+
+// the original dynamic programming solution, memory and time grow with requestedCount.
+// kept as a reference to check the solver against. expects packs to be sorted ascending
+func referenceCalculatePack(availablePacks []models.Pack, requestedCount int) *PackingCalculation {
+	maxPackSize := int(availablePacks[len(availablePacks)-1])
+
+	maxSize := requestedCount + maxPackSize
+	minItems := make([]int, maxSize+1)
+	minPacks := make([]int, maxSize+1)
+
+	for i := 1; i <= maxSize; i++ {
+		minItems[i] = math.MaxInt32
+		minPacks[i] = math.MaxInt32
+	}
+
+	for i := 1; i <= maxSize; i++ {
+		for _, packSize := range availablePacks {
+			if i >= int(packSize) && minItems[i-int(packSize)] != math.MaxInt32 {
+				newTotalItems := minItems[i-int(packSize)] + int(packSize)
+				newTotalPacks := minPacks[i-int(packSize)] + 1
+
+				if newTotalItems < minItems[i] {
+					minItems[i] = newTotalItems
+					minPacks[i] = newTotalPacks
+				} else if newTotalItems == minItems[i] && newTotalPacks < minPacks[i] {
+					minPacks[i] = newTotalPacks
+				}
+			}
+		}
+	}
+
+	for i := requestedCount; i <= maxSize; i++ {
+		if minItems[i] != math.MaxInt32 {
+			return &PackingCalculation{TotalItems: minItems[i], TotalPacks: minPacks[i]}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package orders
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/irreal/order-packs/models"
+)
+
+// statuses an order can move to from each status. shipped and cancelled orders are done
+var statusTransitions = map[models.OrderStatus][]models.OrderStatus{
+	models.OrderStatusNew:       {models.OrderStatusPending, models.OrderStatusCancelled},
+	models.OrderStatusPending:   {models.OrderStatusPacked, models.OrderStatusCancelled},
+	models.OrderStatusPacked:    {models.OrderStatusShipped, models.OrderStatusCancelled},
+	models.OrderStatusShipped:   {},
+	models.OrderStatusCancelled: {},
+	// a backorder has no packs to go through packing, it can only be called off
+	models.OrderStatusBackordered: {models.OrderStatusCancelled},
+	// only a review moves it on, see ApproveOrder and RejectOrder
+	models.OrderStatusNeedsApproval: {models.OrderStatusCancelled},
+	models.OrderStatusRejected:      {},
+}
+
+// Returned when an order can't move from its current status to the requested one
+type StatusTransitionError struct {
+	From models.OrderStatus
+	To   models.OrderStatus
+}
+
+func (e *StatusTransitionError) Error() string {
+	return fmt.Sprintf("order can't go from %s to %s", e.From, e.To)
+}
+
+// statuses an order in this status can move to, empty once it's done
+func NextStatuses(status models.OrderStatus) []models.OrderStatus {
+	return slices.Clone(statusTransitions[status])
+}
+
+func validateStatusTransition(from, to models.OrderStatus) error {
+	if _, known := statusTransitions[to]; !known {
+		return fmt.Errorf("%w: %q", InvalidStatusError, to)
+	}
+	if !slices.Contains(statusTransitions[from], to) {
+		return &StatusTransitionError{From: from, To: to}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package orders
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/irreal/order-packs/models"
+)
+
+func TestValidateStatusTransition(t *testing.T) {
+	tests := []struct {
+		from    models.OrderStatus
+		to      models.OrderStatus
+		allowed bool
+	}{
+		{models.OrderStatusNew, models.OrderStatusPending, true},
+		{models.OrderStatusPending, models.OrderStatusPacked, true},
+		{models.OrderStatusPacked, models.OrderStatusShipped, true},
+		{models.OrderStatusNew, models.OrderStatusCancelled, true},
+		{models.OrderStatusPending, models.OrderStatusCancelled, true},
+		{models.OrderStatusPacked, models.OrderStatusCancelled, true},
+		{models.OrderStatusNew, models.OrderStatusShipped, false},
+		{models.OrderStatusNew, models.OrderStatusNew, false},
+		{models.OrderStatusPacked, models.OrderStatusPending, false},
+		{models.OrderStatusShipped, models.OrderStatusCancelled, false},
+		{models.OrderStatusCancelled, models.OrderStatusNew, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+" to "+string(tt.to), func(t *testing.T) {
+			err := validateStatusTransition(tt.from, tt.to)
+			if tt.allowed {
+				if err != nil {
+					t.Errorf("validateStatusTransition() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var transitionErr *StatusTransitionError
+			if !errors.As(err, &transitionErr) {
+				t.Fatalf("validateStatusTransition() error = %v, want a StatusTransitionError", err)
+			}
+			if transitionErr.From != tt.from || transitionErr.To != tt.to {
+				t.Errorf("StatusTransitionError = %+v, want from %s to %s", transitionErr, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestValidateStatusTransition_UnknownStatus(t *testing.T) {
+	err := validateStatusTransition(models.OrderStatusNew, "lost")
+	if !errors.Is(err, InvalidStatusError) {
+		t.Errorf("validateStatusTransition() error = %v, want %v", err, InvalidStatusError)
+	}
+}
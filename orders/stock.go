@@ -0,0 +1,265 @@
+package orders
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/irreal/order-packs/models"
+)
+
+// the stock constrained dp keeps a row per pack size over every total, this caps its memory
+const maxStockTableCells = 20_000_000
+
+// Solves an order using at most stock[pack] packs of each size. Sizes missing from stock are unlimited.
+// When the best combination can't be built from stock, the next best one according to the solver's strategy is used.
+func (s *Solver) SolveWithStock(requestedCount int, stock map[models.Pack]int) (*PackingCalculation, error) {
+	calculation, err := s.Solve(requestedCount)
+	if err != nil {
+		return nil, err
+	}
+
+	// the unconstrained answer is in stock, nothing can beat it
+	if fitsStock(calculation, stock) {
+		return calculation, nil
+	}
+
+	return s.solveBounded(requestedCount, math.MaxInt, stock)
+}
+
+func fitsStock(calculation *PackingCalculation, stock map[models.Pack]int) bool {
+	for pack, count := range calculation.Packs {
+		if available, limited := stock[pack]; limited && count > available {
+			return false
+		}
+	}
+	return true
+}
+
+type boundedPack struct {
+	size  int
+	cost  int
+	limit int
+}
+
+// stock limits of the solver's packs, sizes out of stock are left out. stockItems only counts the limited sizes
+func (s *Solver) boundedPacks(stock map[models.Pack]int) (packs []boundedPack, stockItems int, unlimited bool) {
+	for i, pack := range s.packs {
+		limit, limited := stock[pack]
+		if limited && limit <= 0 {
+			continue
+		}
+		if !limited {
+			unlimited = true
+			limit = -1
+		} else {
+			stockItems += limit * int(pack)
+		}
+		packs = append(packs, boundedPack{size: int(pack), cost: s.costs[i], limit: limit})
+	}
+	return packs, stockItems, unlimited
+}
+
+// Bounded knapsack, one pack size at a time. The dp is over every total, so big orders set aside the packs
+// every best packing has most of first, see stockPivot, and the dp only runs on what's left.
+// It only runs when stock actually gets in the way. Totals over maxTotal don't count
+func (s *Solver) solveBounded(requestedCount, maxTotal int, stock map[models.Pack]int) (*PackingCalculation, error) {
+	packs, stockItems, unlimited := s.boundedPacks(stock)
+	if len(packs) == 0 || (!unlimited && stockItems < requestedCount) {
+		return nil, fmt.Errorf("%w for %d items", InsufficientStockError, requestedCount)
+	}
+
+	// dropping a pack from a bigger total only makes it better, so the best total is less than a largest pack above the order
+	largest := packs[len(packs)-1].size
+	pivot, setAside := -1, 0
+	if index, window := stockPivot(packs, requestedCount+largest-1); index >= 0 && requestedCount > window {
+		// at least one item is left for the dp
+		pivot, setAside = index, (requestedCount-window-1)/packs[index].size
+	}
+	packs, remainingCount := setAsidePacks(packs, pivot, setAside, requestedCount)
+
+	maxSize := min(remainingCount+largest-1, maxTotal-(requestedCount-remainingCount))
+	if !unlimited {
+		maxSize = min(maxSize, stockItems-(requestedCount-remainingCount))
+	}
+	table, err := newBoundedTable(packs, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	minCost := table.minCost
+
+	// cheapest total that covers the order, the least items on a tie
+	optimalCount := -1
+	for t := remainingCount; t <= maxSize; t++ {
+		if minCost[t] != math.MaxInt && (optimalCount == -1 || minCost[t] < minCost[optimalCount]) {
+			optimalCount = t
+		}
+	}
+
+	if optimalCount == -1 {
+		return nil, fmt.Errorf("%w: no combination of packs in stock covers %d items", InsufficientStockError, requestedCount)
+	}
+
+	return withSetAside(table.packing(optimalCount), packs, pivot, setAside), nil
+}
+
+// Big orders are mostly made of one pack size P, the one with the lowest cost per item (the largest on a tie)
+// that has stock for needed items on its own. Sizes before it are cheaper per item but can't cover the order, so they are limited.
+// A group of sizes after it whose sum is a multiple of P can be swapped for P packs without making a packing worse,
+// and any g of them have such a group, g being P over the greatest common divisor of P and those sizes (the same argument as for the residues).
+// So a best packing can always have fewer than g packs of sizes after P and at most the stock of the ones before it, everything else is P packs.
+// Returns the index of P and how many items the other packs can add up to at most, -1 when no size has enough stock
+func stockPivot(packs []boundedPack, needed int) (pivot int, window int) {
+	pivot = -1
+	for i, pack := range packs {
+		if pack.limit >= 0 && pack.limit*pack.size < needed {
+			continue
+		}
+		if pivot == -1 || cheaperPerItem(pack, packs[pivot]) {
+			pivot = i
+		}
+	}
+	if pivot == -1 {
+		return -1, 0
+	}
+
+	divisor, largestAfter := packs[pivot].size, 0
+	for i, pack := range packs {
+		switch {
+		case i == pivot:
+		case cheaperPerItem(pack, packs[pivot]):
+			window += pack.limit * pack.size
+		default:
+			divisor = gcd(divisor, pack.size)
+			largestAfter = max(largestAfter, pack.size)
+		}
+	}
+	window += (packs[pivot].size/divisor - 1) * largestAfter
+	return pivot, window
+}
+
+// lower cost per item, or the same and larger, the order the solver picks its base pack in
+func cheaperPerItem(a, b boundedPack) bool {
+	if a.cost*b.size != b.cost*a.size {
+		return a.cost*b.size < b.cost*a.size
+	}
+	return a.size > b.size
+}
+
+// the packs with count of the pivot taken out of stock, and what's left of the order. packs is not modified
+func setAsidePacks(packs []boundedPack, pivot, count, requestedCount int) ([]boundedPack, int) {
+	if count == 0 {
+		return packs, requestedCount
+	}
+	packs = slices.Clone(packs)
+	if packs[pivot].limit >= 0 {
+		packs[pivot].limit -= count
+	}
+	return packs, requestedCount - count*packs[pivot].size
+}
+
+// puts the packs set aside by setAsidePacks back in
+func withSetAside(calculation *PackingCalculation, packs []boundedPack, pivot, count int) *PackingCalculation {
+	if count > 0 {
+		calculation.Packs[models.Pack(packs[pivot].size)] += count
+		calculation.TotalItems += count * packs[pivot].size
+		calculation.TotalPacks += count
+	}
+	return calculation
+}
+
+// best (cost, packs) for every exact total up to a size, and how many packs of each size reach it
+type boundedTable struct {
+	packs    []boundedPack
+	minCost  []int
+	minPacks []int
+	used     [][]int32
+}
+
+func newBoundedTable(packs []boundedPack, maxSize int) (*boundedTable, error) {
+	if (maxSize+1)*len(packs) > maxStockTableCells {
+		return nil, fmt.Errorf("%w: item count is too large to calculate with these packs and stock", InvalidOrderItemCountError)
+	}
+
+	// best (cost, packs) for every exact total using the pack sizes processed so far
+	minCost := make([]int, maxSize+1)
+	minPacks := make([]int, maxSize+1)
+	for i := 1; i <= maxSize; i++ {
+		minCost[i] = math.MaxInt
+		minPacks[i] = math.MaxInt
+	}
+
+	// how many packs of each size were used to reach a total, for the reconstruction
+	used := make([][]int32, len(packs))
+
+	for j, pack := range packs {
+		used[j] = make([]int32, maxSize+1)
+		nextCost := make([]int, maxSize+1)
+		nextPacks := make([]int, maxSize+1)
+
+		// totals with the same residue only reach each other, for each we keep a sliding window
+		// over the last limit totals with the best (cost, packs) adjusted for the packs still to add
+		for r := 0; r < pack.size && r <= maxSize; r++ {
+			var window []int
+			key := func(q int) (int, int) {
+				t := r + q*pack.size
+				return minCost[t] - q*pack.cost, minPacks[t] - q
+			}
+
+			for q := 0; r+q*pack.size <= maxSize; q++ {
+				t := r + q*pack.size
+
+				if minCost[t] != math.MaxInt {
+					cost, packCount := key(q)
+					for len(window) > 0 {
+						lastCost, lastPackCount := key(window[len(window)-1])
+						if lastCost < cost || (lastCost == cost && lastPackCount <= packCount) {
+							break
+						}
+						window = window[:len(window)-1]
+					}
+					window = append(window, q)
+				}
+
+				if pack.limit >= 0 && len(window) > 0 && q-window[0] > pack.limit {
+					window = window[1:]
+				}
+
+				if len(window) == 0 {
+					nextCost[t] = math.MaxInt
+					nextPacks[t] = math.MaxInt
+					continue
+				}
+
+				from := window[0]
+				count := q - from
+				nextCost[t] = minCost[r+from*pack.size] + count*pack.cost
+				nextPacks[t] = minPacks[r+from*pack.size] + count
+				used[j][t] = int32(count)
+			}
+		}
+
+		minCost, minPacks = nextCost, nextPacks
+	}
+
+	return &boundedTable{packs: packs, minCost: minCost, minPacks: minPacks, used: used}, nil
+}
+
+// the packs reaching exactly total items, total has to be reachable
+func (t *boundedTable) packing(optimalCount int) *PackingCalculation {
+	finalPacks := make(map[models.Pack]int)
+	remainingCount := optimalCount
+	for j := len(t.packs) - 1; j >= 0; j-- {
+		count := int(t.used[j][remainingCount])
+		if count > 0 {
+			finalPacks[models.Pack(t.packs[j].size)] = count
+		}
+		remainingCount -= count * t.packs[j].size
+	}
+
+	return &PackingCalculation{
+		Packs:      finalPacks,
+		TotalItems: optimalCount,
+		TotalPacks: t.minPacks[optimalCount],
+	}
+}
@@ -0,0 +1,278 @@
+package orders
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/irreal/order-packs/models"
+)
+
+func TestSolver_SolveWithStock(t *testing.T) {
+	defaultPacks := []models.Pack{250, 500, 1000, 2000, 5000}
+
+	tests := []struct {
+		name              string
+		stock             map[models.Pack]int
+		requestedCount    int
+		expectedPacks     map[models.Pack]int
+		expectedItemCount int
+		expectedPackCount int
+	}{
+		{
+			name:              "no stock limits",
+			stock:             nil,
+			requestedCount:    12001,
+			expectedPacks:     map[models.Pack]int{5000: 2, 2000: 1, 250: 1},
+			expectedItemCount: 12250,
+			expectedPackCount: 4,
+		},
+		{
+			name:              "enough stock for the best combination",
+			stock:             map[models.Pack]int{5000: 2, 2000: 1, 250: 1},
+			requestedCount:    12001,
+			expectedPacks:     map[models.Pack]int{5000: 2, 2000: 1, 250: 1},
+			expectedItemCount: 12250,
+			expectedPackCount: 4,
+		},
+		{
+			name:              "out of 5000 packs",
+			stock:             map[models.Pack]int{5000: 0},
+			requestedCount:    12001,
+			expectedPacks:     map[models.Pack]int{2000: 6, 250: 1},
+			expectedItemCount: 12250,
+			expectedPackCount: 7,
+		},
+		{
+			name:              "one 5000 pack left",
+			stock:             map[models.Pack]int{5000: 1},
+			requestedCount:    12001,
+			expectedPacks:     map[models.Pack]int{5000: 1, 2000: 3, 1000: 1, 250: 1},
+			expectedItemCount: 12250,
+			expectedPackCount: 6,
+		},
+		{
+			name:              "no 250 packs, rule 2 falls back to more items",
+			stock:             map[models.Pack]int{250: 0},
+			requestedCount:    1,
+			expectedPacks:     map[models.Pack]int{500: 1},
+			expectedItemCount: 500,
+			expectedPackCount: 1,
+		},
+		{
+			name:              "every size limited",
+			stock:             map[models.Pack]int{250: 1, 500: 1, 1000: 0, 2000: 0, 5000: 0},
+			requestedCount:    600,
+			expectedPacks:     map[models.Pack]int{250: 1, 500: 1},
+			expectedItemCount: 750,
+			expectedPackCount: 2,
+		},
+	}
+
+	solver, err := NewSolver(defaultPacks)
+	if err != nil {
+		t.Fatalf("NewSolver() error = %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := solver.SolveWithStock(tt.requestedCount, tt.stock)
+			if err != nil {
+				t.Fatalf("SolveWithStock() error = %v", err)
+			}
+
+			if result.TotalItems != tt.expectedItemCount {
+				t.Errorf("TotalItems = %d, want %d", result.TotalItems, tt.expectedItemCount)
+			}
+
+			if result.TotalPacks != tt.expectedPackCount {
+				t.Errorf("TotalPacks = %d, want %d", result.TotalPacks, tt.expectedPackCount)
+			}
+
+			if len(result.Packs) != len(tt.expectedPacks) {
+				t.Errorf("count of pack types used = %d, want %d (%v)", len(result.Packs), len(tt.expectedPacks), result.Packs)
+			}
+
+			for pack, count := range tt.expectedPacks {
+				if result.Packs[pack] != count {
+					t.Errorf("pack %d count = %d, want %d", pack, result.Packs[pack], count)
+				}
+			}
+		})
+	}
+}
+
+func TestSolver_SolveWithStock_InsufficientStock(t *testing.T) {
+	solver, err := NewSolver([]models.Pack{250, 500})
+	if err != nil {
+		t.Fatalf("NewSolver() error = %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		stock          map[models.Pack]int
+		requestedCount int
+	}{
+		{name: "everything out of stock", stock: map[models.Pack]int{250: 0, 500: 0}, requestedCount: 1},
+		{name: "stock too small for the order", stock: map[models.Pack]int{250: 2, 500: 1}, requestedCount: 1001},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := solver.SolveWithStock(tt.requestedCount, tt.stock)
+			if !errors.Is(err, InsufficientStockError) {
+				t.Errorf("SolveWithStock() error = %v, want %v", err, InsufficientStockError)
+			}
+		})
+	}
+}
+
+// the stock constrained solution has to match trying every combination within stock
+func TestSolver_SolveWithStock_MatchesBruteForce(t *testing.T) {
+	packs := []models.Pack{3, 5, 8}
+	stock := map[models.Pack]int{3: 2, 5: 3, 8: 1}
+	strategies := []Strategy{
+		LeastItemsStrategy{},
+		FewestPacksStrategy{},
+		LowestCostStrategy{Costs: map[models.Pack]int{3: 1, 5: 4, 8: 2}},
+	}
+
+	for _, strategy := range strategies {
+		solver, err := NewStrategySolver(packs, strategy)
+		if err != nil {
+			t.Fatalf("NewStrategySolver() error = %v", err)
+		}
+
+		for requestedCount := 1; requestedCount <= 3*2+5*3+8; requestedCount++ {
+			result, err := solver.SolveWithStock(requestedCount, stock)
+			if err != nil {
+				t.Fatalf("%s SolveWithStock(%d) error = %v", strategy.Objective(), requestedCount, err)
+			}
+
+			// every combination within stock
+			bestCost, bestItems, bestPacks := math.MaxInt, 0, 0
+			for a := 0; a <= stock[3]; a++ {
+				for b := 0; b <= stock[5]; b++ {
+					for c := 0; c <= stock[8]; c++ {
+						items := 3*a + 5*b + 8*c
+						if items < requestedCount {
+							continue
+						}
+						cost := a*solver.costs[0] + b*solver.costs[1] + c*solver.costs[2]
+						count := a + b + c
+						if cost < bestCost || (cost == bestCost && (items < bestItems || (items == bestItems && count < bestPacks))) {
+							bestCost, bestItems, bestPacks = cost, items, count
+						}
+					}
+				}
+			}
+
+			if result.TotalItems != bestItems || result.TotalPacks != bestPacks {
+				t.Errorf("%s SolveWithStock(%d) = %d items in %d packs, want %d items in %d packs",
+					strategy.Objective(), requestedCount, result.TotalItems, result.TotalPacks, bestItems, bestPacks)
+			}
+			for pack, count := range result.Packs {
+				if count > stock[pack] {
+					t.Errorf("%s SolveWithStock(%d) uses %d packs of %d, only %d in stock",
+						strategy.Objective(), requestedCount, count, pack, stock[pack])
+				}
+			}
+		}
+	}
+}
+
+// big orders set aside pivot packs before the dp, that must not change the answer. checked against the dp over the whole order
+func TestSolver_SolveWithStock_SetAsideMatchesWholeTable(t *testing.T) {
+	packs := []models.Pack{23, 31, 53}
+	strategies := []Strategy{
+		LeastItemsStrategy{},
+		FewestPacksStrategy{},
+		LowestCostStrategy{Costs: map[models.Pack]int{23: 5, 31: 6, 53: 12}},
+	}
+	stocks := []map[models.Pack]int{
+		{53: 40},
+		{31: 5},
+		{23: 3, 53: 100},
+		{23: 200, 31: 0},
+	}
+
+	for _, strategy := range strategies {
+		solver, err := NewStrategySolver(packs, strategy)
+		if err != nil {
+			t.Fatalf("NewStrategySolver() error = %v", err)
+		}
+
+		for _, stock := range stocks {
+			bounded, _, _ := solver.boundedPacks(stock)
+			largest := bounded[len(bounded)-1].size
+			table, err := newBoundedTable(bounded, 6000+largest)
+			if err != nil {
+				t.Fatalf("newBoundedTable() error = %v", err)
+			}
+
+			for requestedCount := 1; requestedCount <= 6000; requestedCount += 7 {
+				result, err := solver.solveBounded(requestedCount, math.MaxInt, stock)
+				if err != nil {
+					t.Fatalf("%s solveBounded(%d, %v) error = %v", strategy.Objective(), requestedCount, stock, err)
+				}
+				expected := -1
+				for total := requestedCount; total < requestedCount+largest; total++ {
+					if table.minCost[total] != math.MaxInt && (expected == -1 || table.minCost[total] < table.minCost[expected]) {
+						expected = total
+					}
+				}
+				if result.TotalItems != expected || result.TotalPacks != table.minPacks[expected] || packingCost(solver, result) != table.minCost[expected] {
+					t.Errorf("%s solveBounded(%d, %v) = %d items in %d packs, want %d items in %d packs",
+						strategy.Objective(), requestedCount, stock, result.TotalItems, result.TotalPacks, expected, table.minPacks[expected])
+				}
+
+				under, err := solver.SolveUnder(requestedCount, stock)
+				if err != nil {
+					t.Fatalf("%s SolveUnder(%d, %v) error = %v", strategy.Objective(), requestedCount, stock, err)
+				}
+				expected = requestedCount
+				for expected > 0 && table.minCost[expected] == math.MaxInt {
+					expected--
+				}
+				if under.TotalItems != expected || under.TotalPacks != table.minPacks[expected] || packingCost(solver, under) != table.minCost[expected] {
+					t.Errorf("%s SolveUnder(%d, %v) = %d items in %d packs, want %d items in %d packs",
+						strategy.Objective(), requestedCount, stock, under.TotalItems, under.TotalPacks, expected, table.minPacks[expected])
+				}
+			}
+		}
+	}
+}
+
+// the dp only covers what's left after setting aside pivot packs, so orders of any size work with limited stock
+func TestSolver_SolveWithStock_LargeOrders(t *testing.T) {
+	solver, err := NewSolver([]models.Pack{250, 500, 1000, 2000, 5000})
+	if err != nil {
+		t.Fatalf("NewSolver() error = %v", err)
+	}
+
+	result, err := solver.SolveWithStock(1_000_000_001, map[models.Pack]int{5000: 10})
+	if err != nil {
+		t.Fatalf("SolveWithStock() error = %v", err)
+	}
+	if result.TotalItems != 1_000_000_250 || result.TotalPacks != 499_986 || result.Packs[5000] != 10 {
+		t.Errorf("SolveWithStock() = %d items in %d packs %v, want 1000000250 items in 499986 packs with all 10 of 5000",
+			result.TotalItems, result.TotalPacks, result.Packs)
+	}
+
+	under, err := solver.SolveUnder(1_000_000_001, map[models.Pack]int{5000: 10})
+	if err != nil {
+		t.Fatalf("SolveUnder() error = %v", err)
+	}
+	if under.TotalItems != 1_000_000_000 || under.TotalPacks != 499_985 {
+		t.Errorf("SolveUnder() = %d items in %d packs, want 1000000000 items in 499985 packs", under.TotalItems, under.TotalPacks)
+	}
+}
+
+// what the strategy prices the packs at
+func packingCost(solver *Solver, calculation *PackingCalculation) int {
+	cost := 0
+	for i, pack := range solver.packs {
+		cost += solver.costs[i] * calculation.Packs[pack]
+	}
+	return cost
+}
@@ -0,0 +1,69 @@
+package orders
+
+import (
+	"fmt"
+
+	"github.com/irreal/order-packs/models"
+)
+
+// A Strategy decides what the best packing is by putting a price on every pack.
+// The solver minimizes the total price first, then the items sent, then the number of packs,
+// so rules 1 and 2 style guarantees (whole packs, at least the requested items) hold for every strategy.
+type Strategy interface {
+	Objective() models.Objective
+	PackCost(pack models.Pack) (int, error)
+}
+
+// the default, rules 2 and 3. packs are free, so the least items win, then the fewest packs
+type LeastItemsStrategy struct{}
+
+func (LeastItemsStrategy) Objective() models.Objective {
+	return models.ObjectiveLeastItems
+}
+
+func (LeastItemsStrategy) PackCost(pack models.Pack) (int, error) {
+	return 0, nil
+}
+
+// every pack costs the same, so the fewest packs win, then the least items
+type FewestPacksStrategy struct{}
+
+func (FewestPacksStrategy) Objective() models.Objective {
+	return models.ObjectiveFewestPacks
+}
+
+func (FewestPacksStrategy) PackCost(pack models.Pack) (int, error) {
+	return 1, nil
+}
+
+// packs are priced by their packaging cost in cents, so the cheapest shipment wins, then the least items, then the fewest packs
+type LowestCostStrategy struct {
+	Costs map[models.Pack]int
+}
+
+func (LowestCostStrategy) Objective() models.Objective {
+	return models.ObjectiveLowestCost
+}
+
+func (s LowestCostStrategy) PackCost(pack models.Pack) (int, error) {
+	cost, ok := s.Costs[pack]
+	if !ok {
+		return 0, fmt.Errorf("%w: no packaging cost configured for pack %d", InvalidObjectiveError, pack)
+	}
+	return cost, nil
+}
+
+// returns the strategy for an objective, an empty objective means the default strategy.
+// costs are only needed for the lowest cost objective
+func StrategyFor(objective models.Objective, costs map[models.Pack]int) (Strategy, error) {
+	switch objective {
+	case "", models.ObjectiveLeastItems:
+		return LeastItemsStrategy{}, nil
+	case models.ObjectiveFewestPacks:
+		return FewestPacksStrategy{}, nil
+	case models.ObjectiveLowestCost:
+		return LowestCostStrategy{Costs: costs}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown objective %q", InvalidObjectiveError, objective)
+	}
+}
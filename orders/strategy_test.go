@@ -0,0 +1,239 @@
+package orders
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/irreal/order-packs/models"
+)
+
+func TestCalculatePackWithStrategy_Functionality(t *testing.T) {
+	defaultPacks := []models.Pack{250, 500, 1000, 2000, 5000}
+	costs := map[models.Pack]int{250: 500, 500: 60, 1000: 100, 2000: 180, 5000: 400}
+
+	tests := []struct {
+		name              string
+		strategy          Strategy
+		availablePacks    []models.Pack
+		requestedCount    int
+		expectedPacks     map[models.Pack]int
+		expectedItemCount int
+		expectedPackCount int
+	}{
+		{
+			name:              "least items, 501 items ship as 250 + 500",
+			strategy:          LeastItemsStrategy{},
+			availablePacks:    defaultPacks,
+			requestedCount:    501,
+			expectedPacks:     map[models.Pack]int{250: 1, 500: 1},
+			expectedItemCount: 750,
+			expectedPackCount: 2,
+		},
+		{
+			name:              "fewest packs, 501 items ship as a single 1000",
+			strategy:          FewestPacksStrategy{},
+			availablePacks:    defaultPacks,
+			requestedCount:    501,
+			expectedPacks:     map[models.Pack]int{1000: 1},
+			expectedItemCount: 1000,
+			expectedPackCount: 1,
+		},
+		{
+			name:              "fewest packs, 12001 items ship as 3x5000",
+			strategy:          FewestPacksStrategy{},
+			availablePacks:    defaultPacks,
+			requestedCount:    12001,
+			expectedPacks:     map[models.Pack]int{5000: 3},
+			expectedItemCount: 15000,
+			expectedPackCount: 3,
+		},
+		{
+			name:              "fewest packs, least items among the fewest packs",
+			strategy:          FewestPacksStrategy{},
+			availablePacks:    defaultPacks,
+			requestedCount:    5001,
+			expectedPacks:     map[models.Pack]int{5000: 1, 250: 1},
+			expectedItemCount: 5250,
+			expectedPackCount: 2,
+		},
+		{
+			name:              "lowest cost, expensive small pack is skipped",
+			strategy:          LowestCostStrategy{Costs: costs},
+			availablePacks:    defaultPacks,
+			requestedCount:    1,
+			expectedPacks:     map[models.Pack]int{500: 1},
+			expectedItemCount: 500,
+			expectedPackCount: 1,
+		},
+		{
+			name:              "lowest cost, large order",
+			strategy:          LowestCostStrategy{Costs: costs},
+			availablePacks:    defaultPacks,
+			requestedCount:    300000001,
+			expectedPacks:     map[models.Pack]int{5000: 60000, 500: 1},
+			expectedItemCount: 300000500,
+			expectedPackCount: 60001,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := CalculatePackWithStrategy(tt.availablePacks, tt.requestedCount, tt.strategy)
+			if err != nil {
+				t.Fatalf("CalculatePackWithStrategy() error = %v", err)
+			}
+
+			if result.TotalItems != tt.expectedItemCount {
+				t.Errorf("TotalItems = %d, want %d", result.TotalItems, tt.expectedItemCount)
+			}
+
+			if result.TotalPacks != tt.expectedPackCount {
+				t.Errorf("TotalPacks = %d, want %d", result.TotalPacks, tt.expectedPackCount)
+			}
+
+			if len(result.Packs) != len(tt.expectedPacks) {
+				t.Errorf("count of pack types used = %d, want %d", len(result.Packs), len(tt.expectedPacks))
+			}
+
+			for pack, count := range tt.expectedPacks {
+				if result.Packs[pack] != count {
+					t.Errorf("pack %d count = %d, want %d", pack, result.Packs[pack], count)
+				}
+			}
+		})
+	}
+}
+
+// every strategy has to agree with a plain dynamic programming solution minimizing cost, then items, then packs
+func TestCalculatePackWithStrategy_MatchesDynamicProgramming(t *testing.T) {
+	tests := []struct {
+		name     string
+		packs    []models.Pack
+		strategy Strategy
+	}{
+		{name: "fewest packs", packs: []models.Pack{250, 500, 1000, 2000, 5000}, strategy: FewestPacksStrategy{}},
+		{name: "fewest packs, edge case packs", packs: []models.Pack{23, 31, 53}, strategy: FewestPacksStrategy{}},
+		{
+			name:     "lowest cost, bulk discount",
+			packs:    []models.Pack{250, 500, 1000, 2000, 5000},
+			strategy: LowestCostStrategy{Costs: map[models.Pack]int{250: 40, 500: 60, 1000: 100, 2000: 180, 5000: 400}},
+		},
+		{
+			name:     "lowest cost, middle pack is the best deal",
+			packs:    []models.Pack{6, 9, 20},
+			strategy: LowestCostStrategy{Costs: map[models.Pack]int{6: 5, 9: 6, 20: 19}},
+		},
+		{
+			name:     "lowest cost, free pack",
+			packs:    []models.Pack{4, 10, 15},
+			strategy: LowestCostStrategy{Costs: map[models.Pack]int{4: 3, 10: 0, 15: 2}},
+		},
+		{
+			name:     "lowest cost, same cost per item",
+			packs:    []models.Pack{3, 5, 7},
+			strategy: LowestCostStrategy{Costs: map[models.Pack]int{3: 3, 5: 5, 7: 7}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			solver, err := NewStrategySolver(tt.packs, tt.strategy)
+			if err != nil {
+				t.Fatalf("NewStrategySolver() error = %v", err)
+			}
+
+			for requestedCount := 1; requestedCount <= solver.bound+3*solver.largest; requestedCount += 7 {
+				result, err := solver.Solve(requestedCount)
+				if err != nil {
+					t.Fatalf("Solve(%d) error = %v", requestedCount, err)
+				}
+
+				expected := referenceCalculateCost(solver, requestedCount)
+				cost := 0
+				for pack, count := range result.Packs {
+					packCost, _ := tt.strategy.PackCost(pack)
+					cost += packCost * count
+				}
+
+				if cost != expected.cost || result.TotalItems != expected.TotalItems || result.TotalPacks != expected.TotalPacks {
+					t.Fatalf("Solve(%d) = cost %d, %d items in %d packs, want cost %d, %d items in %d packs",
+						requestedCount, cost, result.TotalItems, result.TotalPacks, expected.cost, expected.TotalItems, expected.TotalPacks)
+				}
+			}
+		})
+	}
+}
+
+func TestStrategyFor(t *testing.T) {
+	tests := []struct {
+		name      string
+		objective models.Objective
+		expected  models.Objective
+	}{
+		{name: "empty objective is the default", objective: "", expected: models.ObjectiveLeastItems},
+		{name: "least items", objective: models.ObjectiveLeastItems, expected: models.ObjectiveLeastItems},
+		{name: "fewest packs", objective: models.ObjectiveFewestPacks, expected: models.ObjectiveFewestPacks},
+		{name: "lowest cost", objective: models.ObjectiveLowestCost, expected: models.ObjectiveLowestCost},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy, err := StrategyFor(tt.objective, nil)
+			if err != nil {
+				t.Fatalf("StrategyFor() error = %v", err)
+			}
+			if strategy.Objective() != tt.expected {
+				t.Errorf("Objective() = %s, want %s", strategy.Objective(), tt.expected)
+			}
+		})
+	}
+
+	if _, err := StrategyFor("cheapest-please", nil); !errors.Is(err, InvalidObjectiveError) {
+		t.Errorf("StrategyFor() unknown objective error = %v, want %v", err, InvalidObjectiveError)
+	}
+}
+
+func TestLowestCostStrategy_MissingCost(t *testing.T) {
+	strategy := LowestCostStrategy{Costs: map[models.Pack]int{250: 40}}
+
+	_, err := CalculatePackWithStrategy([]models.Pack{250, 500}, 1, strategy)
+	if !errors.Is(err, InvalidObjectiveError) {
+		t.Errorf("CalculatePackWithStrategy() error = %v, want %v", err, InvalidObjectiveError)
+	}
+}
+
+type costCalculation struct {
+	PackingCalculation
+	cost int
+}
+
+// plain dynamic programming over every total, minimizing cost, then items, then packs
+func referenceCalculateCost(solver *Solver, requestedCount int) costCalculation {
+	maxSize := requestedCount + solver.largest
+	minCost := make([]int, maxSize+1)
+	minPacks := make([]int, maxSize+1)
+	for i := 1; i <= maxSize; i++ {
+		minCost[i] = math.MaxInt
+		minPacks[i] = math.MaxInt
+		for j, pack := range solver.packs {
+			if i < int(pack) || minCost[i-int(pack)] == math.MaxInt {
+				continue
+			}
+			cost := minCost[i-int(pack)] + solver.costs[j]
+			packs := minPacks[i-int(pack)] + 1
+			if cost < minCost[i] || (cost == minCost[i] && packs < minPacks[i]) {
+				minCost[i] = cost
+				minPacks[i] = packs
+			}
+		}
+	}
+
+	best := costCalculation{cost: math.MaxInt}
+	for i := requestedCount; i <= maxSize; i++ {
+		if minCost[i] < best.cost {
+			best = costCalculation{PackingCalculation: PackingCalculation{TotalItems: i, TotalPacks: minPacks[i]}, cost: minCost[i]}
+		}
+	}
+	return best
+}
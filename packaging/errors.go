@@ -0,0 +1,5 @@
+package packaging
+
+import "fmt"
+
+var InvalidPackagingError = fmt.Errorf("packaging hierarchy is not valid")
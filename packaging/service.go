@@ -0,0 +1,100 @@
+package packaging
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/irreal/order-packs/models"
+)
+
+// the calculator plans every level with tables as large as the biggest unit, see orders.PlanPackaging
+const MaxUnitsPerLevel = 10_000
+
+type Service struct {
+	repo PackagingRepository
+}
+
+type PackagingRepository interface {
+	// the saved hierarchy, empty until one is saved
+	GetPackaging() (*models.PackagingHierarchy, error)
+	// replaces the whole hierarchy
+	SavePackaging(hierarchy *models.PackagingHierarchy) error
+}
+
+func NewService(repo PackagingRepository) *Service {
+	return &Service{
+		repo: repo,
+	}
+}
+
+func (s *Service) GetPackaging() (*models.PackagingHierarchy, error) {
+	return s.repo.GetPackaging()
+}
+
+// Replaces the case and pallet types. Orders placed before keep the plan they got
+func (s *Service) SavePackaging(hierarchy models.PackagingHierarchy) (*models.PackagingHierarchy, error) {
+	if err := validatePackaging(&hierarchy); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SavePackaging(&hierarchy); err != nil {
+		return nil, err
+	}
+	return &hierarchy, nil
+}
+
+// trims the SKUs and names in place. SKUs are unique across cases and pallets, so a plan never mixes them up
+func validatePackaging(hierarchy *models.PackagingHierarchy) error {
+	if hierarchy.Cases == nil {
+		hierarchy.Cases = []models.CaseType{}
+	}
+	if hierarchy.Pallets == nil {
+		hierarchy.Pallets = []models.PalletType{}
+	}
+
+	skus := make(map[string]bool)
+	checkSKU := func(sku string) error {
+		if sku == "" {
+			return fmt.Errorf("%w: every case and pallet needs a SKU", InvalidPackagingError)
+		}
+		if skus[sku] {
+			return fmt.Errorf("%w: SKU %s is used more than once", InvalidPackagingError, sku)
+		}
+		skus[sku] = true
+		return nil
+	}
+
+	caseSKUs := make(map[string]bool, len(hierarchy.Cases))
+	for i := range hierarchy.Cases {
+		caseType := &hierarchy.Cases[i]
+		caseType.SKU = strings.TrimSpace(caseType.SKU)
+		caseType.Name = strings.TrimSpace(caseType.Name)
+		if err := checkSKU(caseType.SKU); err != nil {
+			return err
+		}
+		if caseType.PackSize <= 0 {
+			return fmt.Errorf("%w: pack size of case %s must be positive", InvalidPackagingError, caseType.SKU)
+		}
+		// a case of a single pack would just be the pack
+		if caseType.Packs < 2 || caseType.Packs > MaxUnitsPerLevel {
+			return fmt.Errorf("%w: case %s has to hold between 2 and %d packs", InvalidPackagingError, caseType.SKU, MaxUnitsPerLevel)
+		}
+		caseSKUs[caseType.SKU] = true
+	}
+
+	for i := range hierarchy.Pallets {
+		pallet := &hierarchy.Pallets[i]
+		pallet.SKU = strings.TrimSpace(pallet.SKU)
+		pallet.Name = strings.TrimSpace(pallet.Name)
+		pallet.CaseSKU = strings.TrimSpace(pallet.CaseSKU)
+		if err := checkSKU(pallet.SKU); err != nil {
+			return err
+		}
+		if !caseSKUs[pallet.CaseSKU] {
+			return fmt.Errorf("%w: pallet %s holds case %q, which isn't one of the cases", InvalidPackagingError, pallet.SKU, pallet.CaseSKU)
+		}
+		if pallet.Cases < 2 || pallet.Cases > MaxUnitsPerLevel {
+			return fmt.Errorf("%w: pallet %s has to hold between 2 and %d cases", InvalidPackagingError, pallet.SKU, MaxUnitsPerLevel)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package packaging
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/irreal/order-packs/models"
+)
+
+type MockPackagingRepository struct {
+	hierarchy *models.PackagingHierarchy
+}
+
+func NewMockPackagingRepository() *MockPackagingRepository {
+	return &MockPackagingRepository{
+		hierarchy: &models.PackagingHierarchy{Cases: []models.CaseType{}, Pallets: []models.PalletType{}},
+	}
+}
+
+func (m *MockPackagingRepository) GetPackaging() (*models.PackagingHierarchy, error) {
+	return m.hierarchy, nil
+}
+
+func (m *MockPackagingRepository) SavePackaging(hierarchy *models.PackagingHierarchy) error {
+	m.hierarchy = hierarchy
+	return nil
+}
+
+func TestService_SavePackaging(t *testing.T) {
+	valid := func() models.PackagingHierarchy {
+		return models.PackagingHierarchy{
+			Cases:   []models.CaseType{{SKU: " CASE-250 ", Name: "Case of 250s", PackSize: 250, Packs: 10}},
+			Pallets: []models.PalletType{{SKU: "PAL-250", CaseSKU: "CASE-250", Cases: 20}},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		change        func(h *models.PackagingHierarchy)
+		expectedError error
+	}{
+		{name: "valid", change: func(h *models.PackagingHierarchy) {}},
+		{name: "nothing", change: func(h *models.PackagingHierarchy) { *h = models.PackagingHierarchy{} }},
+		{name: "case without SKU", change: func(h *models.PackagingHierarchy) { h.Cases[0].SKU = " " }, expectedError: InvalidPackagingError},
+		{name: "case without pack size", change: func(h *models.PackagingHierarchy) { h.Cases[0].PackSize = 0 }, expectedError: InvalidPackagingError},
+		{name: "case of one pack", change: func(h *models.PackagingHierarchy) { h.Cases[0].Packs = 1 }, expectedError: InvalidPackagingError},
+		{name: "case too large", change: func(h *models.PackagingHierarchy) { h.Cases[0].Packs = MaxUnitsPerLevel + 1 }, expectedError: InvalidPackagingError},
+		{name: "pallet of unknown case", change: func(h *models.PackagingHierarchy) { h.Pallets[0].CaseSKU = "CASE-500" }, expectedError: InvalidPackagingError},
+		{name: "pallet of one case", change: func(h *models.PackagingHierarchy) { h.Pallets[0].Cases = 1 }, expectedError: InvalidPackagingError},
+		{name: "SKU of a case and a pallet", change: func(h *models.PackagingHierarchy) { h.Pallets[0].SKU = "CASE-250" }, expectedError: InvalidPackagingError},
+		{
+			name: "same case twice",
+			change: func(h *models.PackagingHierarchy) {
+				h.Cases = append(h.Cases, models.CaseType{SKU: "CASE-250", PackSize: 500, Packs: 4})
+			},
+			expectedError: InvalidPackagingError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := NewMockPackagingRepository()
+			service := NewService(mockRepo)
+			hierarchy := valid()
+			tt.change(&hierarchy)
+
+			saved, err := service.SavePackaging(hierarchy)
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Errorf("SavePackaging() error = %v, want %v", err, tt.expectedError)
+				}
+				if len(mockRepo.hierarchy.Cases) != 0 {
+					t.Errorf("SavePackaging() saved an invalid hierarchy")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SavePackaging() unexpected error = %v", err)
+			}
+			if saved.Cases == nil || saved.Pallets == nil {
+				t.Errorf("SavePackaging() = %+v, want empty lists instead of nil", saved)
+			}
+			if len(saved.Cases) > 0 && saved.Cases[0].SKU != "CASE-250" {
+				t.Errorf("case SKU = %q, want it trimmed", saved.Cases[0].SKU)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package packs
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/irreal/order-packs/models"
+	"github.com/irreal/order-packs/orders"
+)
+
+// most item counts a single analysis calculates, by default it covers counts up to 10 times the largest pack
+const MaxAnalyzedCounts = 100_000
+
+// past this (in multiples of the granularity) sizes are not checked for being sums of smaller ones
+const maxRedundancyTableSize = 10_000_000
+
+// Calculates every item count in the range with the pack set, the way orders would be, and reports what looks wrong with it.
+// Only enabled packs are analyzed, they don't have to be saved
+func AnalyzePackSet(request models.PackAnalysisRequest) (*models.PackSetAnalysis, error) {
+	details := models.EnabledPacks(request.Packs)
+	if len(details) == 0 {
+		return nil, NoEnabledPacksError
+	}
+	costs := make(map[models.Pack]int, len(details))
+	for _, pack := range details {
+		if err := pack.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %w", InvalidPackAnalysisError, err)
+		}
+		costs[pack.Size] = pack.UnitCost
+	}
+
+	sizes := models.PackSizes(details)
+	analysis := &models.PackSetAnalysis{DuplicateSizes: DuplicateSizes(details), Warnings: []string{}}
+	slices.Sort(sizes)
+	analysis.Packs = slices.Compact(sizes)
+	largest := int(analysis.Packs[len(analysis.Packs)-1])
+
+	analysis.From = max(request.From, 1)
+	analysis.To = request.To
+	if analysis.To == 0 {
+		analysis.To = analysis.From + min(10*largest, MaxAnalyzedCounts) - 1
+	}
+	if request.From < 0 || analysis.To < analysis.From {
+		return nil, fmt.Errorf("%w: from %d to %d is not a range of item counts", InvalidPackAnalysisError, request.From, request.To)
+	}
+	if analysis.To-analysis.From >= MaxAnalyzedCounts {
+		return nil, fmt.Errorf("%w: at most %d item counts can be analyzed at once", InvalidPackAnalysisError, MaxAnalyzedCounts)
+	}
+
+	strategy, err := orders.StrategyFor(request.Objective, costs)
+	if err != nil {
+		return nil, err
+	}
+	solver, err := orders.NewStrategySolver(analysis.Packs, strategy)
+	if err == nil && solver.PerOrder() {
+		// too slow to solve every count in the range with
+		err = fmt.Errorf("%w: packs %v need a dp per order", orders.PackSetTooLargeError, analysis.Packs)
+	}
+	if errors.Is(err, orders.PackSetTooLargeError) {
+		return nil, fmt.Errorf("%w: %w", InvalidPackAnalysisError, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[models.Pack]bool, len(analysis.Packs))
+	totalOvershoot := 0
+	for count := analysis.From; count <= analysis.To; count++ {
+		calculation, err := solver.Solve(count)
+		if err != nil {
+			return nil, err
+		}
+		for pack := range calculation.Packs {
+			used[pack] = true
+		}
+		overshoot := calculation.TotalItems - count
+		totalOvershoot += overshoot
+		if overshoot > analysis.WorstOvershoot {
+			analysis.WorstOvershoot = overshoot
+			analysis.WorstOvershootAt = count
+		}
+	}
+	analysis.AverageOvershoot = float64(totalOvershoot) / float64(analysis.To-analysis.From+1)
+
+	analysis.UnusedSizes = models.Packs{}
+	for _, pack := range analysis.Packs {
+		if !used[pack] {
+			analysis.UnusedSizes = append(analysis.UnusedSizes, pack)
+		}
+	}
+	analysis.Granularity = granularity(analysis.Packs)
+	analysis.RedundantSizes = redundantSizes(analysis.Packs, analysis.Granularity)
+
+	if len(analysis.DuplicateSizes) > 0 {
+		analysis.Warnings = append(analysis.Warnings, fmt.Sprintf("%s given more than once", describeSizes(analysis.DuplicateSizes)))
+	}
+	if len(analysis.UnusedSizes) > 0 {
+		analysis.Warnings = append(analysis.Warnings, fmt.Sprintf("%s never used for orders of %d to %d items",
+			describeSizes(analysis.UnusedSizes), analysis.From, analysis.To))
+	}
+	if len(analysis.RedundantSizes) > 0 {
+		analysis.Warnings = append(analysis.Warnings, fmt.Sprintf("%s made of smaller packs, so they can save packs but never items",
+			describeSizes(analysis.RedundantSizes)))
+	}
+	if analysis.Granularity > 1 {
+		analysis.Warnings = append(analysis.Warnings, fmt.Sprintf("every pack is a multiple of %d, so orders are always rounded up to a multiple of %d items",
+			analysis.Granularity, analysis.Granularity))
+	}
+	return analysis, nil
+}
+
+// sizes that are in the packs more than once, sorted
+func DuplicateSizes(packs []models.PackDetails) models.Packs {
+	seen := make(map[models.Pack]int, len(packs))
+	duplicates := models.Packs{}
+	for _, pack := range packs {
+		seen[pack.Size]++
+		if seen[pack.Size] == 2 {
+			duplicates = append(duplicates, pack.Size)
+		}
+	}
+	slices.Sort(duplicates)
+	return duplicates
+}
+
+func granularity(sizes models.Packs) int {
+	divisor := 0
+	for _, size := range sizes {
+		a, b := divisor, int(size)
+		for b != 0 {
+			a, b = b, a%b
+		}
+		divisor = a
+	}
+	return divisor
+}
+
+// Sizes any combination of smaller sizes adds up to. Sizes are distinct and sorted, dividing them by the granularity keeps the table small.
+// Every smaller size is added to the reachable sums in turn, so a size only sees the ones below it
+func redundantSizes(sizes models.Packs, granularity int) models.Packs {
+	redundant := models.Packs{}
+	largest := int(sizes[len(sizes)-1]) / granularity
+	if largest > maxRedundancyTableSize {
+		return redundant
+	}
+
+	reachable := make([]bool, largest+1)
+	reachable[0] = true
+	for _, size := range sizes {
+		step := int(size) / granularity
+		if reachable[step] {
+			redundant = append(redundant, size)
+			// sums it makes are already reachable
+			continue
+		}
+		for total := step; total <= largest; total++ {
+			if reachable[total-step] {
+				reachable[total] = true
+			}
+		}
+	}
+	return redundant
+}
+
+// "pack 250 is" or "packs 250, 500 are"
+func describeSizes(sizes models.Packs) string {
+	if len(sizes) == 1 {
+		return fmt.Sprintf("pack %d is", sizes[0])
+	}
+	names := make([]string, len(sizes))
+	for i, size := range sizes {
+		names[i] = fmt.Sprint(int(size))
+	}
+	return fmt.Sprintf("packs %s are", strings.Join(names, ", "))
+}
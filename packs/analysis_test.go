@@ -0,0 +1,171 @@
+package packs
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/irreal/order-packs/models"
+	"github.com/irreal/order-packs/orders"
+)
+
+func TestAnalyzePackSet(t *testing.T) {
+	tests := []struct {
+		name              string
+		request           models.PackAnalysisRequest
+		expectedFrom      int
+		expectedTo        int
+		expectedDuplicate models.Packs
+		expectedUnused    models.Packs
+		expectedRedundant models.Packs
+		expectedGranular  int
+		expectedWorst     int
+		expectedWorstAt   int
+		expectedAverage   float64
+		expectedWarnings  int
+	}{
+		{
+			name:              "default packs",
+			request:           models.PackAnalysisRequest{Packs: models.Packs{250, 500, 1000, 2000, 5000}.Details()},
+			expectedFrom:      1,
+			expectedTo:        50000,
+			expectedDuplicate: models.Packs{},
+			expectedUnused:    models.Packs{},
+			expectedRedundant: models.Packs{500, 1000, 2000, 5000},
+			expectedGranular:  250,
+			expectedWorst:     249,
+			expectedWorstAt:   1,
+			expectedAverage:   124.5,
+			expectedWarnings:  2,
+		},
+		{
+			name:              "nothing to warn about",
+			request:           models.PackAnalysisRequest{Packs: models.Packs{3, 5}.Details()},
+			expectedFrom:      1,
+			expectedTo:        50,
+			expectedDuplicate: models.Packs{},
+			expectedUnused:    models.Packs{},
+			expectedRedundant: models.Packs{},
+			expectedGranular:  1,
+			expectedWorst:     2,
+			expectedWorstAt:   1,
+			expectedAverage:   0.1,
+			expectedWarnings:  0,
+		},
+		{
+			name:              "duplicates and disabled packs",
+			request:           models.PackAnalysisRequest{Packs: []models.PackDetails{{Size: 3}, {Size: 5}, {Size: 3}, {Size: 1, Disabled: true}}},
+			expectedFrom:      1,
+			expectedTo:        50,
+			expectedDuplicate: models.Packs{3},
+			expectedUnused:    models.Packs{},
+			expectedRedundant: models.Packs{},
+			expectedGranular:  1,
+			expectedWorst:     2,
+			expectedWorstAt:   1,
+			expectedAverage:   0.1,
+			expectedWarnings:  1,
+		},
+		{
+			name: "cheaper larger pack leaves the smaller one unused",
+			request: models.PackAnalysisRequest{
+				Packs:     []models.PackDetails{{Size: 250, UnitCost: 100}, {Size: 500, UnitCost: 10}},
+				Objective: models.ObjectiveLowestCost,
+				From:      1,
+				To:        1000,
+			},
+			expectedFrom:      1,
+			expectedTo:        1000,
+			expectedDuplicate: models.Packs{},
+			expectedUnused:    models.Packs{250},
+			expectedRedundant: models.Packs{500},
+			expectedGranular:  250,
+			expectedWorst:     499,
+			expectedWorstAt:   1,
+			expectedAverage:   249.5,
+			expectedWarnings:  3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis, err := AnalyzePackSet(tt.request)
+			if err != nil {
+				t.Fatalf("AnalyzePackSet() unexpected error = %v", err)
+			}
+
+			if analysis.From != tt.expectedFrom || analysis.To != tt.expectedTo {
+				t.Errorf("range = %d to %d, want %d to %d", analysis.From, analysis.To, tt.expectedFrom, tt.expectedTo)
+			}
+			if !reflect.DeepEqual(analysis.DuplicateSizes, tt.expectedDuplicate) {
+				t.Errorf("DuplicateSizes = %v, want %v", analysis.DuplicateSizes, tt.expectedDuplicate)
+			}
+			if !reflect.DeepEqual(analysis.UnusedSizes, tt.expectedUnused) {
+				t.Errorf("UnusedSizes = %v, want %v", analysis.UnusedSizes, tt.expectedUnused)
+			}
+			if !reflect.DeepEqual(analysis.RedundantSizes, tt.expectedRedundant) {
+				t.Errorf("RedundantSizes = %v, want %v", analysis.RedundantSizes, tt.expectedRedundant)
+			}
+			if analysis.Granularity != tt.expectedGranular {
+				t.Errorf("Granularity = %d, want %d", analysis.Granularity, tt.expectedGranular)
+			}
+			if analysis.WorstOvershoot != tt.expectedWorst || analysis.WorstOvershootAt != tt.expectedWorstAt {
+				t.Errorf("worst overshoot = %d at %d, want %d at %d", analysis.WorstOvershoot, analysis.WorstOvershootAt, tt.expectedWorst, tt.expectedWorstAt)
+			}
+			if analysis.AverageOvershoot != tt.expectedAverage {
+				t.Errorf("AverageOvershoot = %v, want %v", analysis.AverageOvershoot, tt.expectedAverage)
+			}
+			if len(analysis.Warnings) != tt.expectedWarnings {
+				t.Errorf("Warnings = %q, want %d of them", analysis.Warnings, tt.expectedWarnings)
+			}
+		})
+	}
+}
+
+func TestAnalyzePackSet_InvalidRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		request       models.PackAnalysisRequest
+		expectedError error
+	}{
+		{
+			name:          "no packs",
+			request:       models.PackAnalysisRequest{},
+			expectedError: NoEnabledPacksError,
+		},
+		{
+			name:          "only disabled packs",
+			request:       models.PackAnalysisRequest{Packs: []models.PackDetails{{Size: 250, Disabled: true}}},
+			expectedError: NoEnabledPacksError,
+		},
+		{
+			name:          "invalid pack",
+			request:       models.PackAnalysisRequest{Packs: models.Packs{250, -1}.Details()},
+			expectedError: InvalidPackAnalysisError,
+		},
+		{
+			name:          "backwards range",
+			request:       models.PackAnalysisRequest{Packs: models.Packs{250}.Details(), From: 100, To: 10},
+			expectedError: InvalidPackAnalysisError,
+		},
+		{
+			name:          "range too large",
+			request:       models.PackAnalysisRequest{Packs: models.Packs{250}.Details(), From: 1, To: MaxAnalyzedCounts + 1},
+			expectedError: InvalidPackAnalysisError,
+		},
+		{
+			name:          "pack set too large to calculate",
+			request:       models.PackAnalysisRequest{Packs: models.Packs{19997, 20000}.Details()},
+			expectedError: orders.PackSetTooLargeError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := AnalyzePackSet(tt.request)
+			if !errors.Is(err, tt.expectedError) {
+				t.Errorf("AnalyzePackSet() error = %v, want %v", err, tt.expectedError)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package packs
+
+import "fmt"
+
+var PackSetVersionNotFoundError = fmt.Errorf("pack set version not found")
+var InvalidEffectiveFromError = fmt.Errorf("effective from is not valid")
+var PackNotFoundError = fmt.Errorf("pack not found")
+var PackExistsError = fmt.Errorf("pack already exists")
+var InvalidPackOperationError = fmt.Errorf("pack operation is not valid")
+var NoEnabledPacksError = fmt.Errorf("at least one pack has to stay enabled")
+var InvalidPackAnalysisError = fmt.Errorf("pack analysis request is not valid")
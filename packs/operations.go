@@ -0,0 +1,132 @@
+package packs
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/irreal/order-packs/models"
+	"github.com/irreal/order-packs/orders"
+)
+
+// adds the pack, or replaces everything about it if its size is already configured.
+// a pack without stock keeps the stock of its size unless it clears it, see models.WithLiveStock
+func (s *Service) PutPack(pack models.PackDetails, createdBy string) (*models.PackSetVersion, error) {
+	return s.ApplyPackOperations([]models.PackOperation{{Op: models.PackOperationPut, Pack: pack}}, createdBy)
+}
+
+// fails with PackNotFoundError if the size is not configured
+func (s *Service) DeletePack(size models.Pack, createdBy string) (*models.PackSetVersion, error) {
+	return s.ApplyPackOperations([]models.PackOperation{{Op: models.PackOperationRemove, Size: size}}, createdBy)
+}
+
+// disabled packs keep their details and stock, orders just stop using them until they are enabled again
+func (s *Service) SetPackEnabled(size models.Pack, enabled bool, createdBy string) (*models.PackSetVersion, error) {
+	op := models.PackOperationEnable
+	if !enabled {
+		op = models.PackOperationDisable
+	}
+	return s.ApplyPackOperations([]models.PackOperation{{Op: op, Size: size}}, createdBy)
+}
+
+// Applies the operations in order to the live packs and saves the result as a new version that is live right away.
+// It's all or nothing, if one operation fails none are saved
+func (s *Service) ApplyPackOperations(operations []models.PackOperation, createdBy string) (*models.PackSetVersion, error) {
+	if len(operations) == 0 {
+		return nil, fmt.Errorf("%w: no operations given", InvalidPackOperationError)
+	}
+	// bad requests are turned away before touching the repository
+	for _, operation := range operations {
+		if err := validatePackOperation(operation); err != nil {
+			return nil, err
+		}
+	}
+
+	// a change that came due goes first, the operations apply on top of it
+	if err := s.ActivateDue(); err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	version := &models.PackSetVersion{
+		CreatedAt:     now,
+		CreatedBy:     createdBy,
+		EffectiveFrom: now,
+		ActivatedAt:   &now,
+	}
+	err := s.repo.UpdatePacks(version, func(live []models.PackDetails) ([]models.PackDetails, error) {
+		return applyPackOperations(live, operations)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyPacksChanged()
+	return version, nil
+}
+
+func validatePackOperation(operation models.PackOperation) error {
+	switch operation.Op {
+	case models.PackOperationAdd, models.PackOperationPut:
+		if operation.Size != 0 && operation.Size != operation.Pack.Size {
+			return fmt.Errorf("%w: size %d does not match pack size %d", InvalidPackOperationError, operation.Size, operation.Pack.Size)
+		}
+		if err := operation.Pack.Validate(); err != nil {
+			return fmt.Errorf("%w: %w", InvalidPackOperationError, err)
+		}
+	case models.PackOperationRemove, models.PackOperationEnable, models.PackOperationDisable:
+		if operation.Size <= 0 {
+			return fmt.Errorf("%w: %s needs a positive size", InvalidPackOperationError, operation.Op)
+		}
+	default:
+		return fmt.Errorf("%w: unknown operation %q", InvalidPackOperationError, operation.Op)
+	}
+	return nil
+}
+
+// the packs after the operations, sorted by size. live is not modified
+func applyPackOperations(live []models.PackDetails, operations []models.PackOperation) ([]models.PackDetails, error) {
+	packs := slices.Clone(live)
+	for _, operation := range operations {
+		size := operation.Size
+		if operation.Op == models.PackOperationAdd || operation.Op == models.PackOperationPut {
+			size = operation.Pack.Size
+		}
+		index := slices.IndexFunc(packs, func(p models.PackDetails) bool { return p.Size == size })
+
+		switch operation.Op {
+		case models.PackOperationAdd:
+			if index >= 0 {
+				return nil, fmt.Errorf("%w: %d", PackExistsError, size)
+			}
+			packs = append(packs, operation.Pack)
+		case models.PackOperationPut:
+			// stock is counted in the warehouse, replacing the pack's details doesn't stop tracking it
+			pack := models.WithLiveStock([]models.PackDetails{operation.Pack}, packs)[0]
+			if index >= 0 {
+				packs[index] = pack
+			} else {
+				packs = append(packs, pack)
+			}
+		case models.PackOperationRemove, models.PackOperationEnable, models.PackOperationDisable:
+			if index < 0 {
+				return nil, fmt.Errorf("%w: %d", PackNotFoundError, size)
+			}
+			if operation.Op == models.PackOperationRemove {
+				packs = slices.Delete(packs, index, index+1)
+			} else {
+				packs[index].Disabled = operation.Op == models.PackOperationDisable
+			}
+		}
+	}
+
+	// orders need something to be packed in, that they can be calculated with
+	if len(models.EnabledPacks(packs)) == 0 {
+		return nil, NoEnabledPacksError
+	}
+	if err := orders.ValidatePackSetSize(packs); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(packs, func(a, b models.PackDetails) int { return int(a.Size - b.Size) })
+	return packs, nil
+}
@@ -0,0 +1,190 @@
+package packs
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/irreal/order-packs/models"
+	"github.com/irreal/order-packs/orders"
+)
+
+func TestService_ApplyPackOperations(t *testing.T) {
+	live := []models.PackDetails{{Size: 250, Stock: stock(10)}, {Size: 500, Name: "Party Pack"}}
+
+	tests := []struct {
+		name          string
+		operations    []models.PackOperation
+		expectedPacks []models.PackDetails
+		expectedError error
+	}{
+		{
+			name:          "add a new size",
+			operations:    []models.PackOperation{{Op: models.PackOperationAdd, Pack: models.PackDetails{Size: 100}}},
+			expectedPacks: []models.PackDetails{{Size: 100}, {Size: 250, Stock: stock(10)}, {Size: 500, Name: "Party Pack"}},
+		},
+		{
+			name:          "add an existing size",
+			operations:    []models.PackOperation{{Op: models.PackOperationAdd, Pack: models.PackDetails{Size: 250}}},
+			expectedError: PackExistsError,
+		},
+		{
+			name:          "put replaces an existing size",
+			operations:    []models.PackOperation{{Op: models.PackOperationPut, Pack: models.PackDetails{Size: 500, UnitCost: 60}}},
+			expectedPacks: []models.PackDetails{{Size: 250, Stock: stock(10)}, {Size: 500, UnitCost: 60}},
+		},
+		{
+			name:          "put keeps the stock when it's missing",
+			operations:    []models.PackOperation{{Op: models.PackOperationPut, Pack: models.PackDetails{Size: 250, UnitCost: 60}}},
+			expectedPacks: []models.PackDetails{{Size: 250, UnitCost: 60, Stock: stock(10)}, {Size: 500, Name: "Party Pack"}},
+		},
+		{
+			name:          "put sets the stock",
+			operations:    []models.PackOperation{{Op: models.PackOperationPut, Pack: models.PackDetails{Size: 250, Stock: stock(3)}}},
+			expectedPacks: []models.PackDetails{{Size: 250, Stock: stock(3)}, {Size: 500, Name: "Party Pack"}},
+		},
+		{
+			name:          "put with a null stock stops tracking it",
+			operations:    []models.PackOperation{{Op: models.PackOperationPut, Pack: models.PackDetails{Size: 250, ClearStock: true}}},
+			expectedPacks: []models.PackDetails{{Size: 250}, {Size: 500, Name: "Party Pack"}},
+		},
+		{
+			name:          "put adds a missing size",
+			operations:    []models.PackOperation{{Op: models.PackOperationPut, Pack: models.PackDetails{Size: 1000}}},
+			expectedPacks: []models.PackDetails{{Size: 250, Stock: stock(10)}, {Size: 500, Name: "Party Pack"}, {Size: 1000}},
+		},
+		{
+			name:          "remove a size",
+			operations:    []models.PackOperation{{Op: models.PackOperationRemove, Size: 250}},
+			expectedPacks: []models.PackDetails{{Size: 500, Name: "Party Pack"}},
+		},
+		{
+			name:          "remove a missing size",
+			operations:    []models.PackOperation{{Op: models.PackOperationRemove, Size: 1000}},
+			expectedError: PackNotFoundError,
+		},
+		{
+			name:          "disable keeps the details and stock",
+			operations:    []models.PackOperation{{Op: models.PackOperationDisable, Size: 250}},
+			expectedPacks: []models.PackDetails{{Size: 250, Stock: stock(10), Disabled: true}, {Size: 500, Name: "Party Pack"}},
+		},
+		{
+			name: "operations apply in order",
+			operations: []models.PackOperation{
+				{Op: models.PackOperationDisable, Size: 250},
+				{Op: models.PackOperationRemove, Size: 500},
+				{Op: models.PackOperationAdd, Pack: models.PackDetails{Size: 500, UnitCost: 10}},
+				{Op: models.PackOperationEnable, Size: 250},
+			},
+			expectedPacks: []models.PackDetails{{Size: 250, Stock: stock(10)}, {Size: 500, UnitCost: 10}},
+		},
+		{
+			name: "one failed operation saves nothing",
+			operations: []models.PackOperation{
+				{Op: models.PackOperationRemove, Size: 250},
+				{Op: models.PackOperationEnable, Size: 250},
+			},
+			expectedError: PackNotFoundError,
+		},
+		{
+			name: "no enabled packs left",
+			operations: []models.PackOperation{
+				{Op: models.PackOperationDisable, Size: 250},
+				{Op: models.PackOperationRemove, Size: 500},
+			},
+			expectedError: NoEnabledPacksError,
+		},
+		{
+			name:          "no operations",
+			operations:    []models.PackOperation{},
+			expectedError: InvalidPackOperationError,
+		},
+		{
+			name:          "unknown operation",
+			operations:    []models.PackOperation{{Op: "rename", Size: 250}},
+			expectedError: InvalidPackOperationError,
+		},
+		{
+			name:          "invalid pack",
+			operations:    []models.PackOperation{{Op: models.PackOperationPut, Pack: models.PackDetails{Size: 100, UnitCost: -1}}},
+			expectedError: InvalidPackOperationError,
+		},
+		{
+			name:          "pack set too large to calculate",
+			operations:    []models.PackOperation{{Op: models.PackOperationAdd, Pack: models.PackDetails{Size: orders.MaxSolverTableSize + 1}}},
+			expectedError: orders.PackSetTooLargeError,
+		},
+		{
+			name:          "size does not match the pack",
+			operations:    []models.PackOperation{{Op: models.PackOperationPut, Size: 100, Pack: models.PackDetails{Size: 200}}},
+			expectedError: InvalidPackOperationError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := NewMockPackRepository()
+			service := NewService(mockRepo)
+			if _, err := service.SavePacks(live, "tester"); err != nil {
+				t.Fatalf("SavePacks() unexpected error = %v", err)
+			}
+
+			calls := 0
+			service.OnPacksChanged(func() { calls++ })
+
+			version, err := service.ApplyPackOperations(tt.operations, "tester")
+
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Fatalf("ApplyPackOperations() error = %v, want %v", err, tt.expectedError)
+				}
+				// nothing changed
+				if details, _ := mockRepo.GetPackDetails(); !reflect.DeepEqual(details, live) || len(mockRepo.versions) != 1 || calls != 0 {
+					t.Errorf("ApplyPackOperations() failed but changed the packs to %+v", details)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyPackOperations() unexpected error = %v", err)
+			}
+
+			if version.ID != 2 || version.ActivatedAt == nil {
+				t.Errorf("ApplyPackOperations() version = %+v, want activated version 2", version)
+			}
+			details, _ := mockRepo.GetPackDetails()
+			if !reflect.DeepEqual(details, tt.expectedPacks) {
+				t.Errorf("ApplyPackOperations() packs = %+v, want %+v", details, tt.expectedPacks)
+			}
+			if calls != 1 {
+				t.Errorf("listener called %d times, want 1", calls)
+			}
+		})
+	}
+}
+
+func TestService_DisabledPacksAreNotUsed(t *testing.T) {
+	mockRepo := NewMockPackRepository()
+	service := NewService(mockRepo)
+	if _, err := service.SavePacks(models.Packs{250, 500, 1000}.Details(), "tester"); err != nil {
+		t.Fatalf("SavePacks() unexpected error = %v", err)
+	}
+
+	if _, err := service.SetPackEnabled(500, false, "tester"); err != nil {
+		t.Fatalf("SetPackEnabled() unexpected error = %v", err)
+	}
+	packs, _ := service.GetPacks()
+	if !reflect.DeepEqual(packs, models.Packs{250, 1000}) {
+		t.Errorf("GetPacks() with 500 disabled = %v, want [250 1000]", packs)
+	}
+
+	if _, err := service.SetPackEnabled(500, true, "tester"); err != nil {
+		t.Fatalf("SetPackEnabled() unexpected error = %v", err)
+	}
+	if _, err := service.DeletePack(1000, "tester"); err != nil {
+		t.Fatalf("DeletePack() unexpected error = %v", err)
+	}
+	packs, _ = service.GetPacks()
+	if !reflect.DeepEqual(packs, models.Packs{250, 500}) {
+		t.Errorf("GetPacks() after enabling 500 and deleting 1000 = %v, want [250 500]", packs)
+	}
+}
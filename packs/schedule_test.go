@@ -0,0 +1,153 @@
+package packs
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/irreal/order-packs/models"
+)
+
+func TestService_SchedulePacks(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	mockRepo := NewMockPackRepository()
+	service := NewService(mockRepo)
+	service.now = func() time.Time { return now }
+
+	calls := 0
+	service.OnPacksChanged(func() { calls++ })
+
+	if _, err := service.SavePacks([]models.PackDetails{{Size: 250, Stock: stock(10)}, {Size: 500, Stock: stock(3)}, {Size: 2000}}, "alice"); err != nil {
+		t.Fatalf("SavePacks() unexpected error = %v", err)
+	}
+	scheduled, err := service.SchedulePacks([]models.PackDetails{{Size: 250, Stock: stock(99)}, {Size: 500}, {Size: 1000, Stock: stock(5)}}, "bob", now.Add(48*time.Hour))
+	if err != nil {
+		t.Fatalf("SchedulePacks() unexpected error = %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("listener called %d times after scheduling, want 1", calls)
+	}
+
+	// nothing changes before it's due, it's only announced when it gets close
+	packs, _ := service.GetPacks()
+	if !reflect.DeepEqual(packs, models.Packs{250, 500, 2000}) {
+		t.Errorf("GetPacks() before the change = %v, want [250 500 2000]", packs)
+	}
+	if imminent, _ := service.GetImminentChange(); imminent != nil {
+		t.Errorf("GetImminentChange() two days ahead = %+v, want nil", imminent)
+	}
+	now = now.Add(36 * time.Hour)
+	if imminent, _ := service.GetImminentChange(); imminent == nil || imminent.ID != scheduled.ID {
+		t.Errorf("GetImminentChange() = %+v, want version %d", imminent, scheduled.ID)
+	}
+
+	// the future can be looked up before it happens
+	future, err := service.GetPacksAt(now.Add(24 * time.Hour))
+	if err != nil || !reflect.DeepEqual(future, models.Packs{250, 500, 1000}) {
+		t.Errorf("GetPacksAt() = %v, %v, want [250 500 1000]", future, err)
+	}
+
+	// reads leave it to the activation run, once that ran it is live with the stock it was scheduled with,
+	// live sizes scheduled without stock keep theirs
+	now = now.Add(12 * time.Hour)
+	if packs, _ := service.GetPacks(); !reflect.DeepEqual(packs, models.Packs{250, 500, 2000}) {
+		t.Errorf("GetPacks() before the activation run = %v, want [250 500 2000]", packs)
+	}
+	if calls != 1 {
+		t.Errorf("listener called %d times by reads, want 1", calls)
+	}
+	if err := service.ActivateDue(); err != nil {
+		t.Fatalf("ActivateDue() unexpected error = %v", err)
+	}
+	details, err := service.GetPackDetails()
+	if err != nil {
+		t.Fatalf("GetPackDetails() unexpected error = %v", err)
+	}
+	want := []models.PackDetails{{Size: 250, Stock: stock(99)}, {Size: 500, Stock: stock(3)}, {Size: 1000, Stock: stock(5)}}
+	if !reflect.DeepEqual(details, want) {
+		t.Errorf("GetPackDetails() after the change = %+v, want %+v", details, want)
+	}
+	if calls != 2 {
+		t.Errorf("listener called %d times after the change, want 2", calls)
+	}
+	if upcoming, _ := service.GetUpcomingChanges(); len(upcoming) != 0 {
+		t.Errorf("GetUpcomingChanges() = %+v, want none", upcoming)
+	}
+
+	// the past is still there
+	past, err := service.GetPacksAt(now.Add(-time.Hour))
+	if err != nil || !reflect.DeepEqual(past, models.Packs{250, 500, 2000}) {
+		t.Errorf("GetPacksAt() an hour ago = %v, %v, want [250 500 2000]", past, err)
+	}
+	if _, err := service.GetPacksAt(now.Add(-30 * 24 * time.Hour)); !errors.Is(err, PackSetVersionNotFoundError) {
+		t.Errorf("GetPacksAt() before any packs error = %v, want %v", err, PackSetVersionNotFoundError)
+	}
+}
+
+func TestService_SchedulePacks_NotInTheFuture(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	service := NewService(NewMockPackRepository())
+	service.now = func() time.Time { return now }
+
+	for _, effectiveFrom := range []time.Time{now, now.Add(-time.Minute)} {
+		if _, err := service.SchedulePacks(models.Packs{250}.Details(), "bob", effectiveFrom); !errors.Is(err, InvalidEffectiveFromError) {
+			t.Errorf("SchedulePacks(%s) error = %v, want %v", effectiveFrom, err, InvalidEffectiveFromError)
+		}
+	}
+}
+
+func TestService_SchedulePacks_LaterSaveWins(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	service := NewService(NewMockPackRepository())
+	service.now = func() time.Time { return now }
+
+	service.SavePacks(models.Packs{250}.Details(), "alice")
+	service.SchedulePacks(models.Packs{500}.Details(), "bob", now.Add(time.Hour))
+
+	// the scheduled change comes due right before an immediate save, nothing asked for packs in between
+	now = now.Add(2 * time.Hour)
+	if _, err := service.SavePacks(models.Packs{1000}.Details(), "carol"); err != nil {
+		t.Fatalf("SavePacks() unexpected error = %v", err)
+	}
+
+	packs, _ := service.GetPacks()
+	if !reflect.DeepEqual(packs, models.Packs{1000}) {
+		t.Errorf("GetPacks() = %v, want the later save [1000]", packs)
+	}
+}
+
+func TestService_RunActivation(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	service := NewService(NewMockPackRepository())
+	service.now = func() time.Time { return now }
+
+	service.SavePacks(models.Packs{250}.Details(), "alice")
+	service.SchedulePacks(models.Packs{500}.Details(), "bob", now.Add(time.Hour))
+	now = now.Add(2 * time.Hour)
+
+	activated := make(chan struct{}, 1)
+	service.OnPacksChanged(func() { activated <- struct{}{} })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		service.RunActivation(ctx, time.Hour, func(err error) { t.Errorf("RunActivation() unexpected error = %v", err) })
+		close(done)
+	}()
+
+	// the first run doesn't wait for the interval
+	select {
+	case <-activated:
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunActivation() didn't activate the change that came due")
+	}
+	cancel()
+	<-done
+
+	packs, _ := service.GetPacks()
+	if !reflect.DeepEqual(packs, models.Packs{500}) {
+		t.Errorf("GetPacks() = %v, want the scheduled [500]", packs)
+	}
+}
@@ -1,26 +1,210 @@
 package packs
 
-import "github.com/irreal/order-packs/models"
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/irreal/order-packs/models"
+	"github.com/irreal/order-packs/orders"
+)
+
+// how far ahead a scheduled change is announced on the order page
+const ImminentChangeWindow = 24 * time.Hour
+
+// how often RunActivation looks for scheduled changes that came due
+const ActivationInterval = 10 * time.Second
 
 type Service struct {
 	repo PackRepository
+	now  func() time.Time
+
+	listenersMu sync.RWMutex
+	listeners   []func()
 }
 
 type PackRepository interface {
 	GetPacks() (models.Packs, error)
-	SavePacks(packs models.Packs) error
+	GetPackDetails() ([]models.PackDetails, error)
+	// the live packs like GetPackDetails, and the ID of the version they were set by, 0 before one was activated.
+	// they are read together, so they always match
+	GetLivePackSet() ([]models.PackDetails, int64, error)
+	// records the version, setting its ID. when it's already activated its packs become the live pack set,
+	// scheduled versions wait for ActivatePackSets
+	SavePacks(version *models.PackSetVersion) error
+	// saves an activated version whose packs are update applied to the live packs, in one transaction,
+	// so changes made at the same time can't overwrite each other. nothing is saved when update fails
+	UpdatePacks(version *models.PackSetVersion, update func(live []models.PackDetails) ([]models.PackDetails, error)) error
+	// makes the newest scheduled version that is due by now live, unless a newer one already is,
+	// and marks every due one as activated. reports whether the live packs changed
+	ActivatePackSets(now time.Time) (bool, error)
+	// the version in effect at the given time, including scheduled ones.
+	// fails with PackSetVersionNotFoundError if there was none yet
+	GetPackSetVersionAt(at time.Time) (*models.PackSetVersion, error)
+	// versions that haven't gone live yet, soonest first
+	GetScheduledPackSets() ([]*models.PackSetVersion, error)
+	GetPackSetVersions() ([]*models.PackSetVersion, error)
+	// fails with PackSetVersionNotFoundError if there is no such version
+	GetPackSetVersion(id int64) (*models.PackSetVersion, error)
 }
 
 func NewService(repo PackRepository) *Service {
 	return &Service{
 		repo: repo,
+		now:  time.Now,
 	}
 }
 
+// sizes orders can use now. reads don't make scheduled changes live, see ActivateDue
 func (s *Service) GetPacks() (models.Packs, error) {
 	return s.repo.GetPacks()
 }
 
-func (s *Service) SavePacks(packs models.Packs) error {
-	return s.repo.SavePacks(packs)
+// live packs with their details and current stock, disabled ones included. see GetPacks
+func (s *Service) GetPackDetails() ([]models.PackDetails, error) {
+	return s.repo.GetPackDetails()
+}
+
+// the live packs like GetPackDetails, with the ID of their version. orders record it as what they were calculated with
+func (s *Service) GetLivePackSet() ([]models.PackDetails, int64, error) {
+	return s.repo.GetLivePackSet()
+}
+
+// sizes orders could use at the given time, past or future, like GetPacks
+func (s *Service) GetPacksAt(at time.Time) (models.Packs, error) {
+	version, err := s.GetVersionAt(at)
+	if err != nil {
+		return nil, err
+	}
+	return models.PackSizes(models.EnabledPacks(version.Packs)), nil
+}
+
+// the version in effect at the given time, scheduled changes included.
+// fails with PackSetVersionNotFoundError before the first version
+func (s *Service) GetVersionAt(at time.Time) (*models.PackSetVersion, error) {
+	return s.repo.GetPackSetVersionAt(at)
+}
+
+// Replaces the pack set right away, createdBy is whoever made the change and is kept with the new version.
+// Packs without stock keep the live stock of their size, unless they clear it, see models.WithLiveStock.
+// fails with orders.PackSetTooLargeError when orders couldn't be calculated with the packs
+func (s *Service) SavePacks(packs []models.PackDetails, createdBy string) (*models.PackSetVersion, error) {
+	now := s.now().UTC()
+	return s.saveVersion(&models.PackSetVersion{
+		CreatedAt:     now,
+		CreatedBy:     createdBy,
+		EffectiveFrom: now,
+		ActivatedAt:   &now,
+	}, func(live []models.PackDetails) []models.PackDetails {
+		return models.WithLiveStock(packs, live)
+	})
+}
+
+// Saves a pack set that takes over at effectiveFrom, which has to be in the future.
+// Until then orders keep using the live packs. When it goes live, the stock it was scheduled with is applied,
+// sizes that are live then and were scheduled without stock keep theirs. it can't stop tracking the stock of a live size
+func (s *Service) SchedulePacks(packs []models.PackDetails, createdBy string, effectiveFrom time.Time) (*models.PackSetVersion, error) {
+	now := s.now().UTC()
+	if !effectiveFrom.After(now) {
+		return nil, fmt.Errorf("%w: %s is not in the future", InvalidEffectiveFromError, effectiveFrom.Format(time.RFC3339))
+	}
+	if err := orders.ValidatePackSetSize(packs); err != nil {
+		return nil, err
+	}
+
+	version := &models.PackSetVersion{
+		CreatedAt:     now,
+		CreatedBy:     createdBy,
+		EffectiveFrom: effectiveFrom.UTC(),
+		Packs:         packs,
+	}
+	if err := s.repo.SavePacks(version); err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// scheduled pack sets that haven't gone live yet, soonest first
+func (s *Service) GetUpcomingChanges() ([]*models.PackSetVersion, error) {
+	return s.repo.GetScheduledPackSets()
+}
+
+// the next scheduled change if it happens within ImminentChangeWindow, nil otherwise
+func (s *Service) GetImminentChange() (*models.PackSetVersion, error) {
+	upcoming, err := s.GetUpcomingChanges()
+	if err != nil || len(upcoming) == 0 {
+		return nil, err
+	}
+	if upcoming[0].EffectiveFrom.After(s.now().Add(ImminentChangeWindow)) {
+		return nil, nil
+	}
+	return upcoming[0], nil
+}
+
+// saves version, live right away, with the packs made from the live ones. reading and saving is one transaction,
+// so stock taken by orders in the meantime isn't put back
+func (s *Service) saveVersion(version *models.PackSetVersion, packs func(live []models.PackDetails) []models.PackDetails) (*models.PackSetVersion, error) {
+	// a change that came due in the meantime goes first, so it doesn't override this one later
+	if err := s.ActivateDue(); err != nil {
+		return nil, err
+	}
+	err := s.repo.UpdatePacks(version, func(live []models.PackDetails) ([]models.PackDetails, error) {
+		packs := packs(live)
+		if err := orders.ValidatePackSetSize(packs); err != nil {
+			return nil, err
+		}
+		return packs, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyPacksChanged()
+	return version, nil
+}
+
+// Makes scheduled pack sets that came due live. Reads leave that to RunActivation,
+// anything that writes against the live packs calls this first so it doesn't wait for the next run
+func (s *Service) ActivateDue() error {
+	activated, err := s.repo.ActivatePackSets(s.now().UTC())
+	if err != nil {
+		return err
+	}
+	if activated {
+		s.notifyPacksChanged()
+	}
+	return nil
+}
+
+// Runs ActivateDue right away and then every interval until ctx is done. errors go to onError, the next run tries again
+func (s *Service) RunActivation(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.ActivateDue(); err != nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// registers a callback that runs after every successful change of the pack set,
+// used to drop anything cached for the previous set
+func (s *Service) OnPacksChanged(listener func()) {
+	s.listenersMu.Lock()
+	defer s.listenersMu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *Service) notifyPacksChanged() {
+	s.listenersMu.RLock()
+	defer s.listenersMu.RUnlock()
+	for _, listener := range s.listeners {
+		listener()
+	}
 }
@@ -1,42 +1,145 @@
 package packs
 
 import (
+	"encoding/json"
 	"errors"
 	"reflect"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/irreal/order-packs/models"
 )
 
 type MockPackRepository struct {
-	packs          models.Packs
+	packs          []models.PackDetails
+	versions       []*models.PackSetVersion
 	getPacksError  error
 	savePacksError error
 }
 
 func NewMockPackRepository() *MockPackRepository {
 	return &MockPackRepository{
-		packs: make(models.Packs, 0),
+		packs: make([]models.PackDetails, 0),
 	}
 }
 
 func (m *MockPackRepository) GetPacks() (models.Packs, error) {
+	if m.getPacksError != nil {
+		return nil, m.getPacksError
+	}
+	return models.PackSizes(models.EnabledPacks(m.packs)), nil
+}
+
+func (m *MockPackRepository) GetPackDetails() ([]models.PackDetails, error) {
 	if m.getPacksError != nil {
 		return nil, m.getPacksError
 	}
 	return m.packs, nil
 }
 
-func (m *MockPackRepository) SavePacks(packs models.Packs) error {
+func (m *MockPackRepository) GetLivePackSet() ([]models.PackDetails, int64, error) {
+	if m.getPacksError != nil {
+		return nil, 0, m.getPacksError
+	}
+	var versionID int64
+	if live := LiveVersion(m.versions); live != nil {
+		versionID = live.ID
+	}
+	return m.packs, versionID, nil
+}
+
+func (m *MockPackRepository) SavePacks(version *models.PackSetVersion) error {
 	if m.savePacksError != nil {
 		return m.savePacksError
 	}
-	m.packs = packs
+	version.ID = int64(len(m.versions) + 1)
+	m.versions = append(m.versions, version)
+	if version.ActivatedAt != nil {
+		m.packs = version.Packs
+	}
 	return nil
 }
 
-func (m *MockPackRepository) SetPacks(packs models.Packs) {
+func (m *MockPackRepository) UpdatePacks(version *models.PackSetVersion, update func(live []models.PackDetails) ([]models.PackDetails, error)) error {
+	if m.savePacksError != nil {
+		return m.savePacksError
+	}
+	packs, err := update(m.packs)
+	if err != nil {
+		return err
+	}
+	version.Packs = packs
+	return m.SavePacks(version)
+}
+
+func (m *MockPackRepository) ActivatePackSets(now time.Time) (bool, error) {
+	live := LiveVersion(m.versions)
+	var due *models.PackSetVersion
+	for _, version := range m.versions {
+		if version.ActivatedAt == nil && !version.EffectiveFrom.After(now) {
+			version.ActivatedAt = &now
+			due = version
+		}
+	}
+	if due == nil || LiveVersion(m.versions) == live {
+		return false, nil
+	}
+
+	// live sizes scheduled without stock keep theirs
+	packs := slices.Clone(due.Packs)
+	for i, pack := range packs {
+		for _, current := range m.packs {
+			if current.Size == pack.Size && pack.Stock == nil {
+				packs[i].Stock = current.Stock
+			}
+		}
+	}
 	m.packs = packs
+	return true, nil
+}
+
+func (m *MockPackRepository) GetPackSetVersionAt(at time.Time) (*models.PackSetVersion, error) {
+	var found *models.PackSetVersion
+	for _, version := range m.versions {
+		if !version.EffectiveFrom.After(at) && (found == nil || !version.EffectiveFrom.Before(found.EffectiveFrom)) {
+			found = version
+		}
+	}
+	if found == nil {
+		return nil, PackSetVersionNotFoundError
+	}
+	return found, nil
+}
+
+func (m *MockPackRepository) GetScheduledPackSets() ([]*models.PackSetVersion, error) {
+	var scheduled []*models.PackSetVersion
+	for _, version := range m.versions {
+		if version.ActivatedAt == nil {
+			scheduled = append(scheduled, version)
+		}
+	}
+	slices.SortStableFunc(scheduled, func(a, b *models.PackSetVersion) int {
+		return a.EffectiveFrom.Compare(b.EffectiveFrom)
+	})
+	return scheduled, nil
+}
+
+func (m *MockPackRepository) GetPackSetVersions() ([]*models.PackSetVersion, error) {
+	versions := slices.Clone(m.versions)
+	slices.Reverse(versions)
+	return versions, nil
+}
+
+func (m *MockPackRepository) GetPackSetVersion(id int64) (*models.PackSetVersion, error) {
+	if id < 1 || id > int64(len(m.versions)) {
+		return nil, PackSetVersionNotFoundError
+	}
+	return m.versions[id-1], nil
+}
+
+func (m *MockPackRepository) SetPacks(packs models.Packs) {
+	m.packs = packs.Details()
 }
 
 func (m *MockPackRepository) SetGetPacksError(err error) {
@@ -48,7 +151,7 @@ func (m *MockPackRepository) SetSavePacksError(err error) {
 }
 
 func (m *MockPackRepository) Reset() {
-	m.packs = make(models.Packs, 0)
+	m.packs = make([]models.PackDetails, 0)
 	m.getPacksError = nil
 	m.savePacksError = nil
 }
@@ -152,7 +255,7 @@ func TestService_SavePacks(t *testing.T) {
 			mockRepo := NewMockPackRepository()
 			service := NewService(mockRepo)
 
-			err := service.SavePacks(tt.packsToSave)
+			_, err := service.SavePacks(tt.packsToSave.Details(), "tester")
 
 			if err != nil {
 				t.Fatalf("SavePacks() unexpected error = %v", err)
@@ -173,7 +276,7 @@ func TestService_SavePacks_RepositoryError(t *testing.T) {
 	service := NewService(mockRepo)
 
 	packsToSave := models.Packs{250, 500, 1000}
-	err := service.SavePacks(packsToSave)
+	_, err := service.SavePacks(packsToSave.Details(), "tester")
 
 	// return error when repository fails
 	if err == nil {
@@ -194,7 +297,7 @@ func TestService_SaveAndRetrievePacks(t *testing.T) {
 	originalPacks := models.Packs{100, 250, 500, 1000}
 
 	// save packs
-	err := service.SavePacks(originalPacks)
+	_, err := service.SavePacks(originalPacks.Details(), "tester")
 	if err != nil {
 		t.Fatalf("SavePacks() unexpected error = %v", err)
 	}
@@ -210,3 +313,86 @@ func TestService_SaveAndRetrievePacks(t *testing.T) {
 		t.Errorf("Retrieved packs = %v, want %v", retrievedPacks, originalPacks)
 	}
 }
+
+func TestService_SavePacks_NotifiesListeners(t *testing.T) {
+	mockRepo := NewMockPackRepository()
+	service := NewService(mockRepo)
+
+	calls := 0
+	service.OnPacksChanged(func() { calls++ })
+
+	if _, err := service.SavePacks(models.Packs{250, 500}.Details(), "tester"); err != nil {
+		t.Fatalf("SavePacks() unexpected error = %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("listener called %d times, want 1", calls)
+	}
+
+	// failed saves do not notify
+	mockRepo.SetSavePacksError(errors.New("disk full"))
+	if _, err := service.SavePacks(models.Packs{250}.Details(), "tester"); err == nil {
+		t.Fatal("SavePacks() expected error when repository fails")
+	}
+	if calls != 1 {
+		t.Errorf("listener called %d times after failed save, want 1", calls)
+	}
+}
+
+func TestService_SaveAndRetrievePackDetails(t *testing.T) {
+	mockRepo := NewMockPackRepository()
+	service := NewService(mockRepo)
+
+	originalPacks := []models.PackDetails{
+		{Size: 250, SKU: "BLN-0250", Name: "Party Pack", UnitCost: 40, TareWeight: 30, Dimensions: models.Dimensions{Length: 200, Width: 150, Height: 50}},
+		{Size: 500, SKU: "BLN-0500", Name: "Party Pack XL", UnitCost: 60, TareWeight: 50},
+	}
+
+	if _, err := service.SavePacks(originalPacks, "tester"); err != nil {
+		t.Fatalf("SavePacks() unexpected error = %v", err)
+	}
+
+	retrievedPacks, err := service.GetPackDetails()
+	if err != nil {
+		t.Fatalf("GetPackDetails() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(retrievedPacks, originalPacks) {
+		t.Errorf("Retrieved pack details = %v, want %v", retrievedPacks, originalPacks)
+	}
+
+	// sizes are still available on their own for the calculator
+	sizes, err := service.GetPacks()
+	if err != nil {
+		t.Fatalf("GetPacks() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(sizes, models.Packs{250, 500}) {
+		t.Errorf("GetPacks() = %v, want %v", sizes, models.Packs{250, 500})
+	}
+}
+
+// stock is counted in the warehouse, saving packs without it must not stop tracking it
+func TestService_SavePacks_KeepsLiveStock(t *testing.T) {
+	mockRepo := NewMockPackRepository()
+	service := NewService(mockRepo)
+	if _, err := service.SavePacks([]models.PackDetails{{Size: 250, Stock: stock(10)}, {Size: 500, Stock: stock(5)}, {Size: 1000, Stock: stock(2)}}, "tester"); err != nil {
+		t.Fatalf("SavePacks() unexpected error = %v", err)
+	}
+
+	// bare sizes and packs without stock keep it, a null stock stops tracking it, new sizes without stock aren't tracked
+	var packs []models.PackDetails
+	if err := json.Unmarshal([]byte(`[250, {"size": 500, "unitCost": 60}, {"size": 1000, "stock": null}, {"size": 2000}, {"size": 5000, "stock": 1}]`), &packs); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	version, err := service.SavePacks(packs, "tester")
+	if err != nil {
+		t.Fatalf("SavePacks() unexpected error = %v", err)
+	}
+
+	expected := []models.PackDetails{{Size: 250, Stock: stock(10)}, {Size: 500, UnitCost: 60, Stock: stock(5)}, {Size: 1000}, {Size: 2000}, {Size: 5000, Stock: stock(1)}}
+	if details, _ := service.GetPackDetails(); !reflect.DeepEqual(details, expected) {
+		t.Errorf("GetPackDetails() = %+v, want %+v", details, expected)
+	}
+	// the version shows the stock the packs went live with
+	if !reflect.DeepEqual(version.Packs, expected) {
+		t.Errorf("version packs = %+v, want %+v", version.Packs, expected)
+	}
+}
@@ -0,0 +1,117 @@
+package packs
+
+import (
+	"slices"
+
+	"github.com/irreal/order-packs/models"
+)
+
+// every version of the pack set, newest first
+func (s *Service) GetVersions() ([]*models.PackSetVersion, error) {
+	return s.repo.GetPackSetVersions()
+}
+
+// fails with PackSetVersionNotFoundError if there is no such version
+func (s *Service) GetVersion(id int64) (*models.PackSetVersion, error) {
+	return s.repo.GetPackSetVersion(id)
+}
+
+// what changed between two versions, packs are matched by size
+func (s *Service) DiffVersions(from, to int64) (*models.PackSetDiff, error) {
+	fromVersion, err := s.repo.GetPackSetVersion(from)
+	if err != nil {
+		return nil, err
+	}
+	toVersion, err := s.repo.GetPackSetVersion(to)
+	if err != nil {
+		return nil, err
+	}
+	return diffPackSets(fromVersion, toVersion), nil
+}
+
+// Makes an older version live again by saving it as a new version, so history is never rewritten.
+// Stock is operational rather than configuration, sizes that are live keep their current stock,
+// only sizes that come back get the stock their version was saved with
+func (s *Service) Rollback(id int64, createdBy string) (*models.PackSetVersion, error) {
+	if err := s.ActivateDue(); err != nil {
+		return nil, err
+	}
+	target, err := s.repo.GetPackSetVersion(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	return s.saveVersion(&models.PackSetVersion{
+		CreatedAt:      now,
+		CreatedBy:      createdBy,
+		RolledBackFrom: &target.ID,
+		EffectiveFrom:  now,
+		ActivatedAt:    &now,
+	}, func(live []models.PackDetails) []models.PackDetails {
+		packs := slices.Clone(target.Packs)
+		for i, pack := range packs {
+			index := slices.IndexFunc(live, func(p models.PackDetails) bool { return p.Size == pack.Size })
+			if index >= 0 {
+				packs[i].Stock = live[index].Stock
+			}
+		}
+		return packs
+	})
+}
+
+// the version whose packs are live, the newest to take effect of the activated ones. nil when there are none
+func LiveVersion(versions []*models.PackSetVersion) *models.PackSetVersion {
+	var live *models.PackSetVersion
+	for _, version := range versions {
+		if version.ActivatedAt == nil {
+			continue
+		}
+		if live == nil || version.EffectiveFrom.After(live.EffectiveFrom) ||
+			(version.EffectiveFrom.Equal(live.EffectiveFrom) && version.ID > live.ID) {
+			live = version
+		}
+	}
+	return live
+}
+
+func diffPackSets(from, to *models.PackSetVersion) *models.PackSetDiff {
+	diff := &models.PackSetDiff{
+		From:    from.ID,
+		To:      to.ID,
+		Added:   []models.PackDetails{},
+		Removed: []models.PackDetails{},
+		Changed: []models.PackChange{},
+	}
+
+	before := make(map[models.Pack]models.PackDetails, len(from.Packs))
+	for _, pack := range from.Packs {
+		before[pack.Size] = pack
+	}
+	for _, pack := range to.Packs {
+		old, found := before[pack.Size]
+		if !found {
+			diff.Added = append(diff.Added, pack)
+			continue
+		}
+		delete(before, pack.Size)
+		if !samePack(old, pack) {
+			diff.Changed = append(diff.Changed, models.PackChange{Size: pack.Size, Before: old, After: pack})
+		}
+	}
+	for _, pack := range from.Packs {
+		if _, removed := before[pack.Size]; removed {
+			diff.Removed = append(diff.Removed, pack)
+		}
+	}
+	return diff
+}
+
+// stock is a pointer, so packs can't be compared with ==
+func samePack(a, b models.PackDetails) bool {
+	if (a.Stock == nil) != (b.Stock == nil) || (a.Stock != nil && *a.Stock != *b.Stock) {
+		return false
+	}
+	a.Stock, b.Stock = nil, nil
+	return a == b
+}
@@ -0,0 +1,130 @@
+package packs
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/irreal/order-packs/models"
+)
+
+func stock(level int) *int {
+	return &level
+}
+
+func TestService_SavePacks_CreatesVersions(t *testing.T) {
+	service := NewService(NewMockPackRepository())
+
+	first, err := service.SavePacks(models.Packs{250, 500}.Details(), "alice")
+	if err != nil {
+		t.Fatalf("SavePacks() unexpected error = %v", err)
+	}
+	second, err := service.SavePacks(models.Packs{250, 1000}.Details(), "bob")
+	if err != nil {
+		t.Fatalf("SavePacks() unexpected error = %v", err)
+	}
+	if first.ID == second.ID {
+		t.Fatalf("both saves got version %d", first.ID)
+	}
+
+	versions, err := service.GetVersions()
+	if err != nil {
+		t.Fatalf("GetVersions() unexpected error = %v", err)
+	}
+	if len(versions) != 2 || versions[0].ID != second.ID || versions[1].ID != first.ID {
+		t.Fatalf("GetVersions() = %+v, want versions %d and %d, newest first", versions, second.ID, first.ID)
+	}
+	if versions[1].CreatedBy != "alice" || !reflect.DeepEqual(models.PackSizes(versions[1].Packs), models.Packs{250, 500}) {
+		t.Errorf("first version = %+v, want alice's 250 and 500", versions[1])
+	}
+
+	if _, err := service.GetVersion(99); !errors.Is(err, PackSetVersionNotFoundError) {
+		t.Errorf("GetVersion() error = %v, want %v", err, PackSetVersionNotFoundError)
+	}
+}
+
+func TestService_DiffVersions(t *testing.T) {
+	service := NewService(NewMockPackRepository())
+
+	from, _ := service.SavePacks([]models.PackDetails{
+		{Size: 250, UnitCost: 40},
+		{Size: 500, UnitCost: 60},
+		{Size: 1000, UnitCost: 100, Stock: stock(5)},
+	}, "alice")
+	to, _ := service.SavePacks([]models.PackDetails{
+		{Size: 250, UnitCost: 40},
+		{Size: 1000, UnitCost: 100, Stock: stock(7)},
+		{Size: 2000, UnitCost: 180},
+	}, "bob")
+
+	diff, err := service.DiffVersions(from.ID, to.ID)
+	if err != nil {
+		t.Fatalf("DiffVersions() unexpected error = %v", err)
+	}
+
+	want := &models.PackSetDiff{
+		From:    from.ID,
+		To:      to.ID,
+		Added:   []models.PackDetails{{Size: 2000, UnitCost: 180}},
+		Removed: []models.PackDetails{{Size: 500, UnitCost: 60}},
+		Changed: []models.PackChange{{
+			Size:   1000,
+			Before: models.PackDetails{Size: 1000, UnitCost: 100, Stock: stock(5)},
+			After:  models.PackDetails{Size: 1000, UnitCost: 100, Stock: stock(7)},
+		}},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("DiffVersions() = %+v, want %+v", diff, want)
+	}
+
+	if _, err := service.DiffVersions(from.ID, 99); !errors.Is(err, PackSetVersionNotFoundError) {
+		t.Errorf("DiffVersions() error = %v, want %v", err, PackSetVersionNotFoundError)
+	}
+}
+
+func TestService_Rollback(t *testing.T) {
+	mockRepo := NewMockPackRepository()
+	service := NewService(mockRepo)
+
+	calls := 0
+	service.OnPacksChanged(func() { calls++ })
+
+	old, _ := service.SavePacks([]models.PackDetails{
+		{Size: 250, Name: "Party Pack", Stock: stock(10)},
+		{Size: 500, Stock: stock(20)},
+	}, "alice")
+	service.SavePacks([]models.PackDetails{
+		{Size: 250, Name: "Renamed", Stock: stock(3)},
+		{Size: 1000},
+	}, "bob")
+
+	restored, err := service.Rollback(old.ID, "carol")
+	if err != nil {
+		t.Fatalf("Rollback() unexpected error = %v", err)
+	}
+	if restored.ID == old.ID || restored.RolledBackFrom == nil || *restored.RolledBackFrom != old.ID || restored.CreatedBy != "carol" {
+		t.Errorf("Rollback() = %+v, want a new version by carol restoring %d", restored, old.ID)
+	}
+
+	// 250 is live and keeps its current stock, 500 comes back with the stock it was saved with
+	want := []models.PackDetails{
+		{Size: 250, Name: "Party Pack", Stock: stock(3)},
+		{Size: 500, Stock: stock(20)},
+	}
+	live, _ := service.GetPackDetails()
+	if !reflect.DeepEqual(live, want) {
+		t.Errorf("live packs after Rollback() = %+v, want %+v", live, want)
+	}
+	if calls != 3 {
+		t.Errorf("listener called %d times, want 3", calls)
+	}
+
+	// the old version itself is untouched
+	if *old.Packs[0].Stock != 10 {
+		t.Errorf("old version stock changed to %d", *old.Packs[0].Stock)
+	}
+
+	if _, err := service.Rollback(99, "carol"); !errors.Is(err, PackSetVersionNotFoundError) {
+		t.Errorf("Rollback() error = %v, want %v", err, PackSetVersionNotFoundError)
+	}
+}
@@ -0,0 +1,329 @@
+package postgres
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/irreal/order-packs/models"
+)
+
+// A versioned schema change, like the sqlite ones in db/migrations.go. Postgres support started with the schema as it was then,
+// so its first migration creates all of it and the versions of the two databases don't line up
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+// in version order, versions start at 1 and have no gaps. only ever append, a released migration never changes
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create schema",
+		up: `
+		CREATE TABLE packs (
+			id BIGSERIAL PRIMARY KEY,
+			size BIGINT NOT NULL UNIQUE,
+			sku TEXT NOT NULL DEFAULT '',
+			name TEXT NOT NULL DEFAULT '',
+			unit_cost BIGINT NOT NULL DEFAULT 0,
+			tare_weight BIGINT NOT NULL DEFAULT 0,
+			length BIGINT NOT NULL DEFAULT 0,
+			width BIGINT NOT NULL DEFAULT 0,
+			height BIGINT NOT NULL DEFAULT 0,
+			stock BIGINT,
+			disabled BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+
+		-- every save of the packs is kept as a version, the packs table only has the live set
+		CREATE TABLE pack_set_versions (
+			id BIGSERIAL PRIMARY KEY,
+			created_at TIMESTAMPTZ NOT NULL,
+			created_by TEXT NOT NULL DEFAULT '',
+			rolled_back_from BIGINT REFERENCES pack_set_versions(id),
+			effective_from TIMESTAMPTZ NOT NULL,
+			activated_at TIMESTAMPTZ
+		);
+		CREATE INDEX idx_pack_set_versions_effective_from ON pack_set_versions(effective_from, id);
+
+		CREATE TABLE pack_set_version_packs (
+			version_id BIGINT NOT NULL REFERENCES pack_set_versions(id),
+			size BIGINT NOT NULL,
+			sku TEXT NOT NULL DEFAULT '',
+			name TEXT NOT NULL DEFAULT '',
+			unit_cost BIGINT NOT NULL DEFAULT 0,
+			tare_weight BIGINT NOT NULL DEFAULT 0,
+			length BIGINT NOT NULL DEFAULT 0,
+			width BIGINT NOT NULL DEFAULT 0,
+			height BIGINT NOT NULL DEFAULT 0,
+			stock BIGINT,
+			disabled BOOLEAN NOT NULL DEFAULT FALSE,
+			PRIMARY KEY (version_id, size)
+		);
+
+		CREATE TABLE orders (
+			id BIGSERIAL PRIMARY KEY,
+			public_id TEXT NOT NULL UNIQUE,
+			requested_item_count BIGINT NOT NULL,
+			shipped_item_count BIGINT NOT NULL,
+			packs_json TEXT NOT NULL,
+			packaging_cost BIGINT NOT NULL DEFAULT 0,
+			shipment_weight BIGINT NOT NULL DEFAULT 0,
+			status TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			pack_set_version BIGINT REFERENCES pack_set_versions(id),
+			explanation_json TEXT,
+			shipment_json TEXT,
+			packaging_json TEXT,
+			backorder_of BIGINT REFERENCES orders(id)
+		);
+		CREATE INDEX idx_orders_created_at ON orders(created_at DESC);
+		CREATE INDEX idx_orders_backorder_of ON orders(backorder_of);
+
+		-- order history filters and sorts, every sort is broken by id so it's part of the indexes
+		CREATE INDEX idx_orders_status ON orders(status, id);
+		CREATE INDEX idx_orders_requested_item_count ON orders(requested_item_count, id);
+		CREATE INDEX idx_orders_shipped_item_count ON orders(shipped_item_count, id);
+
+		-- packs of every order as rows, for filtering history by pack size
+		CREATE TABLE order_packs (
+			order_id BIGINT NOT NULL REFERENCES orders(id),
+			pack_size BIGINT NOT NULL,
+			count BIGINT NOT NULL,
+			PRIMARY KEY (order_id, pack_size)
+		);
+		CREATE INDEX idx_order_packs_pack_size ON order_packs(pack_size, order_id);
+
+		-- responses of requests sent with an Idempotency-Key, status_code is 0 until the first request is done
+		CREATE TABLE idempotency_keys (
+			key TEXT PRIMARY KEY,
+			request_hash TEXT NOT NULL,
+			status_code INTEGER NOT NULL DEFAULT 0,
+			response_body BYTEA,
+			created_at TIMESTAMPTZ NOT NULL,
+			expires_at TIMESTAMPTZ NOT NULL,
+			locked_until TIMESTAMPTZ NOT NULL
+		);
+		CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+		-- products have their own packs, multi-line orders keep every line next to the totals in orders
+		CREATE TABLE products (
+			id BIGSERIAL PRIMARY KEY,
+			sku TEXT NOT NULL UNIQUE,
+			name TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL
+		);
+
+		CREATE TABLE product_packs (
+			product_id BIGINT NOT NULL REFERENCES products(id),
+			size BIGINT NOT NULL,
+			sku TEXT NOT NULL DEFAULT '',
+			name TEXT NOT NULL DEFAULT '',
+			unit_cost BIGINT NOT NULL DEFAULT 0,
+			tare_weight BIGINT NOT NULL DEFAULT 0,
+			length BIGINT NOT NULL DEFAULT 0,
+			width BIGINT NOT NULL DEFAULT 0,
+			height BIGINT NOT NULL DEFAULT 0,
+			stock BIGINT,
+			disabled BOOLEAN NOT NULL DEFAULT FALSE,
+			PRIMARY KEY (product_id, size)
+		);
+
+		CREATE TABLE order_lines (
+			order_id BIGINT NOT NULL REFERENCES orders(id),
+			line INTEGER NOT NULL,
+			product_id BIGINT NOT NULL REFERENCES products(id),
+			product_sku TEXT NOT NULL,
+			product_name TEXT NOT NULL,
+			requested_item_count BIGINT NOT NULL,
+			shipped_item_count BIGINT NOT NULL,
+			packs_json TEXT NOT NULL,
+			packaging_cost BIGINT NOT NULL,
+			shipment_weight BIGINT NOT NULL,
+			PRIMARY KEY (order_id, line)
+		);
+
+		-- packs go in cases and cases on pallets, see orders.PlanPackaging
+		CREATE TABLE case_types (
+			position INTEGER PRIMARY KEY,
+			sku TEXT NOT NULL UNIQUE,
+			name TEXT NOT NULL DEFAULT '',
+			pack_size BIGINT NOT NULL,
+			packs BIGINT NOT NULL
+		);
+
+		CREATE TABLE pallet_types (
+			position INTEGER PRIMARY KEY,
+			sku TEXT NOT NULL UNIQUE,
+			name TEXT NOT NULL DEFAULT '',
+			case_sku TEXT NOT NULL REFERENCES case_types(sku),
+			cases BIGINT NOT NULL
+		);
+		`,
+		down: `
+		DROP TABLE pallet_types;
+		DROP TABLE case_types;
+		DROP TABLE order_lines;
+		DROP TABLE product_packs;
+		DROP TABLE products;
+		DROP TABLE idempotency_keys;
+		DROP TABLE order_packs;
+		DROP TABLE orders;
+		DROP TABLE pack_set_version_packs;
+		DROP TABLE pack_set_versions;
+		DROP TABLE packs;
+		`,
+	},
+}
+
+// the schema version this build works with
+var LatestSchemaVersion = migrations[len(migrations)-1].version
+
+// Applies every migration that hasn't been applied yet, oldest first
+func (db *DB) Migrate() error {
+	return db.MigrateTo(LatestSchemaVersion)
+}
+
+// Applies or undoes migrations until the schema is at version, 0 undoes all of them.
+// Fails without changing anything when the database was migrated by a newer build
+func (db *DB) MigrateTo(version int) error {
+	if version < 0 || version > LatestSchemaVersion {
+		return fmt.Errorf("unknown schema version %d, the latest is %d", version, LatestSchemaVersion)
+	}
+
+	current, err := db.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if current > LatestSchemaVersion {
+		return fmt.Errorf("database schema version %d is newer than this build knows, the latest is %d", current, LatestSchemaVersion)
+	}
+
+	for i := current; i < version; i++ {
+		m := migrations[i]
+		if err := db.runMigration(m, true); err != nil {
+			return fmt.Errorf("failed to apply migration %d (%s): %w", m.version, m.name, err)
+		}
+	}
+
+	for i := current - 1; i >= version; i-- {
+		m := migrations[i]
+		if err := db.runMigration(m, false); err != nil {
+			return fmt.Errorf("failed to undo migration %d (%s): %w", m.version, m.name, err)
+		}
+	}
+	return nil
+}
+
+// the change and its schema_migrations record go in together. several instances of the app can start at once,
+// the lock makes them take turns and whoever comes second finds the migration done already
+func (db *DB) runMigration(m migration, up bool) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("LOCK TABLE schema_migrations IN EXCLUSIVE MODE"); err != nil {
+		return fmt.Errorf("failed to lock schema migrations: %w", err)
+	}
+	var applied bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", m.version).Scan(&applied); err != nil {
+		return fmt.Errorf("failed to check migration: %w", err)
+	}
+	if applied == up {
+		return nil
+	}
+
+	if up {
+		if _, err := tx.Exec(m.up); err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)", m.version, m.name, time.Now().UTC())
+	} else {
+		if _, err := tx.Exec(m.down); err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM schema_migrations WHERE version = $1", m.version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if up {
+		log.Printf("Applied migration %d: %s", m.version, m.name)
+	} else {
+		log.Printf("Undid migration %d: %s", m.version, m.name)
+	}
+	return nil
+}
+
+// the version of the last applied migration, 0 for a database that was never migrated
+func (db *DB) SchemaVersion() (int, error) {
+	if err := db.createMigrationsTable(); err != nil {
+		return 0, err
+	}
+
+	var version int
+	if err := db.conn.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// every migration this build knows, oldest first
+func (db *DB) MigrationStatus() ([]models.MigrationStatus, error) {
+	if err := db.createMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan migration: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query migrations: %w", err)
+	}
+
+	statuses := make([]models.MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		statuses[i] = models.MigrationStatus{Version: m.version, Name: m.name}
+		if appliedAt, found := applied[m.version]; found {
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+	return statuses, nil
+}
+
+func (db *DB) createMigrationsTable() error {
+	_, err := db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema migrations table: %w", err)
+	}
+	return nil
+}
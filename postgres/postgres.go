@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/irreal/order-packs/db"
+	"github.com/lib/pq"
+	"github.com/lib/pq/pqerror"
+)
+
+// the repositories of db.DB, kept in postgres. the queries are the same, run with the postgres dialect,
+// only the schema and its migrations are kept here
+type DB struct {
+	*db.DB
+	conn *sql.DB
+}
+
+var dialect = db.Dialect{
+	NumberedPlaceholders: true,
+	ReturningID:          true,
+	// postgres doesn't serialize writers like sqlite does. everything that replaces the live packs, or reads them to change them,
+	// locks the table first so those changes take turns. orders taking packs out of stock wait for them too
+	LockPacks: "LOCK TABLE packs IN EXCLUSIVE MODE",
+	UniqueViolation: func(err error) bool {
+		var pqErr *pq.Error
+		return errors.As(err, &pqErr) && pqErr.Code == pqerror.UniqueViolation
+	},
+}
+
+// connects to the database at url and migrates it to the latest schema, a new database also gets sample data
+func NewDB(url string) (*DB, error) {
+	database, err := Open(url)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := database.SchemaVersion()
+	if err != nil {
+		database.Close()
+		return nil, err
+	}
+
+	if err := database.Migrate(); err != nil {
+		database.Close()
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
+	if version == 0 {
+		if err := database.Seed(); err != nil {
+			database.Close()
+			return nil, fmt.Errorf("failed to seed data: %w", err)
+		}
+		log.Println("Database initialized with schema and sample data")
+	}
+
+	return database, nil
+}
+
+// connects to the database at url as it is, without migrating it.
+// url is a postgres:// url or a key=value connection string
+func Open(url string) (*DB, error) {
+	config, err := pq.NewConfig(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database url: %w", err)
+	}
+
+	// times come back in UTC, like they do from sqlite
+	if config.Runtime == nil {
+		config.Runtime = make(map[string]string)
+	}
+	config.Runtime["timezone"] = "UTC"
+
+	connector, err := pq.NewConnectorConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database url: %w", err)
+	}
+	conn := sql.OpenDB(connector)
+
+	if err := conn.Ping(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	return &DB{DB: db.New(conn, dialect), conn: conn}, nil
+}
@@ -0,0 +1,7 @@
+package products
+
+import "fmt"
+
+var ProductNotFoundError = fmt.Errorf("product not found")
+var ProductExistsError = fmt.Errorf("product already exists")
+var InvalidProductError = fmt.Errorf("product is not valid")
@@ -0,0 +1,149 @@
+package products
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/irreal/order-packs/models"
+	"github.com/irreal/order-packs/orders"
+	"github.com/irreal/order-packs/packs"
+)
+
+type Service struct {
+	repo ProductRepository
+	now  func() time.Time
+
+	listenersMu sync.RWMutex
+	listeners   []func()
+}
+
+type ProductRepository interface {
+	// all products, by ID
+	GetProducts() ([]*models.Product, error)
+	// fails with ProductNotFoundError if there is no such product
+	GetProduct(id int64) (*models.Product, error)
+	// saves a new product, setting its ID. fails with ProductExistsError if the SKU is taken
+	CreateProduct(product *models.Product) error
+	// replaces the saved product with the same ID.
+	// fails with ProductNotFoundError or, when the SKU is taken by another product, ProductExistsError
+	UpdateProduct(product *models.Product) error
+}
+
+func NewService(repo ProductRepository) *Service {
+	return &Service{
+		repo: repo,
+		now:  time.Now,
+	}
+}
+
+func (s *Service) GetProducts() ([]*models.Product, error) {
+	return s.repo.GetProducts()
+}
+
+func (s *Service) GetProduct(id int64) (*models.Product, error) {
+	return s.repo.GetProduct(id)
+}
+
+// the products with the given IDs by ID, fails with ProductNotFoundError on the first one missing
+func (s *Service) GetProductsByID(ids []int64) (map[int64]*models.Product, error) {
+	products := make(map[int64]*models.Product, len(ids))
+	for _, id := range ids {
+		if _, found := products[id]; found {
+			continue
+		}
+		product, err := s.repo.GetProduct(id)
+		if err != nil {
+			return nil, err
+		}
+		products[id] = product
+	}
+	return products, nil
+}
+
+func (s *Service) CreateProduct(product models.Product) (*models.Product, error) {
+	product, err := validateProduct(product)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	product.ID = 0
+	product.CreatedAt = now
+	product.UpdatedAt = now
+	if err := s.repo.CreateProduct(&product); err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// replaces the product's SKU, name and packs. orders placed before keep what they were packed with
+func (s *Service) UpdateProduct(id int64, product models.Product) (*models.Product, error) {
+	product, err := validateProduct(product)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.repo.GetProduct(id)
+	if err != nil {
+		return nil, err
+	}
+
+	product.ID = id
+	product.CreatedAt = existing.CreatedAt
+	product.UpdatedAt = s.now().UTC()
+	if err := s.repo.UpdateProduct(&product); err != nil {
+		return nil, err
+	}
+	s.notifyProductChanged()
+	return &product, nil
+}
+
+// registers a callback that runs after every successful change of a product,
+// used to drop anything cached for its previous packs
+func (s *Service) OnProductChanged(listener func()) {
+	s.listenersMu.Lock()
+	defer s.listenersMu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *Service) notifyProductChanged() {
+	s.listenersMu.RLock()
+	defer s.listenersMu.RUnlock()
+	for _, listener := range s.listeners {
+		listener()
+	}
+}
+
+// the product cleaned up, with its packs sorted by size
+func validateProduct(product models.Product) (models.Product, error) {
+	product.SKU = strings.TrimSpace(product.SKU)
+	product.Name = strings.TrimSpace(product.Name)
+	if product.SKU == "" {
+		return product, fmt.Errorf("%w: SKU is required", InvalidProductError)
+	}
+
+	for _, pack := range product.Packs {
+		if err := pack.Validate(); err != nil {
+			return product, fmt.Errorf("%w: %w", InvalidProductError, err)
+		}
+		// stock is counted for the warehouse pack set only
+		if pack.Stock != nil {
+			return product, fmt.Errorf("%w: stock of pack %d can't be tracked on a product", InvalidProductError, pack.Size)
+		}
+	}
+	if duplicates := packs.DuplicateSizes(product.Packs); len(duplicates) > 0 {
+		return product, fmt.Errorf("%w: pack sizes %v are listed more than once", InvalidProductError, duplicates)
+	}
+	if len(models.EnabledPacks(product.Packs)) == 0 {
+		return product, fmt.Errorf("%w: at least one pack has to be enabled", InvalidProductError)
+	}
+	if err := orders.ValidatePackSetSize(product.Packs); err != nil {
+		return product, fmt.Errorf("%w: %w", InvalidProductError, err)
+	}
+
+	product.Packs = slices.Clone(product.Packs)
+	slices.SortFunc(product.Packs, func(a, b models.PackDetails) int { return int(a.Size - b.Size) })
+	return product, nil
+}
@@ -0,0 +1,212 @@
+package products
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/irreal/order-packs/models"
+)
+
+type MockProductRepository struct {
+	products []*models.Product
+}
+
+func NewMockProductRepository() *MockProductRepository {
+	return &MockProductRepository{
+		products: make([]*models.Product, 0),
+	}
+}
+
+func (m *MockProductRepository) GetProducts() ([]*models.Product, error) {
+	return m.products, nil
+}
+
+func (m *MockProductRepository) GetProduct(id int64) (*models.Product, error) {
+	for _, product := range m.products {
+		if product.ID == id {
+			return product, nil
+		}
+	}
+	return nil, ProductNotFoundError
+}
+
+func (m *MockProductRepository) CreateProduct(product *models.Product) error {
+	if m.skuTaken(product) {
+		return ProductExistsError
+	}
+	product.ID = int64(len(m.products) + 1)
+	m.products = append(m.products, product)
+	return nil
+}
+
+func (m *MockProductRepository) UpdateProduct(product *models.Product) error {
+	if m.skuTaken(product) {
+		return ProductExistsError
+	}
+	for i, saved := range m.products {
+		if saved.ID == product.ID {
+			m.products[i] = product
+			return nil
+		}
+	}
+	return ProductNotFoundError
+}
+
+func (m *MockProductRepository) skuTaken(product *models.Product) bool {
+	for _, saved := range m.products {
+		if saved.SKU == product.SKU && saved.ID != product.ID {
+			return true
+		}
+	}
+	return false
+}
+
+func TestService_CreateProduct(t *testing.T) {
+	stock := 5
+
+	tests := []struct {
+		name          string
+		product       models.Product
+		expectedPacks models.Packs
+		expectedError error
+	}{
+		{
+			name:          "packs are sorted",
+			product:       models.Product{SKU: " BLN-BLUE ", Name: "Blue balloons", Packs: models.Packs{500, 100}.Details()},
+			expectedPacks: models.Packs{100, 500},
+		},
+		{
+			name:          "some packs disabled",
+			product:       models.Product{SKU: "BLN-BLUE", Packs: []models.PackDetails{{Size: 100}, {Size: 500, Disabled: true}}},
+			expectedPacks: models.Packs{100, 500},
+		},
+		{
+			name:          "no SKU",
+			product:       models.Product{SKU: "  ", Packs: models.Packs{100}.Details()},
+			expectedError: InvalidProductError,
+		},
+		{
+			name:          "no packs",
+			product:       models.Product{SKU: "BLN-BLUE"},
+			expectedError: InvalidProductError,
+		},
+		{
+			name:          "only disabled packs",
+			product:       models.Product{SKU: "BLN-BLUE", Packs: []models.PackDetails{{Size: 100, Disabled: true}}},
+			expectedError: InvalidProductError,
+		},
+		{
+			name:          "duplicate sizes",
+			product:       models.Product{SKU: "BLN-BLUE", Packs: models.Packs{100, 100}.Details()},
+			expectedError: InvalidProductError,
+		},
+		{
+			name:          "invalid pack",
+			product:       models.Product{SKU: "BLN-BLUE", Packs: models.Packs{0}.Details()},
+			expectedError: InvalidProductError,
+		},
+		{
+			name:          "stock",
+			product:       models.Product{SKU: "BLN-BLUE", Packs: []models.PackDetails{{Size: 100, Stock: &stock}}},
+			expectedError: InvalidProductError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService(NewMockProductRepository())
+			product, err := service.CreateProduct(tt.product)
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Errorf("CreateProduct() error = %v, want %v", err, tt.expectedError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateProduct() unexpected error = %v", err)
+			}
+			if product.ID != 1 || product.SKU != "BLN-BLUE" || product.CreatedAt.IsZero() {
+				t.Errorf("CreateProduct() = %+v, want ID 1, trimmed SKU and a creation time", product)
+			}
+			if !reflect.DeepEqual(models.PackSizes(product.Packs), tt.expectedPacks) {
+				t.Errorf("Packs = %v, want %v", models.PackSizes(product.Packs), tt.expectedPacks)
+			}
+		})
+	}
+}
+
+func TestService_CreateProduct_SKUTaken(t *testing.T) {
+	service := NewService(NewMockProductRepository())
+	if _, err := service.CreateProduct(models.Product{SKU: "BLN-BLUE", Packs: models.Packs{100}.Details()}); err != nil {
+		t.Fatalf("CreateProduct() unexpected error = %v", err)
+	}
+	if _, err := service.CreateProduct(models.Product{SKU: "BLN-BLUE", Packs: models.Packs{200}.Details()}); !errors.Is(err, ProductExistsError) {
+		t.Errorf("CreateProduct() error = %v, want %v", err, ProductExistsError)
+	}
+}
+
+func TestService_UpdateProduct(t *testing.T) {
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	service := NewService(NewMockProductRepository())
+	service.now = func() time.Time { return created }
+	for _, sku := range []string{"BLN-BLUE", "BLN-RED"} {
+		if _, err := service.CreateProduct(models.Product{SKU: sku, Packs: models.Packs{100}.Details()}); err != nil {
+			t.Fatalf("CreateProduct() unexpected error = %v", err)
+		}
+	}
+
+	calls := 0
+	service.OnProductChanged(func() { calls++ })
+
+	service.now = func() time.Time { return created.Add(time.Hour) }
+	product, err := service.UpdateProduct(1, models.Product{SKU: "BLN-BLUE", Name: "Blue", Packs: models.Packs{300, 200}.Details()})
+	if err != nil {
+		t.Fatalf("UpdateProduct() unexpected error = %v", err)
+	}
+	if !product.CreatedAt.Equal(created) || !product.UpdatedAt.Equal(created.Add(time.Hour)) {
+		t.Errorf("CreatedAt, UpdatedAt = %v, %v, want the creation time kept", product.CreatedAt, product.UpdatedAt)
+	}
+	saved, _ := service.GetProduct(1)
+	if saved.Name != "Blue" || !reflect.DeepEqual(models.PackSizes(saved.Packs), models.Packs{200, 300}) {
+		t.Errorf("GetProduct() = %+v, want the new name and packs", saved)
+	}
+	if calls != 1 {
+		t.Errorf("listener called %d times, want 1", calls)
+	}
+
+	if _, err := service.UpdateProduct(1, models.Product{SKU: "BLN-RED", Packs: models.Packs{100}.Details()}); !errors.Is(err, ProductExistsError) {
+		t.Errorf("UpdateProduct() to a taken SKU error = %v, want %v", err, ProductExistsError)
+	}
+	if _, err := service.UpdateProduct(3, models.Product{SKU: "BLN-GREEN", Packs: models.Packs{100}.Details()}); !errors.Is(err, ProductNotFoundError) {
+		t.Errorf("UpdateProduct() of a missing product error = %v, want %v", err, ProductNotFoundError)
+	}
+	if _, err := service.UpdateProduct(1, models.Product{SKU: "BLN-BLUE"}); !errors.Is(err, InvalidProductError) {
+		t.Errorf("UpdateProduct() without packs error = %v, want %v", err, InvalidProductError)
+	}
+	if calls != 1 {
+		t.Errorf("listener called %d times after failed updates, want 1", calls)
+	}
+}
+
+func TestService_GetProductsByID(t *testing.T) {
+	service := NewService(NewMockProductRepository())
+	for _, sku := range []string{"BLN-BLUE", "BLN-RED"} {
+		if _, err := service.CreateProduct(models.Product{SKU: sku, Packs: models.Packs{100}.Details()}); err != nil {
+			t.Fatalf("CreateProduct() unexpected error = %v", err)
+		}
+	}
+
+	products, err := service.GetProductsByID([]int64{2, 1, 2})
+	if err != nil {
+		t.Fatalf("GetProductsByID() unexpected error = %v", err)
+	}
+	if len(products) != 2 || products[1].SKU != "BLN-BLUE" || products[2].SKU != "BLN-RED" {
+		t.Errorf("GetProductsByID() = %v, want both products by ID", products)
+	}
+
+	if _, err := service.GetProductsByID([]int64{1, 7}); !errors.Is(err, ProductNotFoundError) {
+		t.Errorf("GetProductsByID() error = %v, want %v", err, ProductNotFoundError)
+	}
+}
@@ -0,0 +1,621 @@
+// checks every database implementation of the order and pack repositories has to pass, so they all behave the same.
+// each database package runs them from its own tests
+package repotest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/irreal/order-packs/idempotency"
+	"github.com/irreal/order-packs/models"
+	"github.com/irreal/order-packs/orders"
+	"github.com/irreal/order-packs/packs"
+	"github.com/irreal/order-packs/products"
+)
+
+type Repository interface {
+	orders.OrderRepository
+	packs.PackRepository
+	idempotency.KeyRepository
+	products.ProductRepository
+}
+
+// runs every check, each one against a new repository from newRepository that has no packs and no orders yet
+func Run(t *testing.T, newRepository func(t *testing.T) Repository) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, repo Repository)
+	}{
+		{"save packs makes an activated version live", testSavePacks},
+		{"save packs replaces the live packs", testSavePacksReplaces},
+		{"update packs saves nothing when the update fails", testUpdatePacks},
+		{"scheduled pack sets", testScheduledPackSets},
+		{"save order takes packs out of stock", testSaveOrderStock},
+		{"get order", testGetOrder},
+		{"last 10 orders are the newest first", testLast10Orders},
+		{"update order status", testUpdateOrderStatus},
+		{"list orders", testListOrders},
+		{"backorders", testBackorders},
+		{"orders with lines", testLineOrders},
+		{"idempotency keys", testIdempotencyKeys},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepository(t))
+		})
+	}
+}
+
+// a fixed time, truncated so every database keeps it exactly
+var base = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func stock(level int) *int {
+	return &level
+}
+
+func activeVersion(at time.Time, packs ...models.PackDetails) *models.PackSetVersion {
+	return &models.PackSetVersion{CreatedAt: at, CreatedBy: "repotest", EffectiveFrom: at, ActivatedAt: &at, Packs: packs}
+}
+
+func savePacks(t *testing.T, repo Repository, version *models.PackSetVersion) {
+	t.Helper()
+	if err := repo.SavePacks(version); err != nil {
+		t.Fatalf("failed to save packs: %v", err)
+	}
+}
+
+func newOrder(requested int, packs map[models.Pack]int, createdAt time.Time) *models.Order {
+	shipped := 0
+	for pack, count := range packs {
+		shipped += int(pack) * count
+	}
+	return &models.Order{
+		PublicID:           models.NewPublicOrderID(),
+		RequestedItemCount: requested,
+		ShippedItemCount:   shipped,
+		Packs:              packs,
+		Status:             models.OrderStatusNew,
+		CreatedAt:          createdAt,
+		UpdatedAt:          createdAt,
+	}
+}
+
+func saveOrder(t *testing.T, repo Repository, order *models.Order) {
+	t.Helper()
+	if err := repo.SaveOrder(order); err != nil {
+		t.Fatalf("failed to save order: %v", err)
+	}
+}
+
+func expectPacks(t *testing.T, repo Repository, expected models.Packs) {
+	t.Helper()
+	packs, err := repo.GetPacks()
+	if err != nil {
+		t.Fatalf("failed to get packs: %v", err)
+	}
+	if !reflect.DeepEqual(packs, expected) {
+		t.Errorf("expected packs %v, got %v", expected, packs)
+	}
+}
+
+func expectStock(t *testing.T, repo Repository, size models.Pack, expected *int) {
+	t.Helper()
+	details, err := repo.GetPackDetails()
+	if err != nil {
+		t.Fatalf("failed to get pack details: %v", err)
+	}
+	for _, pack := range details {
+		if pack.Size != size {
+			continue
+		}
+		if !reflect.DeepEqual(pack.Stock, expected) {
+			t.Errorf("pack %d: expected stock %v, got %v", size, describeStock(expected), describeStock(pack.Stock))
+		}
+		return
+	}
+	t.Errorf("pack %d is missing", size)
+}
+
+func describeStock(level *int) any {
+	if level == nil {
+		return "untracked"
+	}
+	return *level
+}
+
+func testSavePacks(t *testing.T, repo Repository) {
+	version := activeVersion(base,
+		models.PackDetails{Size: 250, SKU: "P-250", Name: "Small", UnitCost: 40, TareWeight: 30, Dimensions: models.Dimensions{Length: 200, Width: 150, Height: 50}},
+		models.PackDetails{Size: 500, Stock: stock(10)},
+		models.PackDetails{Size: 1000, Disabled: true},
+	)
+	savePacks(t, repo, version)
+	if version.ID == 0 {
+		t.Error("expected the version to get an id")
+	}
+
+	expectPacks(t, repo, models.Packs{250, 500})
+	details, err := repo.GetPackDetails()
+	if err != nil {
+		t.Fatalf("failed to get pack details: %v", err)
+	}
+	if !reflect.DeepEqual(details, version.Packs) {
+		t.Errorf("expected pack details %+v, got %+v", version.Packs, details)
+	}
+
+	saved, err := repo.GetPackSetVersion(version.ID)
+	if err != nil {
+		t.Fatalf("failed to get pack set version: %v", err)
+	}
+	if saved.CreatedBy != version.CreatedBy || !saved.CreatedAt.Equal(base) || !saved.EffectiveFrom.Equal(base) ||
+		saved.ActivatedAt == nil || !saved.ActivatedAt.Equal(base) || saved.RolledBackFrom != nil {
+		t.Errorf("saved version differs, got %+v", saved)
+	}
+	if !reflect.DeepEqual(saved.Packs, version.Packs) {
+		t.Errorf("expected version packs %+v, got %+v", version.Packs, saved.Packs)
+	}
+
+	if _, err := repo.GetPackSetVersion(version.ID + 100); !errors.Is(err, packs.PackSetVersionNotFoundError) {
+		t.Errorf("expected PackSetVersionNotFoundError, got %v", err)
+	}
+}
+
+func testSavePacksReplaces(t *testing.T, repo Repository) {
+	first := activeVersion(base, models.PackDetails{Size: 250}, models.PackDetails{Size: 500})
+	savePacks(t, repo, first)
+	second := activeVersion(base.Add(time.Minute), models.PackDetails{Size: 1000})
+	second.RolledBackFrom = &first.ID
+	savePacks(t, repo, second)
+
+	expectPacks(t, repo, models.Packs{1000})
+
+	versions, err := repo.GetPackSetVersions()
+	if err != nil {
+		t.Fatalf("failed to get pack set versions: %v", err)
+	}
+	if len(versions) != 2 || versions[0].ID != second.ID || versions[1].ID != first.ID {
+		t.Fatalf("expected versions %d and %d newest first, got %+v", second.ID, first.ID, versions)
+	}
+	if len(versions[0].Packs) != 1 || len(versions[1].Packs) != 2 {
+		t.Errorf("expected versions to keep their own packs, got %+v and %+v", versions[0].Packs, versions[1].Packs)
+	}
+	if versions[0].RolledBackFrom == nil || *versions[0].RolledBackFrom != first.ID {
+		t.Errorf("expected version to be rolled back from %d, got %v", first.ID, versions[0].RolledBackFrom)
+	}
+}
+
+func testUpdatePacks(t *testing.T, repo Repository) {
+	savePacks(t, repo, activeVersion(base, models.PackDetails{Size: 250}))
+
+	failure := errors.New("update failed")
+	err := repo.UpdatePacks(activeVersion(base.Add(time.Minute)), func(live []models.PackDetails) ([]models.PackDetails, error) {
+		return append(live, models.PackDetails{Size: 500}), failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected the update error, got %v", err)
+	}
+	expectPacks(t, repo, models.Packs{250})
+	versions, err := repo.GetPackSetVersions()
+	if err != nil {
+		t.Fatalf("failed to get pack set versions: %v", err)
+	}
+	if len(versions) != 1 {
+		t.Errorf("expected the failed update not to save a version, got %d versions", len(versions))
+	}
+
+	var seen []models.PackDetails
+	version := activeVersion(base.Add(2 * time.Minute))
+	err = repo.UpdatePacks(version, func(live []models.PackDetails) ([]models.PackDetails, error) {
+		seen = live
+		return append(live, models.PackDetails{Size: 500}), nil
+	})
+	if err != nil {
+		t.Fatalf("failed to update packs: %v", err)
+	}
+	if len(seen) != 1 || seen[0].Size != 250 {
+		t.Errorf("expected the update to get the live packs, got %+v", seen)
+	}
+	if version.ID == 0 {
+		t.Error("expected the version to get an id")
+	}
+	expectPacks(t, repo, models.Packs{250, 500})
+}
+
+func testScheduledPackSets(t *testing.T, repo Repository) {
+	live := activeVersion(base.Add(-time.Hour), models.PackDetails{Size: 250, Stock: stock(5)}, models.PackDetails{Size: 1000, Stock: stock(7)})
+	savePacks(t, repo, live)
+	scheduled := &models.PackSetVersion{
+		CreatedAt:     base,
+		EffectiveFrom: base.Add(time.Hour),
+		Packs:         []models.PackDetails{{Size: 250, Stock: stock(100)}, {Size: 500}, {Size: 1000}},
+	}
+	savePacks(t, repo, scheduled)
+
+	expectPacks(t, repo, models.Packs{250, 1000})
+	upcoming, err := repo.GetScheduledPackSets()
+	if err != nil {
+		t.Fatalf("failed to get scheduled pack sets: %v", err)
+	}
+	if len(upcoming) != 1 || upcoming[0].ID != scheduled.ID || len(upcoming[0].Packs) != 3 || upcoming[0].ActivatedAt != nil {
+		t.Errorf("expected version %d to be scheduled, got %+v", scheduled.ID, upcoming)
+	}
+
+	atTimes := []struct {
+		at       time.Time
+		expected int64
+	}{
+		{base, live.ID},
+		{base.Add(2 * time.Hour), scheduled.ID},
+	}
+	for _, tt := range atTimes {
+		version, err := repo.GetPackSetVersionAt(tt.at)
+		if err != nil {
+			t.Fatalf("failed to get pack set version at %s: %v", tt.at, err)
+		}
+		if version.ID != tt.expected {
+			t.Errorf("expected version %d at %s, got %d", tt.expected, tt.at, version.ID)
+		}
+	}
+	if _, err := repo.GetPackSetVersionAt(base.Add(-2 * time.Hour)); !errors.Is(err, packs.PackSetVersionNotFoundError) {
+		t.Errorf("expected PackSetVersionNotFoundError before the first version, got %v", err)
+	}
+
+	if changed, err := repo.ActivatePackSets(base); err != nil || changed {
+		t.Errorf("expected nothing to activate yet, got %v, %v", changed, err)
+	}
+	if changed, err := repo.ActivatePackSets(base.Add(2 * time.Hour)); err != nil || !changed {
+		t.Fatalf("expected the scheduled version to go live, got %v, %v", changed, err)
+	}
+	if changed, err := repo.ActivatePackSets(base.Add(3 * time.Hour)); err != nil || changed {
+		t.Errorf("expected activating again to do nothing, got %v, %v", changed, err)
+	}
+
+	expectPacks(t, repo, models.Packs{250, 500, 1000})
+	// the stock the set was scheduled with is applied, a pack scheduled without stock keeps what it had
+	expectStock(t, repo, 250, stock(100))
+	expectStock(t, repo, 500, nil)
+	expectStock(t, repo, 1000, stock(7))
+	upcoming, err = repo.GetScheduledPackSets()
+	if err != nil {
+		t.Fatalf("failed to get scheduled pack sets: %v", err)
+	}
+	if len(upcoming) != 0 {
+		t.Errorf("expected nothing scheduled anymore, got %+v", upcoming)
+	}
+}
+
+func testSaveOrderStock(t *testing.T, repo Repository) {
+	version := activeVersion(base, models.PackDetails{Size: 250, Stock: stock(2)}, models.PackDetails{Size: 500})
+	savePacks(t, repo, version)
+
+	live, liveVersion, err := repo.GetLivePackSet()
+	if err != nil {
+		t.Fatalf("failed to get the live pack set: %v", err)
+	}
+	if liveVersion != version.ID || len(live) != 2 {
+		t.Errorf("expected the 2 packs of version %d, got %+v of version %d", version.ID, live, liveVersion)
+	}
+
+	order := newOrder(1700, map[models.Pack]int{250: 1, 500: 3}, base)
+	order.PackSetVersion = liveVersion
+	saveOrder(t, repo, order)
+	if order.ID == 0 {
+		t.Error("expected the order to get an id")
+	}
+	byID, err := repo.GetOrder(order.ID)
+	if err != nil {
+		t.Fatalf("failed to get order: %v", err)
+	}
+	if byID.PackSetVersion != version.ID {
+		t.Errorf("expected the order to be calculated with version %d, got %d", version.ID, byID.PackSetVersion)
+	}
+	expectStock(t, repo, 250, stock(1))
+	expectStock(t, repo, 500, nil)
+
+	// both packs are checked in one transaction, so the untracked one isn't saved either
+	tooMany := newOrder(1000, map[models.Pack]int{250: 2, 500: 1}, base.Add(time.Minute))
+	if err := repo.SaveOrder(tooMany); !errors.Is(err, orders.InsufficientStockError) {
+		t.Fatalf("expected InsufficientStockError, got %v", err)
+	}
+	expectStock(t, repo, 250, stock(1))
+	saved, err := repo.GetLast10Orders()
+	if err != nil {
+		t.Fatalf("failed to get orders: %v", err)
+	}
+	if len(saved) != 1 {
+		t.Errorf("expected only the first order to be saved, got %d", len(saved))
+	}
+}
+
+func testGetOrder(t *testing.T, repo Repository) {
+	savePacks(t, repo, activeVersion(base, models.PackDetails{Size: 250}))
+	order := newOrder(251, map[models.Pack]int{250: 2}, base)
+	order.PackagingCost = 80
+	order.ShipmentWeight = 60
+	saveOrder(t, repo, order)
+
+	byID, err := repo.GetOrder(order.ID)
+	if err != nil {
+		t.Fatalf("failed to get order: %v", err)
+	}
+	byPublicID, err := repo.GetOrderByPublicID(order.PublicID)
+	if err != nil {
+		t.Fatalf("failed to get order by public id: %v", err)
+	}
+	for _, saved := range []*models.Order{byID, byPublicID} {
+		if saved.ID != order.ID || saved.PublicID != order.PublicID || saved.RequestedItemCount != 251 || saved.ShippedItemCount != 500 ||
+			!reflect.DeepEqual(saved.Packs, order.Packs) || saved.PackagingCost != 80 || saved.ShipmentWeight != 60 ||
+			saved.Status != models.OrderStatusNew || !saved.CreatedAt.Equal(base) || saved.PackSetVersion != order.PackSetVersion {
+			t.Errorf("saved order differs, got %+v", saved)
+		}
+	}
+
+	if _, err := repo.GetOrder(order.ID + 100); !errors.Is(err, orders.OrderNotFoundError) {
+		t.Errorf("expected OrderNotFoundError, got %v", err)
+	}
+	if _, err := repo.GetOrderByPublicID(models.NewPublicOrderID()); !errors.Is(err, orders.OrderNotFoundError) {
+		t.Errorf("expected OrderNotFoundError, got %v", err)
+	}
+}
+
+func testLast10Orders(t *testing.T, repo Repository) {
+	// saved out of order, they come back by creation time
+	for i := range 12 {
+		minute := i * 5 % 12
+		saveOrder(t, repo, newOrder(minute+1, map[models.Pack]int{}, base.Add(time.Duration(minute)*time.Minute)))
+	}
+
+	recent, err := repo.GetLast10Orders()
+	if err != nil {
+		t.Fatalf("failed to get orders: %v", err)
+	}
+	if len(recent) != 10 {
+		t.Fatalf("expected 10 orders, got %d", len(recent))
+	}
+	for i, order := range recent {
+		if order.RequestedItemCount != 12-i {
+			t.Errorf("expected order %d to be the one for %d items, got %d", i, 12-i, order.RequestedItemCount)
+		}
+	}
+}
+
+func testUpdateOrderStatus(t *testing.T, repo Repository) {
+	savePacks(t, repo, activeVersion(base, models.PackDetails{Size: 250, Stock: stock(5)}))
+	order := newOrder(500, map[models.Pack]int{250: 2}, base)
+	saveOrder(t, repo, order)
+	expectStock(t, repo, 250, stock(3))
+
+	stale := *order
+	if err := repo.UpdateOrderStatus(order, models.OrderStatusPending); err != nil {
+		t.Fatalf("failed to update order status: %v", err)
+	}
+	if order.Status != models.OrderStatusPending || order.UpdatedAt.Before(order.CreatedAt) {
+		t.Errorf("expected the order to be updated, got %+v", order)
+	}
+
+	if err := repo.UpdateOrderStatus(&stale, models.OrderStatusCancelled); !errors.Is(err, orders.OrderStatusChangedError) {
+		t.Fatalf("expected OrderStatusChangedError, got %v", err)
+	}
+	expectStock(t, repo, 250, stock(3))
+
+	if err := repo.UpdateOrderStatus(order, models.OrderStatusCancelled); err != nil {
+		t.Fatalf("failed to cancel order: %v", err)
+	}
+	expectStock(t, repo, 250, stock(5))
+	saved, err := repo.GetOrder(order.ID)
+	if err != nil {
+		t.Fatalf("failed to get order: %v", err)
+	}
+	if saved.Status != models.OrderStatusCancelled {
+		t.Errorf("expected the order to be cancelled, got %s", saved.Status)
+	}
+}
+
+func testListOrders(t *testing.T, repo Repository) {
+	savePacks(t, repo, activeVersion(base, models.PackDetails{Size: 250}, models.PackDetails{Size: 500}))
+	saved := []*models.Order{
+		newOrder(200, map[models.Pack]int{250: 1}, base),
+		newOrder(600, map[models.Pack]int{500: 1, 250: 1}, base.Add(time.Hour)),
+		newOrder(400, map[models.Pack]int{500: 1}, base.Add(2*time.Hour)),
+		newOrder(400, map[models.Pack]int{500: 1}, base.Add(3*time.Hour)),
+	}
+	for _, order := range saved {
+		saveOrder(t, repo, order)
+	}
+	if err := repo.UpdateOrderStatus(saved[1], models.OrderStatusPending); err != nil {
+		t.Fatalf("failed to update order status: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		query    models.OrderQuery
+		expected []*models.Order
+	}{
+		{
+			name:     "newest first",
+			query:    models.OrderQuery{Sort: models.OrderSortNewest, Limit: 10},
+			expected: []*models.Order{saved[3], saved[2], saved[1], saved[0]},
+		},
+		{
+			name:     "page after a cursor",
+			query:    models.OrderQuery{Sort: models.OrderSortOldest, Limit: 2, After: &models.OrderCursor{Sort: models.OrderSortOldest, ID: saved[0].ID}},
+			expected: []*models.Order{saved[1], saved[2]},
+		},
+		{
+			name:     "ties broken by id",
+			query:    models.OrderQuery{Sort: models.OrderSortRequestedDesc, Limit: 10},
+			expected: []*models.Order{saved[1], saved[3], saved[2], saved[0]},
+		},
+		{
+			name: "page inside a tie",
+			query: models.OrderQuery{Sort: models.OrderSortRequestedDesc, Limit: 10,
+				After: &models.OrderCursor{Sort: models.OrderSortRequestedDesc, Value: 400, ID: saved[3].ID}},
+			expected: []*models.Order{saved[2], saved[0]},
+		},
+		{
+			name:     "by status",
+			query:    models.OrderQuery{Sort: models.OrderSortNewest, Limit: 10, Statuses: []models.OrderStatus{models.OrderStatusPending}},
+			expected: []*models.Order{saved[1]},
+		},
+		{
+			name:     "by pack size and shipped items",
+			query:    models.OrderQuery{Sort: models.OrderSortShippedAsc, Limit: 10, PackSize: 250, MinShipped: 250, MaxShipped: 750},
+			expected: []*models.Order{saved[0], saved[1]},
+		},
+		{
+			name:     "by creation time",
+			query:    models.OrderQuery{Sort: models.OrderSortNewest, Limit: 10, CreatedFrom: base.Add(time.Hour), CreatedTo: base.Add(3 * time.Hour)},
+			expected: []*models.Order{saved[2], saved[1]},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listed, err := repo.ListOrders(tt.query)
+			if err != nil {
+				t.Fatalf("failed to list orders: %v", err)
+			}
+			var ids, expected []int64
+			for _, order := range listed {
+				ids = append(ids, order.ID)
+			}
+			for _, order := range tt.expected {
+				expected = append(expected, order.ID)
+			}
+			if !reflect.DeepEqual(ids, expected) {
+				t.Errorf("expected orders %v, got %v", expected, ids)
+			}
+		})
+	}
+}
+
+func testBackorders(t *testing.T, repo Repository) {
+	savePacks(t, repo, activeVersion(base, models.PackDetails{Size: 250}))
+	order := newOrder(750, map[models.Pack]int{250: 2}, base)
+	order.Backorder = newOrder(250, map[models.Pack]int{}, base)
+	order.Backorder.Status = models.OrderStatusBackordered
+	saveOrder(t, repo, order)
+	if order.Backorder.ID == 0 {
+		t.Fatal("expected the backorder to get an id")
+	}
+
+	saved, err := repo.GetOrder(order.ID)
+	if err != nil {
+		t.Fatalf("failed to get order: %v", err)
+	}
+	if saved.Backorder == nil || saved.Backorder.ID != order.Backorder.ID || saved.Backorder.BackorderOf != order.PublicID {
+		t.Errorf("expected the order to come with its backorder, got %+v", saved.Backorder)
+	}
+	backorder, err := repo.GetOrder(order.Backorder.ID)
+	if err != nil {
+		t.Fatalf("failed to get backorder: %v", err)
+	}
+	if backorder.BackorderOf != order.PublicID || backorder.Status != models.OrderStatusBackordered || len(backorder.Packs) != 0 {
+		t.Errorf("backorder differs, got %+v", backorder)
+	}
+
+	// the backorder's items were asked for by the order already
+	demand, err := repo.GetOrderDemand()
+	if err != nil {
+		t.Fatalf("failed to get order demand: %v", err)
+	}
+	if !reflect.DeepEqual(demand, []models.OrderDemand{{ItemCount: 750, Orders: 1}}) {
+		t.Errorf("expected demand only for the order, got %+v", demand)
+	}
+	listed, err := repo.ListOrders(models.OrderQuery{Sort: models.OrderSortOldest, Limit: 10, SingleCountOnly: true})
+	if err != nil {
+		t.Fatalf("failed to list orders: %v", err)
+	}
+	if len(listed) != 1 || listed[0].ID != order.ID {
+		t.Errorf("expected only the order without its backorder, got %+v", listed)
+	}
+}
+
+func testLineOrders(t *testing.T, repo Repository) {
+	savePacks(t, repo, activeVersion(base, models.PackDetails{Size: 250}))
+	product := &models.Product{SKU: "BLN-RED", Name: "Red balloons", Packs: models.Packs{250}.Details(), CreatedAt: base, UpdatedAt: base}
+	if err := repo.CreateProduct(product); err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+	single := newOrder(250, map[models.Pack]int{250: 1}, base)
+	saveOrder(t, repo, single)
+	// the line's packs are the product's, the order has none of its own
+	order := newOrder(250, map[models.Pack]int{}, base)
+	order.ShippedItemCount = 250
+	order.Lines = []models.OrderLine{{ProductID: product.ID, ProductSKU: product.SKU, ProductName: product.Name,
+		RequestedItemCount: 250, ShippedItemCount: 250, Packs: map[models.Pack]int{250: 1}}}
+	saveOrder(t, repo, order)
+
+	saved, err := repo.GetOrder(order.ID)
+	if err != nil {
+		t.Fatalf("failed to get order: %v", err)
+	}
+	if len(saved.Packs) != 0 || len(saved.Lines) != 1 || saved.Lines[0].Packs[250] != 1 || saved.PackSetVersion != 0 {
+		t.Errorf("expected the packs only on the line, got %v and lines %+v", saved.Packs, saved.Lines)
+	}
+
+	queries := []struct {
+		name  string
+		query models.OrderQuery
+	}{
+		{"by pack size", models.OrderQuery{Sort: models.OrderSortOldest, Limit: 10, PackSize: 250}},
+		{"single count orders", models.OrderQuery{Sort: models.OrderSortOldest, Limit: 10, SingleCountOnly: true}},
+	}
+	for _, tt := range queries {
+		listed, err := repo.ListOrders(tt.query)
+		if err != nil {
+			t.Fatalf("failed to list orders %s: %v", tt.name, err)
+		}
+		if len(listed) != 1 || listed[0].ID != single.ID {
+			t.Errorf("expected only the order without lines %s, got %+v", tt.name, listed)
+		}
+	}
+}
+
+func testIdempotencyKeys(t *testing.T, repo Repository) {
+	reserve := func(hash string, at time.Time) *models.IdempotencyRecord {
+		t.Helper()
+		existing, err := repo.ReserveIdempotencyKey(&models.IdempotencyRecord{
+			Key: "abc", RequestHash: hash, CreatedAt: at, ExpiresAt: at.Add(time.Hour), LockedUntil: at.Add(time.Minute),
+		})
+		if err != nil {
+			t.Fatalf("failed to reserve idempotency key: %v", err)
+		}
+		return existing
+	}
+
+	if existing := reserve("one", base); existing != nil {
+		t.Fatalf("expected a new key to be reserved, got %+v", existing)
+	}
+	existing := reserve("two", base.Add(30*time.Second))
+	if existing == nil || existing.RequestHash != "one" || existing.Completed() || !existing.LockedUntil.Equal(base.Add(time.Minute)) {
+		t.Fatalf("expected the first request to still hold the key, got %+v", existing)
+	}
+
+	// the first request never finished, its lease ran out
+	if existing := reserve("two", base.Add(time.Minute)); existing != nil {
+		t.Fatalf("expected the abandoned key to be taken over, got %+v", existing)
+	}
+	if err := repo.CompleteIdempotencyKey("abc", 201, []byte("done")); err != nil {
+		t.Fatalf("failed to complete idempotency key: %v", err)
+	}
+
+	// completed keys are kept until they expire, leases don't matter anymore
+	existing = reserve("three", base.Add(30*time.Minute))
+	if existing == nil || existing.RequestHash != "two" || existing.StatusCode != 201 || string(existing.ResponseBody) != "done" {
+		t.Fatalf("expected the completed request, got %+v", existing)
+	}
+	if existing := reserve("three", base.Add(2*time.Hour)); existing != nil {
+		t.Fatalf("expected the expired key to be reserved again, got %+v", existing)
+	}
+
+	if err := repo.ReleaseIdempotencyKey("abc"); err != nil {
+		t.Fatalf("failed to release idempotency key: %v", err)
+	}
+	if existing := reserve("four", base.Add(2*time.Hour)); existing != nil {
+		t.Fatalf("expected the released key to be reserved again, got %+v", existing)
+	}
+}